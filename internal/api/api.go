@@ -3,6 +3,7 @@ package api
 import (
 	"crypto/tls"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net"
 	"net/http"
@@ -80,7 +81,7 @@ func Init() {
 	}
 
 	// Initialize the HTTPS server
-	if cfg.Mod.TLSListen != "" && cfg.Mod.TLSCert != "" && cfg.Mod.TLSKey != "" {
+	if cfg.Mod.TLSListen != "" {
 		go tlsListen("tcp", cfg.Mod.TLSListen, cfg.Mod.TLSCert, cfg.Mod.TLSKey)
 	}
 }
@@ -103,7 +104,13 @@ func listen(network, address string) {
 	}
 }
 
-func tlsListen(network, address, certFile, keyFile string) {
+// TLSConfig - config with certificate from file path or from PEM text,
+// shared by all modules with TLS listeners
+func TLSConfig(certFile, keyFile string) (*tls.Config, error) {
+	if certFile == "" || keyFile == "" {
+		return nil, errors.New("api: tls_cert and tls_key are required")
+	}
+
 	var cert tls.Certificate
 	var err error
 	if strings.IndexByte(certFile, '\n') < 0 && strings.IndexByte(keyFile, '\n') < 0 {
@@ -114,7 +121,16 @@ func tlsListen(network, address, certFile, keyFile string) {
 		cert, err = tls.X509KeyPair([]byte(certFile), []byte(keyFile))
 	}
 	if err != nil {
-		log.Error().Err(err).Caller().Send()
+		return nil, err
+	}
+
+	return &tls.Config{Certificates: []tls.Certificate{cert}}, nil
+}
+
+func tlsListen(network, address, certFile, keyFile string) {
+	config, err := TLSConfig(certFile, keyFile)
+	if err != nil {
+		log.Error().Err(err).Msg("[api] tls listen")
 		return
 	}
 
@@ -128,7 +144,7 @@ func tlsListen(network, address, certFile, keyFile string) {
 
 	server := &http.Server{
 		Handler:           Handler,
-		TLSConfig:         &tls.Config{Certificates: []tls.Certificate{cert}},
+		TLSConfig:         config,
 		ReadHeaderTimeout: 5 * time.Second,
 	}
 	if err = server.ServeTLS(ln, "", ""); err != nil {
@@ -156,6 +172,12 @@ func HandleFunc(pattern string, handler http.HandlerFunc) {
 	http.HandleFunc(pattern, handler)
 }
 
+// HandleHeaderFunc handle requests to unregistered paths with special header.
+// Useful for protocols over HTTP that can use any path (RTSP over HTTP tunneling).
+func HandleHeaderFunc(header string, handler http.HandlerFunc) {
+	headerHandlers = append(headerHandlers, headerHandler{header, handler})
+}
+
 // ResponseJSON important always add Content-Type
 // so go won't need to call http.DetectContentType
 func ResponseJSON(w http.ResponseWriter, v any) {
@@ -188,6 +210,13 @@ const StreamNotFound = "stream not found"
 var basePath string
 var log zerolog.Logger
 
+type headerHandler struct {
+	header  string
+	handler http.HandlerFunc
+}
+
+var headerHandlers []headerHandler
+
 func middlewareLog(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		log.Trace().Msgf("[api] %s %s %s", r.Method, r.URL, r.RemoteAddr)
@@ -213,8 +242,9 @@ func middlewareAuth(username, password string, next http.Handler) http.Handler {
 func middlewareCORS(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Authorization, Content-Type")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Authorization, Content-Type, If-Match")
+		w.Header().Set("Access-Control-Expose-Headers", "Location, ETag, Link")
 		next.ServeHTTP(w, r)
 	})
 }
@@ -19,6 +19,13 @@ func initStatic(staticDir string) {
 	fileServer := http.FileServer(root)
 
 	HandleFunc("", func(w http.ResponseWriter, r *http.Request) {
+		for _, h := range headerHandlers {
+			if r.Header.Get(h.header) != "" {
+				h.handler(w, r)
+				return
+			}
+		}
+
 		if base > 0 {
 			r.URL.Path = r.URL.Path[base:]
 		}
@@ -45,6 +45,7 @@ func Init() {
 	})
 
 	streams.HandleFunc("exec", execHandle)
+	streams.HandleKeyframeFunc("exec", keyframeHandle)
 
 	log = app.GetLogger("exec")
 }
@@ -102,6 +103,29 @@ func execHandle(rawURL string) (prod core.Producer, err error) {
 	return
 }
 
+// keyframeHandle - forward keyframe request to internal streams used by command
+// (ex. `exec:ffmpeg -i rtsp://127.0.0.1:8554/camera1 ...`)
+func keyframeHandle(rawURL string) error {
+	if rtsp.Port == "" {
+		return nil
+	}
+
+	prefix := "rtsp://127.0.0.1:" + rtsp.Port + "/"
+
+	for _, field := range strings.Fields(rawURL) {
+		name, ok := strings.CutPrefix(strings.Trim(field, `"'`), prefix)
+		if !ok {
+			continue
+		}
+		name, _, _ = strings.Cut(name, "?")
+		if stream := streams.Get(name); stream != nil {
+			stream.RequestKeyframe()
+		}
+	}
+
+	return nil
+}
+
 func handlePipe(source string, cmd *shell.Command) (core.Producer, error) {
 	stdout, err := cmd.StdoutPipe()
 	if err != nil {
@@ -50,6 +50,7 @@ func Init() {
 	})
 
 	streams.HandleFunc("ffmpeg", NewProducer)
+	streams.HandleKeyframeFunc("ffmpeg", keyframeHandler)
 
 	api.HandleFunc("api/ffmpeg", apiFFmpeg)
 
@@ -151,6 +152,16 @@ var defaults = map[string]string{
 
 var log zerolog.Logger
 
+// keyframeHandler - forward keyframe request to the input stream,
+// transcoded output gets it only with `#raw=-force_key_frames source`
+func keyframeHandler(source string) error {
+	name, _, _ := strings.Cut(source[7:], "#")
+	if stream := streams.Get(name); stream != nil {
+		stream.RequestKeyframe()
+	}
+	return nil
+}
+
 // configTemplate - return template from config (defaults) if exist or return raw template
 func configTemplate(template string) string {
 	if s := defaults[template]; s != "" {
@@ -172,14 +183,31 @@ func inputTemplate(name, s string, query url.Values) string {
 	return strings.Replace(template, "{input}", s, 1)
 }
 
-func parseArgs(s string) *ffmpeg.Args {
-	// init FFmpeg arguments
-	args := &ffmpeg.Args{
+// NewArgs - FFmpeg arguments with binary, global params and RTSP output from config,
+// for modules that build their own pipelines
+func NewArgs() *ffmpeg.Args {
+	return &ffmpeg.Args{
 		Bin:     defaults["bin"],
 		Global:  defaults["global"],
 		Output:  defaults["output"],
 		Version: verAV,
 	}
+}
+
+// StreamInput - video input from internal RTSP server by stream name
+func StreamInput(name, source string) string {
+	s := "rtsp://127.0.0.1:" + rtsp.Port + "/" + name + "?video&source=" + url.QueryEscape(source)
+	return "-allowed_media_types video " + inputTemplate("rtsp", s, nil)
+}
+
+// Template - codec, input or output template from config or raw value
+func Template(name string) string {
+	return configTemplate(name)
+}
+
+func parseArgs(s string) *ffmpeg.Args {
+	// init FFmpeg arguments
+	args := NewArgs()
 
 	var source = s
 	var query url.Values
@@ -297,7 +325,7 @@ func parseArgs(s string) *ffmpeg.Args {
 			drawtext = configTemplate(drawtext)
 
 			// support default timestamp format
-			if !strings.Contains(drawtext, "text=") {
+			if !strings.Contains(drawtext, "text=") && !strings.Contains(drawtext, "textfile=") {
 				drawtext += `:text='%{localtime\:%Y-%m-%d %X}'`
 			}
 
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"net/url"
 	"os/exec"
+	"strconv"
 
 	"github.com/hamza-farouk/go2rtc/internal/ffmpeg/hardware"
 	"github.com/hamza-farouk/go2rtc/pkg/core"
@@ -17,12 +18,33 @@ func JPEGWithQuery(b []byte, query url.Values) ([]byte, error) {
 	return transcode(b, args.String())
 }
 
+// JPEG - decode H264/H265 keyframe to JPEG without filters, with optional hardware decoding
+func JPEG(b []byte, hw string) ([]byte, error) {
+	args := defaultArgs()
+	if hw != "" {
+		hardware.MakeHardware(args, hw, defaults)
+	}
+	return transcode(b, args.String())
+}
+
 func JPEGWithScale(b []byte, width, height int) ([]byte, error) {
 	args := defaultArgs()
 	args.AddFilter(fmt.Sprintf("scale=%d:%d", width, height))
 	return transcode(b, args.String())
 }
 
+// WebP - animated WebP from JPEG frames, FFmpeg should be built with libwebp
+func WebP(frames [][]byte, fps int) ([]byte, error) {
+	args := &ffmpeg.Args{
+		Bin:    defaults["bin"],
+		Global: defaults["global"],
+		Input:  "-f mjpeg -framerate " + strconv.Itoa(fps) + " -i -",
+		Codecs: []string{"-c:v libwebp -loop 0"},
+		Output: "-f webp -",
+	}
+	return transcode(bytes.Join(frames, nil), args.String())
+}
+
 func transcode(b []byte, args string) ([]byte, error) {
 	cmdArgs := shell.QuoteSplit(args)
 	cmd := exec.Command(cmdArgs[0], cmdArgs[1:]...)
@@ -0,0 +1,141 @@
+package hls
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hamza-farouk/go2rtc/pkg/core"
+	"github.com/hamza-farouk/go2rtc/pkg/h264"
+	"github.com/hamza-farouk/go2rtc/pkg/h265"
+)
+
+// abr - adaptive bitrate groups from config, name => variant stream names
+var abr map[string][]string
+
+// abrTimelines - shared timeline for each ABR group, name => timeline
+var abrTimelines map[string]*core.Timeline
+
+// abrSegmentDuration - grid step of ABR timeline
+const abrSegmentDuration = time.Second
+
+// mainABR - master playlist with one variant per stream. Variants start
+// together and are cut on the shared timeline of the group, so segments
+// started on the same keyframes have the same sequence numbers and program
+// date time, and players can switch between them.
+func (s *Session) mainABR() []byte {
+	// wait first segment for real bandwidth
+	var wg sync.WaitGroup
+	for _, segmenter := range s.segmenters {
+		wg.Add(1)
+		go func(segmenter *Segmenter) {
+			segmenter.Ready()
+			wg.Done()
+		}(segmenter)
+	}
+	wg.Wait()
+
+	sb := &strings.Builder{}
+	sb.WriteString("#EXTM3U\n")
+
+	for i, segmenter := range s.segmenters {
+		width, height := segmenter.Resolution()
+
+		peak, average := segmenter.Bandwidth()
+		if peak == 0 {
+			peak = estimateBandwidth(width, height)
+			average = peak
+		}
+
+		fmt.Fprintf(sb, "#EXT-X-STREAM-INF:BANDWIDTH=%d,AVERAGE-BANDWIDTH=%d", peak, average)
+		if width > 0 && height > 0 {
+			fmt.Fprintf(sb, ",RESOLUTION=%dx%d", width, height)
+		}
+		fmt.Fprintf(sb, ",CODECS=\"%s\"\nhls/playlist.m3u8?id=%s&v=%d\n", segmenter.Codecs(), s.id, i)
+	}
+
+	return []byte(sb.String())
+}
+
+// Ready - wait first complete segment
+func (s *Segmenter) Ready() {
+	if s.fmp4 != nil {
+		s.fmp4.WaitComplete(1, blockTimeout)
+	} else {
+		s.ts.WaitComplete(1, blockTimeout)
+	}
+}
+
+// Resolution - from SPS of video codec
+func (s *Segmenter) Resolution() (width, height uint16) {
+	for _, codec := range s.cons.(interface{ Codecs() []*core.Codec }).Codecs() {
+		switch codec.Name {
+		case core.CodecH264:
+			if sps, _ := h264.GetParameterSet(codec.FmtpLine); sps != nil {
+				if info := h264.DecodeSPS(sps); info != nil {
+					return info.Width(), info.Height()
+				}
+			}
+		case core.CodecH265:
+			if _, sps, _ := h265.GetParameterSet(codec.FmtpLine); sps != nil {
+				if info := h265.DecodeSPS(sps); info != nil {
+					return info.Width(), info.Height()
+				}
+			}
+		}
+	}
+	return
+}
+
+// Bandwidth - peak and average bits per second of complete segments
+func (s *Segmenter) Bandwidth() (peak, average int) {
+	var sizes []int
+	var durations []time.Duration
+
+	if s.fmp4 != nil {
+		for _, segment := range s.fmp4.Segments() {
+			if segment.Complete {
+				sizes = append(sizes, segment.Size)
+				durations = append(durations, segment.Duration)
+			}
+		}
+	} else {
+		for _, segment := range s.ts.Segments() {
+			if segment.Complete {
+				sizes = append(sizes, segment.Size)
+				durations = append(durations, segment.Duration)
+			}
+		}
+	}
+
+	var totalSize int
+	var totalDuration time.Duration
+
+	for i, size := range sizes {
+		if durations[i] <= 0 {
+			continue
+		}
+		peak = max(peak, bitrate(size, durations[i]))
+		totalSize += size
+		totalDuration += durations[i]
+	}
+
+	if totalDuration > 0 {
+		average = bitrate(totalSize, totalDuration)
+	}
+
+	return
+}
+
+func bitrate(size int, duration time.Duration) int {
+	return int(float64(size*8) / duration.Seconds())
+}
+
+// estimateBandwidth - about 2 bits per pixel per second, when there is no real data
+func estimateBandwidth(width, height uint16) int {
+	if width == 0 || height == 0 {
+		return 192000
+	}
+	return 2 * int(width) * int(height)
+}
@@ -0,0 +1,100 @@
+package hls
+
+import (
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"github.com/hamza-farouk/go2rtc/pkg/core"
+	"github.com/hamza-farouk/go2rtc/pkg/mp4"
+	"github.com/pion/rtp"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestVariant - fMP4 segmenter with 3 seconds of 30 fps H264 video,
+// keyframe every second and fixed frame size
+func newTestVariant(t *testing.T, sps string, frameSize int, timeline *core.Timeline) *Segmenter {
+	codec := &core.Codec{
+		Name: core.CodecH264, ClockRate: 90000,
+		FmtpLine: "sprop-parameter-sets=" + sps + ",aM48gA==",
+	}
+
+	muxer := &mp4.Muxer{}
+	muxer.AddTrack(codec)
+
+	init, err := muxer.GetInit()
+	require.Nil(t, err)
+
+	segmenter := mp4.NewSegmenter()
+	segmenter.Timeline = timeline
+	_, _ = segmenter.Write(init)
+
+	for i := 0; i <= 90; i++ {
+		payload := make([]byte, frameSize)
+		binary.BigEndian.PutUint32(payload, uint32(frameSize-4))
+		if i%30 == 0 {
+			payload[4] = 0x65
+		} else {
+			payload[4] = 0x41
+		}
+
+		packet := &rtp.Packet{
+			Header:  rtp.Header{Timestamp: uint32(3000 * (i + 1))},
+			Payload: payload,
+		}
+		_, _ = segmenter.Write(muxer.GetPayload(0, packet))
+	}
+
+	media := &core.Media{Kind: core.KindVideo, Direction: core.DirectionSendonly}
+	cons := mp4.NewConsumer(nil)
+	cons.Senders = append(cons.Senders, core.NewSender(media, codec))
+
+	return &Segmenter{id: core.RandString(8, 62), cons: cons, fmp4: segmenter}
+}
+
+func TestBandwidth(t *testing.T) {
+	s := newTestVariant(t, "Z01AMqaAKAC1kAA=", 1000, nil)
+
+	// 30 frames per second, 1000 bytes per frame, plus moof headers
+	peak, average := s.Bandwidth()
+	require.InEpsilon(t, 240000, peak, 0.15)
+	require.InEpsilon(t, 240000, average, 0.15)
+	require.GreaterOrEqual(t, peak, average)
+
+	require.Equal(t, 192000, estimateBandwidth(0, 0))
+	require.Equal(t, 2*1920*1080, estimateBandwidth(1920, 1080))
+}
+
+func TestResolution(t *testing.T) {
+	s := newTestVariant(t, "Z01AMqaAKAC1kAA=", 100, nil)
+	width, height := s.Resolution()
+	require.Equal(t, uint16(2560), width)
+	require.Equal(t, uint16(1440), height)
+}
+
+func TestMainABR(t *testing.T) {
+	timeline := core.NewTimeline(time.Second)
+
+	high := newTestVariant(t, "Z01AMqaAKAC1kAA=", 2000, timeline)
+	low := newTestVariant(t, "R00AKZmgHgCJ+WEAAAMD6AAATiCE", 500, timeline)
+
+	session := &Session{id: "abc", segmenters: []*Segmenter{high, low}}
+	main := string(session.mainABR())
+
+	require.Contains(t, main, "#EXTM3U\n")
+	require.Regexp(t, `BANDWIDTH=\d+,AVERAGE-BANDWIDTH=\d+,RESOLUTION=2560x1440,CODECS="avc1\.\w+"\nhls/playlist\.m3u8\?id=abc&v=0\n`, main)
+	require.Regexp(t, `BANDWIDTH=\d+,AVERAGE-BANDWIDTH=\d+,RESOLUTION=1920x1080,CODECS="avc1\.\w+"\nhls/playlist\.m3u8\?id=abc&v=1\n`, main)
+
+	highPeak, _ := high.Bandwidth()
+	lowPeak, _ := low.Bandwidth()
+	require.Greater(t, highPeak, lowPeak)
+
+	// variants on the shared timeline have same sequences and program date time
+	highSegments := high.fmp4.Segments()
+	lowSegments := low.fmp4.Segments()
+	require.Len(t, lowSegments, len(highSegments))
+	for i := range highSegments {
+		require.Equal(t, highSegments[i].Sequence, lowSegments[i].Sequence)
+		require.Equal(t, highSegments[i].Time, lowSegments[i].Time)
+	}
+}
@@ -0,0 +1,249 @@
+package hls
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/hamza-farouk/go2rtc/internal/api"
+	"github.com/hamza-farouk/go2rtc/internal/streams"
+	"github.com/hamza-farouk/go2rtc/pkg/core"
+	"github.com/hamza-farouk/go2rtc/pkg/mp4"
+)
+
+// handlerDASH - live MPEG-DASH with CMAF segments from the same fMP4 segmenters
+// as HLS, one segmenter per media (video and audio adaptation sets). Players
+// reload MPD every minimumUpdatePeriod, so each request holds the segmenters
+// for a keepalive period with a lightweight session.
+func handlerDASH(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Content-Type", "application/dash+xml")
+	w.Header().Set("Cache-Control", "no-cache")
+
+	if r.Method == "OPTIONS" {
+		w.Header().Set("Access-Control-Allow-Methods", "GET")
+		return
+	}
+
+	query := r.URL.Query()
+
+	stream := streams.Get(query.Get("src"))
+	if stream == nil {
+		http.Error(w, api.StreamNotFound, http.StatusNotFound)
+		return
+	}
+
+	// DASH supports only fMP4, default codecs same as for HLS with mp4 param
+	if !query.Has("mp4") {
+		query.Set("mp4", "")
+	}
+
+	var segmenters []*Segmenter
+
+	for _, media := range mp4.ParseQuery(query) {
+		medias := []*core.Media{media}
+		segmenter, err := getSegmenter(stream, mediasKey("fmp4", medias), nil, func() core.Consumer {
+			c := mp4.NewConsumer(medias)
+			c.FormatName = "dash/fmp4"
+			c.WithRequest(r)
+			return c
+		})
+		if err != nil {
+			// ex. stream without audio
+			log.Trace().Err(err).Msgf("[hls] skip dash %s", media.Kind)
+			continue
+		}
+		segmenters = append(segmenters, segmenter)
+	}
+
+	if len(segmenters) == 0 {
+		http.Error(w, "can't find media for dash", http.StatusNotFound)
+		return
+	}
+
+	NewSession(segmenters...)
+
+	data := MPD(segmenters)
+	if data == nil {
+		log.Warn().Msgf("[hls] can't get mpd %s", r.URL.RawQuery)
+		http.NotFound(w, r)
+		return
+	}
+
+	if _, err := w.Write(data); err != nil {
+		log.Error().Err(err).Caller().Send()
+	}
+}
+
+// adaptationSet - one media of DASH stream from its own segmenter
+type adaptationSet struct {
+	id        string // segmenter ID
+	kind      string // video or audio
+	codecs    string
+	bandwidth int
+	width     uint16
+	height    uint16
+	zero      time.Time // wall clock of media time zero (tfdt = 0)
+	segments  []segmentTime
+}
+
+// MPD - dynamic manifest with SegmentTimeline of complete segments
+// https://dashif.org/docs/DASH-IF-IOP-v4.3.pdf
+func MPD(segmenters []*Segmenter) []byte {
+	var sets []*adaptationSet
+	var target, update time.Duration
+
+	for _, s := range segmenters {
+		s.Ready()
+
+		set := &adaptationSet{id: s.id, codecs: s.Codecs()}
+
+		for _, segment := range s.fmp4.Segments() {
+			if segment.Complete {
+				set.segments = append(set.segments, segmentTime{
+					number: segment.Sequence,
+					start:  segment.DTS,
+					end:    segment.DTS + segment.Duration,
+					time:   segment.Time,
+				})
+			}
+		}
+		if len(set.segments) == 0 {
+			continue
+		}
+
+		first := set.segments[0]
+		set.zero = first.time.Add(-first.start)
+
+		// segmenter has single media, so all codecs have the same kind
+		for _, codec := range s.cons.(interface{ Codecs() []*core.Codec }).Codecs() {
+			set.kind = codec.Kind()
+		}
+		switch set.kind {
+		case core.KindVideo:
+			set.width, set.height = s.Resolution()
+		case "":
+			continue
+		}
+
+		if set.bandwidth, _ = s.Bandwidth(); set.bandwidth == 0 {
+			set.bandwidth = estimateBandwidth(set.width, set.height)
+		}
+
+		target = max(target, s.TargetDuration())
+		update = max(update, s.fmp4.SegmentDuration)
+
+		sets = append(sets, set)
+	}
+
+	if len(sets) == 0 {
+		return nil
+	}
+
+	return writeMPD(sets, target, update, time.Now())
+}
+
+func writeMPD(sets []*adaptationSet, target, update time.Duration, now time.Time) []byte {
+	// Each media has own consumer with decode time from zero. S@t is the real
+	// media time (tfdt), and presentationTimeOffset moves it to the period
+	// timeline. Period time zero is the latest of media zeros, so all offsets
+	// are positive and segment is available at AST + S@t - PTO + S@d, right
+	// after it was cut.
+	availabilityStart := sets[0].zero
+	for _, set := range sets[1:] {
+		if set.zero.After(availabilityStart) {
+			availabilityStart = set.zero
+		}
+	}
+
+	// time shift buffer - shortest window of all media
+	var depth time.Duration
+	for i, set := range sets {
+		first := set.segments[0]
+		last := set.segments[len(set.segments)-1]
+		if d := last.end - first.start; i == 0 || d < depth {
+			depth = d
+		}
+	}
+
+	latency := target + target/2 // one segment in buffer and one in progress
+
+	sb := &strings.Builder{}
+	sb.WriteString(`<?xml version="1.0" encoding="UTF-8"?>` + "\n")
+	fmt.Fprintf(sb, `<MPD xmlns="urn:mpeg:dash:schema:mpd:2011" profiles="urn:mpeg:dash:profile:isoff-live:2011" type="dynamic"`+
+		` availabilityStartTime="%s" publishTime="%s" minimumUpdatePeriod="%s" timeShiftBufferDepth="%s"`+
+		` maxSegmentDuration="%s" minBufferTime="%s" suggestedPresentationDelay="%s">`+"\n",
+		availabilityStart.UTC().Format(timeFormat), now.UTC().Format(timeFormat), isoDuration(update),
+		isoDuration(depth), isoDuration(target), isoDuration(update), isoDuration(latency),
+	)
+
+	// low latency settings for dash.js and ExoPlayer
+	fmt.Fprintf(sb, `<ServiceDescription id="0"><Latency target="%d" min="%d" max="%d"/>`+
+		`<PlaybackRate min="0.96" max="1.04"/></ServiceDescription>`+"\n",
+		latency.Milliseconds(), target.Milliseconds(), 3*target.Milliseconds(),
+	)
+
+	sb.WriteString(`<Period id="0" start="PT0S">` + "\n")
+
+	for i, set := range sets {
+		fmt.Fprintf(sb, `<AdaptationSet id="%d" contentType="%s" mimeType="%s/mp4" segmentAlignment="true" startWithSAP="1">`+"\n",
+			i, set.kind, set.kind,
+		)
+		fmt.Fprintf(sb, `<SegmentTemplate timescale="1000" presentationTimeOffset="%d" startNumber="%d" initialization="dash/init.mp4?id=%s" media="dash/segment.m4s?id=%s&amp;n=$Number$">`+"\n",
+			availabilityStart.Sub(set.zero).Milliseconds(), set.segments[0].number, set.id, set.id,
+		)
+		sb.WriteString("<SegmentTimeline>\n")
+		writeTimeline(sb, set.segments)
+		sb.WriteString("</SegmentTimeline>\n</SegmentTemplate>\n")
+
+		fmt.Fprintf(sb, `<Representation id="%d" bandwidth="%d" codecs="%s"`, i, set.bandwidth, set.codecs)
+		if set.width > 0 && set.height > 0 {
+			fmt.Fprintf(sb, ` width="%d" height="%d"`, set.width, set.height)
+		}
+		sb.WriteString("/>\n</AdaptationSet>\n")
+	}
+
+	sb.WriteString("</Period>\n")
+
+	// server time in the manifest itself, without additional requests
+	fmt.Fprintf(sb, `<UTCTiming schemeIdUri="urn:mpeg:dash:utc:direct:2014" value="%s"/>`+"\n", now.UTC().Format(timeFormat))
+	sb.WriteString("</MPD>\n")
+
+	return []byte(sb.String())
+}
+
+type segmentTime struct {
+	number     int
+	start, end time.Duration
+	time       time.Time
+}
+
+// writeTimeline - segments media time in milliseconds, with repeat count for equal durations
+func writeTimeline(sb *strings.Builder, segments []segmentTime) {
+	for i := 0; i < len(segments); {
+		t := segments[i].start.Milliseconds()
+		d := segments[i].end.Milliseconds() - segments[i].start.Milliseconds()
+
+		r := 0
+		for i+r+1 < len(segments) {
+			next := segments[i+r+1]
+			if next.end.Milliseconds()-next.start.Milliseconds() != d {
+				break
+			}
+			r++
+		}
+
+		if r > 0 {
+			fmt.Fprintf(sb, `<S t="%d" d="%d" r="%d"/>`+"\n", t, d, r)
+		} else {
+			fmt.Fprintf(sb, `<S t="%d" d="%d"/>`+"\n", t, d)
+		}
+
+		i += r + 1
+	}
+}
+
+func isoDuration(d time.Duration) string {
+	return fmt.Sprintf("PT%.3fS", d.Seconds())
+}
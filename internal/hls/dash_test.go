@@ -0,0 +1,134 @@
+package hls
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hamza-farouk/go2rtc/pkg/core"
+	"github.com/hamza-farouk/go2rtc/pkg/iso"
+	"github.com/hamza-farouk/go2rtc/pkg/mp4"
+	"github.com/pion/rtp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteTimeline(t *testing.T) {
+	segments := []segmentTime{
+		{start: 0, end: 2 * time.Second},
+		{start: 2 * time.Second, end: 4 * time.Second},
+		{start: 4 * time.Second, end: 6 * time.Second},
+		{start: 6 * time.Second, end: 7500 * time.Millisecond},
+	}
+
+	sb := &strings.Builder{}
+	writeTimeline(sb, segments)
+	require.Equal(t, `<S t="0" d="2000" r="2"/>`+"\n"+`<S t="6000" d="1500"/>`+"\n", sb.String())
+}
+
+func TestMPD(t *testing.T) {
+	zero := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	video := &adaptationSet{
+		id: "video1", kind: core.KindVideo, codecs: "avc1.640028",
+		bandwidth: 4000000, width: 1920, height: 1080,
+		zero: zero,
+		segments: []segmentTime{
+			{number: 5, start: 0, end: 2 * time.Second},
+			{number: 6, start: 2 * time.Second, end: 4 * time.Second},
+		},
+	}
+	audio := &adaptationSet{
+		id: "audio1", kind: core.KindAudio, codecs: "mp4a.40.2",
+		bandwidth: 128000,
+		zero:      zero.Add(20 * time.Millisecond), // audio started a bit later
+		segments: []segmentTime{
+			{number: 3, start: 0, end: time.Second},
+			{number: 4, start: time.Second, end: 2 * time.Second},
+		},
+	}
+
+	mpd := string(writeMPD([]*adaptationSet{video, audio}, 2*time.Second, time.Second, zero.Add(5*time.Second)))
+
+	// latest media zero
+	require.Contains(t, mpd, `availabilityStartTime="2024-01-01T00:00:00.020Z"`)
+	require.Contains(t, mpd, `timeShiftBufferDepth="PT2.000S"`)
+
+	// separate adaptation sets with own segmenter and mime type from codec kind
+	require.Contains(t, mpd, `<AdaptationSet id="0" contentType="video" mimeType="video/mp4"`)
+	require.Contains(t, mpd, `presentationTimeOffset="20" startNumber="5" initialization="dash/init.mp4?id=video1" media="dash/segment.m4s?id=video1&amp;n=$Number$"`)
+	require.Contains(t, mpd, `<S t="0" d="2000" r="1"/>`)
+	require.Contains(t, mpd, `<Representation id="0" bandwidth="4000000" codecs="avc1.640028" width="1920" height="1080"/>`)
+
+	require.Contains(t, mpd, `<AdaptationSet id="1" contentType="audio" mimeType="audio/mp4"`)
+	require.Contains(t, mpd, `presentationTimeOffset="0" startNumber="3" initialization="dash/init.mp4?id=audio1"`)
+	require.Contains(t, mpd, `<S t="0" d="1000" r="1"/>`)
+	require.Contains(t, mpd, `<Representation id="1" bandwidth="128000" codecs="mp4a.40.2"/>`)
+}
+
+func TestMPDDecodeTime(t *testing.T) {
+	codec := &core.Codec{Name: core.CodecH264, ClockRate: 90000}
+
+	muxer := &mp4.Muxer{}
+	muxer.AddTrack(codec)
+
+	init, err := muxer.GetInit()
+	require.Nil(t, err)
+
+	segmenter := mp4.NewSegmenter()
+	_, _ = segmenter.Write(init)
+
+	// stream starts from non keyframes, so first segment has non zero tfdt
+	for i := 20; i <= 120; i++ {
+		payload := []byte{0, 0, 0, 1, 0x41}
+		if i%30 == 0 {
+			payload[4] = 0x65
+		}
+		packet := &rtp.Packet{
+			Header:  rtp.Header{Timestamp: uint32(3000 * i)},
+			Payload: payload,
+		}
+		_, _ = segmenter.Write(muxer.GetPayload(0, packet))
+	}
+
+	media := &core.Media{Kind: core.KindVideo, Direction: core.DirectionSendonly}
+	cons := mp4.NewConsumer(nil)
+	cons.Senders = append(cons.Senders, core.NewSender(media, codec))
+
+	s := &Segmenter{id: "video1", cons: cons, fmp4: segmenter}
+	mpd := string(MPD([]*Segmenter{s}))
+
+	m := regexp.MustCompile(`presentationTimeOffset="(\d+)" startNumber="(\d+)"`).FindStringSubmatch(mpd)
+	require.NotNil(t, m)
+	require.Equal(t, "0", m[1]) // single media
+	number, _ := strconv.Atoi(m[2])
+
+	// S@t of every segment should be tfdt of the segment in milliseconds
+	var starts []int64
+	for _, m := range regexp.MustCompile(`<S t="(\d+)" d="(\d+)"(?: r="(\d+)")?/>`).FindAllStringSubmatch(mpd, -1) {
+		t0, _ := strconv.ParseInt(m[1], 10, 64)
+		d, _ := strconv.ParseInt(m[2], 10, 64)
+		r, _ := strconv.Atoi(m[3])
+		for i := 0; i <= r; i++ {
+			starts = append(starts, t0+int64(i)*d)
+		}
+	}
+	require.Len(t, starts, 3)
+
+	for i, start := range starts {
+		atoms, err := iso.DecodeAtoms(segmenter.Segment(number+i, 0))
+		require.Nil(t, err)
+
+		var tfdt *iso.AtomTfdt
+		for _, atom := range atoms {
+			if atom, ok := atom.(*iso.AtomTfdt); ok {
+				tfdt = atom
+				break
+			}
+		}
+		require.NotNil(t, tfdt)
+		require.NotZero(t, tfdt.DecodeTime)
+		require.Equal(t, int64(tfdt.DecodeTime*1000/90000), start)
+	}
+}
@@ -2,6 +2,7 @@ package hls
 
 import (
 	"net/http"
+	"strconv"
 	"sync"
 	"time"
 
@@ -16,6 +17,20 @@ import (
 )
 
 func Init() {
+	var cfg struct {
+		Mod struct {
+			ABR map[string][]string `yaml:"abr"` // group name => variant stream names
+		} `yaml:"hls"`
+	}
+
+	app.LoadConfig(&cfg)
+
+	abr = cfg.Mod.ABR
+	abrTimelines = map[string]*core.Timeline{}
+	for name := range abr {
+		abrTimelines[name] = core.NewTimeline(abrSegmentDuration)
+	}
+
 	log = app.GetLogger("hls")
 
 	api.HandleFunc("api/stream.m3u8", handlerStream)
@@ -28,6 +43,11 @@ func Init() {
 	api.HandleFunc("api/hls/init.mp4", handlerInit)
 	api.HandleFunc("api/hls/segment.m4s", handlerSegmentMP4)
 
+	// MPEG-DASH (CMAF) with the same segmenters
+	api.HandleFunc("api/stream.mpd", handlerDASH)
+	api.HandleFunc("api/dash/init.mp4", handlerInit)
+	api.HandleFunc("api/dash/segment.m4s", handlerSegmentMP4)
+
 	ws.HandleFunc("hls", handlerWSHLS)
 }
 
@@ -35,6 +55,9 @@ var log zerolog.Logger
 
 const keepalive = 5 * time.Second
 
+// blockTimeout - max time for LL-HLS blocking playlist reload and preload hint requests
+const blockTimeout = 3 * time.Second
+
 // once I saw 404 on MP4 segment, so better to use mutex
 var sessions = map[string]*Session{}
 var sessionsMu sync.RWMutex
@@ -49,48 +72,69 @@ func handlerStream(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	src := r.URL.Query().Get("src")
-	stream := streams.Get(src)
-	if stream == nil {
-		http.Error(w, api.StreamNotFound, http.StatusNotFound)
-		return
-	}
+	query := r.URL.Query()
+	src := query.Get("src")
 
-	var cons core.Consumer
+	// ABR group or single stream
+	names, ok := abr[src]
+	if !ok {
+		names = []string{src}
+	}
 
 	// use fMP4 with codecs filter and TS without
-	medias := mp4.ParseQuery(r.URL.Query())
+	medias := mp4.ParseQuery(query)
+	format := "mpegts"
 	if medias != nil {
-		c := mp4.NewConsumer(medias)
-		c.FormatName = "hls/fmp4"
-		c.WithRequest(r)
-		cons = c
-	} else {
-		c := mpegts.NewConsumer()
-		c.FormatName = "hls/mpegts"
-		c.WithRequest(r)
-		cons = c
+		format = "fmp4"
 	}
+	key := mediasKey(format, medias)
 
-	if err := stream.AddConsumer(cons); err != nil {
-		log.Error().Err(err).Caller().Send()
-		return
+	// ABR variants have own segmenters on the shared timeline of the group
+	timeline := abrTimelines[src]
+	if timeline != nil {
+		key += "|abr=" + src
 	}
 
-	session := NewSession(cons)
-	session.alive = time.AfterFunc(keepalive, func() {
-		sessionsMu.Lock()
-		delete(sessions, session.id)
-		sessionsMu.Unlock()
-
-		stream.RemoveConsumer(cons)
-	})
+	var variants []*streams.Stream
+	for _, name := range names {
+		stream := streams.Get(name)
+		if stream == nil {
+			http.Error(w, api.StreamNotFound, http.StatusNotFound)
+			return
+		}
+		variants = append(variants, stream)
+	}
 
-	sessionsMu.Lock()
-	sessions[session.id] = session
-	sessionsMu.Unlock()
+	segmenters := make([]*Segmenter, len(variants))
+	errs := make([]error, len(variants))
+
+	// start all variants together
+	var wg sync.WaitGroup
+	for i, stream := range variants {
+		wg.Add(1)
+		go func(i int, stream *streams.Stream) {
+			segmenters[i], errs[i] = getSegmenter(stream, key, timeline, func() core.Consumer {
+				return newConsumer(r, format, medias)
+			})
+			wg.Done()
+		}(i, stream)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err == nil {
+			continue
+		}
+		for _, segmenter := range segmenters {
+			if segmenter != nil {
+				releaseSegmenter(segmenter)
+			}
+		}
+		log.Error().Err(err).Caller().Send()
+		return
+	}
 
-	go session.Run()
+	session := NewSession(segmenters...)
 
 	if _, err := w.Write(session.Main()); err != nil {
 		log.Error().Err(err).Caller().Send()
@@ -100,6 +144,7 @@ func handlerStream(w http.ResponseWriter, r *http.Request) {
 func handlerPlaylist(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Access-Control-Allow-Origin", "*")
 	w.Header().Set("Content-Type", "application/vnd.apple.mpegurl")
+	w.Header().Set("Cache-Control", "no-cache")
 
 	if r.Method == "OPTIONS" {
 		w.Header().Set("Access-Control-Allow-Methods", "GET")
@@ -115,43 +160,43 @@ func handlerPlaylist(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if _, err := w.Write(session.Playlist()); err != nil {
-		log.Error().Err(err).Caller().Send()
-	}
-}
+	release := session.Hold()
+	defer release()
 
-func handlerSegmentTS(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Access-Control-Allow-Origin", "*")
-	w.Header().Set("Content-Type", "video/mp2t")
+	query := r.URL.Query()
 
-	if r.Method == "OPTIONS" {
-		w.Header().Set("Access-Control-Allow-Methods", "GET")
+	// variant index for ABR session
+	v, _ := strconv.Atoi(query.Get("v"))
+	segmenter := session.Segmenter(v)
+	if segmenter == nil {
+		http.NotFound(w, r)
 		return
 	}
 
-	sid := r.URL.Query().Get("id")
-	sessionsMu.RLock()
-	session := sessions[sid]
-	sessionsMu.RUnlock()
-	if session == nil {
-		http.NotFound(w, r)
+	data, err := segmenter.Playlist(query)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-
-	session.alive.Reset(keepalive)
-
-	data := session.Segment()
 	if data == nil {
-		log.Warn().Msgf("[hls] can't get segment %s", r.URL.RawQuery)
+		log.Warn().Msgf("[hls] can't get playlist %s", r.URL.RawQuery)
 		http.NotFound(w, r)
 		return
 	}
 
-	if _, err := w.Write(data); err != nil {
+	if _, err = w.Write(data); err != nil {
 		log.Error().Err(err).Caller().Send()
 	}
 }
 
+func handlerSegmentTS(w http.ResponseWriter, r *http.Request) {
+	handlerSegment(w, r, "video/mp2t")
+}
+
+func handlerSegmentMP4(w http.ResponseWriter, r *http.Request) {
+	handlerSegment(w, r, "video/iso.segment")
+}
+
 func handlerInit(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Access-Control-Allow-Origin", "*")
 	w.Header().Add("Content-Type", "video/mp4")
@@ -161,30 +206,32 @@ func handlerInit(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	sid := r.URL.Query().Get("id")
-	sessionsMu.RLock()
-	session := sessions[sid]
-	sessionsMu.RUnlock()
-	if session == nil {
+	segmenter := findSegmenter(r.URL.Query().Get("id"))
+	if segmenter == nil {
 		http.NotFound(w, r)
 		return
 	}
 
-	data := session.Init()
+	data := segmenter.Init()
 	if data == nil {
 		log.Warn().Msgf("[hls] can't get init %s", r.URL.RawQuery)
 		http.NotFound(w, r)
 		return
 	}
 
+	w.Header().Set("Cache-Control", cacheControl)
+
 	if _, err := w.Write(data); err != nil {
 		log.Error().Err(err).Caller().Send()
 	}
 }
 
-func handlerSegmentMP4(w http.ResponseWriter, r *http.Request) {
+// cacheControl - segment URLs contain unique segmenter ID, so data never changes
+const cacheControl = "public, max-age=3600, immutable"
+
+func handlerSegment(w http.ResponseWriter, r *http.Request, contentType string) {
 	w.Header().Set("Access-Control-Allow-Origin", "*")
-	w.Header().Add("Content-Type", "video/iso.segment")
+	w.Header().Add("Content-Type", contentType)
 
 	if r.Method == "OPTIONS" {
 		w.Header().Set("Access-Control-Allow-Methods", "GET")
@@ -193,25 +240,59 @@ func handlerSegmentMP4(w http.ResponseWriter, r *http.Request) {
 
 	query := r.URL.Query()
 
-	sid := query.Get("id")
-	sessionsMu.RLock()
-	session := sessions[sid]
-	sessionsMu.RUnlock()
-	if session == nil {
+	segmenter := findSegmenter(query.Get("id"))
+	if segmenter == nil {
 		http.NotFound(w, r)
 		return
 	}
 
-	session.alive.Reset(keepalive)
+	n, err := strconv.Atoi(query.Get("n"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
-	data := session.Segment()
+	p := -1
+	if v := query.Get("p"); v != "" {
+		if p, err = strconv.Atoi(v); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	data := segmenter.Segment(n, p)
 	if data == nil {
 		log.Warn().Msgf("[hls] can't get segment %s", r.URL.RawQuery)
 		http.NotFound(w, r)
 		return
 	}
 
-	if _, err := w.Write(data); err != nil {
+	w.Header().Set("Cache-Control", cacheControl)
+
+	if _, err = w.Write(data); err != nil {
 		log.Error().Err(err).Caller().Send()
 	}
 }
+
+// newConsumer - fMP4 consumer with codecs filter and TS consumer without
+func newConsumer(r *http.Request, format string, medias []*core.Media) core.Consumer {
+	if format == "fmp4" {
+		c := mp4.NewConsumer(medias)
+		c.FormatName = "hls/fmp4"
+		c.WithRequest(r)
+		return c
+	}
+	c := mpegts.NewConsumer()
+	c.FormatName = "hls/mpegts"
+	c.WithRequest(r)
+	return c
+}
+
+// mediasKey - segmenters with the same stream, consumer format and medias can be shared
+func mediasKey(format string, medias []*core.Media) string {
+	key := format
+	for _, media := range medias {
+		key += "|" + media.String()
+	}
+	return key
+}
@@ -0,0 +1,326 @@
+package hls
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hamza-farouk/go2rtc/internal/streams"
+	"github.com/hamza-farouk/go2rtc/pkg/core"
+	"github.com/hamza-farouk/go2rtc/pkg/mp4"
+	"github.com/hamza-farouk/go2rtc/pkg/mpegts"
+)
+
+// Segmenter - shared consumer and segments window for all sessions of one
+// stream with the same format. Segment URLs contain the segmenter ID, so they
+// are the same for all viewers and can be cached.
+type Segmenter struct {
+	id  string
+	key string
+
+	stream *streams.Stream
+	cons   core.Consumer
+
+	fmp4 *mp4.Segmenter
+	ts   *mpegts.Segmenter
+
+	sessions int
+	done     bool // consumer finished
+}
+
+// segmenters - by segmenter ID, protected with sessionsMu for reading
+var segmenters = map[string]*Segmenter{}
+
+// segmentersMu - protects segmenters creation, without blocking other requests
+var segmentersMu sync.Mutex
+
+// pendingSegmenters - segmenters with consumer in creation, so other requests for
+// the same stream and key wait for it instead of creating own consumer
+var pendingSegmenters = map[pendingKey]*pendingSegmenter{}
+
+type pendingKey struct {
+	stream *streams.Stream
+	key    string
+}
+
+type pendingSegmenter struct {
+	waiters int
+	done    chan struct{}
+	s       *Segmenter
+	err     error
+}
+
+// getSegmenter - find running segmenter or create new one, should be released
+// with releaseSegmenter. Consumer is created and added to the stream without
+// global lock, because it may take a long time to start the stream producers.
+// Optional timeline aligns segments of ABR variants, should be part of the key.
+func getSegmenter(stream *streams.Stream, key string, timeline *core.Timeline, newConsumer func() core.Consumer) (*Segmenter, error) {
+	segmentersMu.Lock()
+
+	for _, s := range segmenters {
+		if s.stream == stream && s.key == key && !s.done {
+			s.sessions++
+			segmentersMu.Unlock()
+			return s, nil
+		}
+	}
+
+	pk := pendingKey{stream: stream, key: key}
+	if p := pendingSegmenters[pk]; p != nil {
+		p.waiters++
+		segmentersMu.Unlock()
+
+		// creator counts waiters as sessions
+		<-p.done
+		return p.s, p.err
+	}
+
+	p := &pendingSegmenter{done: make(chan struct{})}
+	pendingSegmenters[pk] = p
+	segmentersMu.Unlock()
+
+	cons := newConsumer()
+	err := stream.AddConsumer(cons)
+
+	segmentersMu.Lock()
+	defer segmentersMu.Unlock()
+
+	delete(pendingSegmenters, pk)
+	defer close(p.done)
+
+	if err != nil {
+		p.err = err
+		return nil, err
+	}
+
+	s := &Segmenter{
+		id:       core.RandString(8, 62),
+		key:      key,
+		stream:   stream,
+		cons:     cons,
+		sessions: 1 + p.waiters,
+	}
+	p.s = s
+
+	var wr io.Writer
+	if _, ok := cons.(*mp4.Consumer); ok {
+		s.fmp4 = mp4.NewSegmenter()
+		if timeline != nil {
+			s.fmp4.Timeline = timeline
+			s.fmp4.SegmentDuration = timeline.Duration
+		}
+		wr = s.fmp4
+	} else {
+		s.ts = mpegts.NewSegmenter()
+		if timeline != nil {
+			s.ts.Timeline = timeline
+			s.ts.SegmentDuration = timeline.Duration
+		}
+		wr = s.ts
+	}
+
+	sessionsMu.Lock()
+	segmenters[s.id] = s
+	sessionsMu.Unlock()
+
+	go func() {
+		_, _ = cons.(io.WriterTo).WriteTo(wr)
+
+		segmentersMu.Lock()
+		s.done = true
+		segmentersMu.Unlock()
+
+		s.close()
+	}()
+
+	log.Trace().Msgf("[hls] new segmenter id=%s key=%s", s.id, key)
+
+	return s, nil
+}
+
+func releaseSegmenter(s *Segmenter) {
+	segmentersMu.Lock()
+	s.sessions--
+	if s.sessions > 0 {
+		segmentersMu.Unlock()
+		return
+	}
+	sessionsMu.Lock()
+	delete(segmenters, s.id)
+	sessionsMu.Unlock()
+	segmentersMu.Unlock()
+
+	log.Trace().Msgf("[hls] stop segmenter id=%s", s.id)
+
+	s.stream.RemoveConsumer(s.cons)
+	s.close()
+}
+
+func findSegmenter(id string) *Segmenter {
+	sessionsMu.RLock()
+	defer sessionsMu.RUnlock()
+	return segmenters[id]
+}
+
+func (s *Segmenter) close() {
+	if s.fmp4 != nil {
+		s.fmp4.Close()
+	} else {
+		s.ts.Close()
+	}
+}
+
+func (s *Segmenter) Codecs() string {
+	type withCodecs interface {
+		Codecs() []*core.Codec
+	}
+
+	codecs := mp4.MimeCodecs(s.cons.(withCodecs).Codecs())
+	return strings.Replace(codecs, mp4.MimeFlac, "fLaC", 1)
+}
+
+// TargetDuration - max segment duration in the window
+func (s *Segmenter) TargetDuration() (target time.Duration) {
+	if s.fmp4 != nil {
+		target = s.fmp4.SegmentDuration
+		for _, segment := range s.fmp4.Segments() {
+			if segment.Duration > target {
+				target = segment.Duration
+			}
+		}
+	} else {
+		target = s.ts.SegmentDuration
+		for _, segment := range s.ts.Segments() {
+			if segment.Duration > target {
+				target = segment.Duration
+			}
+		}
+	}
+	return
+}
+
+func (s *Segmenter) Init() []byte {
+	if s.fmp4 == nil {
+		return nil
+	}
+	return s.fmp4.Init(blockTimeout)
+}
+
+// Segment - wait and return segment or part (p >= 0) of fMP4 segment
+func (s *Segmenter) Segment(n, p int) []byte {
+	switch {
+	case s.ts != nil:
+		return s.ts.Segment(n, blockTimeout)
+	case p >= 0:
+		return s.fmp4.Part(n, p, blockTimeout)
+	}
+	return s.fmp4.Segment(n, blockTimeout)
+}
+
+// Playlist - TS playlist or Low-Latency HLS playlist with blocking reload support
+// https://datatracker.ietf.org/doc/html/draft-pantos-hls-rfc8216bis#section-6.2.5.2
+func (s *Segmenter) Playlist(query url.Values) ([]byte, error) {
+	if s.ts != nil {
+		// two segments important for Chromecast
+		s.ts.WaitComplete(2, blockTimeout)
+		return s.playlistTS(), nil
+	}
+
+	if v := query.Get("_HSN"); v != "" {
+		msn, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, err
+		}
+
+		part := -1
+		if v = query.Get("_HSP"); v != "" {
+			if part, err = strconv.Atoi(v); err != nil {
+				return nil, err
+			}
+		}
+
+		s.fmp4.Wait(msn, part, blockTimeout)
+	} else {
+		// non LL-HLS players need at least one complete segment
+		s.fmp4.WaitComplete(1, blockTimeout)
+	}
+
+	return s.playlistLL(), nil
+}
+
+func (s *Segmenter) playlistTS() []byte {
+	segments := s.ts.Segments()
+	if len(segments) < 2 {
+		return nil
+	}
+
+	sb := &strings.Builder{}
+	sb.WriteString("#EXTM3U\n#EXT-X-VERSION:3\n")
+	fmt.Fprintf(sb, "#EXT-X-TARGETDURATION:%d\n", targetDuration(s.TargetDuration()))
+	fmt.Fprintf(sb, "#EXT-X-MEDIA-SEQUENCE:%d\n", segments[0].Sequence)
+
+	for _, segment := range segments {
+		if segment.Complete {
+			fmt.Fprintf(sb, "#EXT-X-PROGRAM-DATE-TIME:%s\n", segment.Time.Format(timeFormat))
+			fmt.Fprintf(sb, "#EXTINF:%.3f,\nsegment.ts?id=%s&n=%d\n", segment.Duration.Seconds(), s.id, segment.Sequence)
+		}
+	}
+
+	return []byte(sb.String())
+}
+
+func (s *Segmenter) playlistLL() []byte {
+	segments := s.fmp4.Segments()
+	if len(segments) < 2 {
+		return nil
+	}
+
+	partTarget := s.fmp4.PartDuration.Seconds()
+
+	sb := &strings.Builder{}
+	sb.WriteString("#EXTM3U\n#EXT-X-VERSION:6\n")
+	fmt.Fprintf(sb, "#EXT-X-TARGETDURATION:%d\n", targetDuration(s.TargetDuration()))
+	fmt.Fprintf(sb, "#EXT-X-SERVER-CONTROL:CAN-BLOCK-RELOAD=YES,PART-HOLD-BACK=%.3f\n", 3*partTarget)
+	fmt.Fprintf(sb, "#EXT-X-PART-INF:PART-TARGET=%.3f\n", partTarget)
+	fmt.Fprintf(sb, "#EXT-X-MEDIA-SEQUENCE:%d\n", segments[0].Sequence)
+	fmt.Fprintf(sb, "#EXT-X-MAP:URI=\"init.mp4?id=%s\"\n", s.id)
+
+	// parts only for last segments, it's enough for PART-HOLD-BACK
+	withParts := len(segments) - 3
+
+	for i, segment := range segments {
+		// important for switching between ABR variants
+		if segment.Complete || len(segment.Parts) > 0 {
+			fmt.Fprintf(sb, "#EXT-X-PROGRAM-DATE-TIME:%s\n", segment.Time.Format(timeFormat))
+		}
+
+		if i >= withParts {
+			for j, part := range segment.Parts {
+				fmt.Fprintf(sb, "#EXT-X-PART:DURATION=%.3f,URI=\"segment.m4s?id=%s&n=%d&p=%d\"", part.Duration.Seconds(), s.id, segment.Sequence, j)
+				if part.Independent {
+					sb.WriteString(",INDEPENDENT=YES")
+				}
+				sb.WriteByte('\n')
+			}
+		}
+
+		if segment.Complete {
+			fmt.Fprintf(sb, "#EXTINF:%.3f,\nsegment.m4s?id=%s&n=%d\n", segment.Duration.Seconds(), s.id, segment.Sequence)
+		} else {
+			fmt.Fprintf(sb, "#EXT-X-PRELOAD-HINT:TYPE=PART,URI=\"segment.m4s?id=%s&n=%d&p=%d\"\n", s.id, segment.Sequence, len(segment.Parts))
+		}
+	}
+
+	return []byte(sb.String())
+}
+
+const timeFormat = "2006-01-02T15:04:05.000Z07:00"
+
+func targetDuration(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
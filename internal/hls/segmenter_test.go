@@ -0,0 +1,71 @@
+package hls
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hamza-farouk/go2rtc/internal/streams"
+	"github.com/hamza-farouk/go2rtc/pkg/core"
+	"github.com/hamza-farouk/go2rtc/pkg/mpegts"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetSegmenterPending(t *testing.T) {
+	stream := streams.NewStream(nil) // without producers, so AddConsumer fails
+	pk := pendingKey{stream: stream, key: "mpegts"}
+
+	var calls atomic.Int32
+	release := make(chan struct{})
+
+	newConsumer := func() core.Consumer {
+		calls.Add(1)
+		<-release
+		return mpegts.NewConsumer()
+	}
+
+	waiters := func() int {
+		segmentersMu.Lock()
+		defer segmentersMu.Unlock()
+		if p := pendingSegmenters[pk]; p != nil {
+			return p.waiters
+		}
+		return -1
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 5)
+	get := func() {
+		defer wg.Done()
+		_, err := getSegmenter(stream, "mpegts", nil, newConsumer)
+		errs <- err
+	}
+
+	wg.Add(1)
+	go get()
+	require.Eventually(t, func() bool { return waiters() == 0 }, time.Second, time.Millisecond)
+
+	// other requests don't block on global lock and don't create own consumers
+	wg.Add(4)
+	for i := 0; i < 4; i++ {
+		go get()
+	}
+	require.Eventually(t, func() bool { return waiters() == 4 }, time.Second, time.Millisecond)
+
+	close(release)
+	wg.Wait()
+	close(errs)
+
+	require.Equal(t, int32(1), calls.Load())
+	for err := range errs {
+		require.Error(t, err)
+	}
+	require.Equal(t, -1, waiters())
+}
+
+func TestMediasKey(t *testing.T) {
+	require.Equal(t, "mpegts", mediasKey("mpegts", nil))
+	// WebSocket HLS creates fMP4 consumer even without codecs
+	require.Equal(t, "fmp4", mediasKey("fmp4", nil))
+}
@@ -1,127 +1,93 @@
 package hls
 
 import (
-	"fmt"
-	"io"
-	"strings"
 	"sync"
 	"time"
 
 	"github.com/hamza-farouk/go2rtc/pkg/core"
-	"github.com/hamza-farouk/go2rtc/pkg/mp4"
 )
 
+// Session - lightweight viewer cursor with keepalive timer. All sessions of
+// one stream with the same format use shared Segmenter. ABR session has
+// one segmenter per variant.
 type Session struct {
-	cons     core.Consumer
-	id       string
-	template string
-	init     []byte
-	buffer   []byte
-	seq      int
-	alive    *time.Timer
-	mu       sync.Mutex
+	id         string
+	segmenters []*Segmenter
+	alive      *time.Timer
+	requests   int
+	closed     bool
+	mu         sync.Mutex
 }
 
-func NewSession(cons core.Consumer) *Session {
+func NewSession(segmenters ...*Segmenter) *Session {
 	s := &Session{
-		id:   core.RandString(8, 62),
-		cons: cons,
+		id:         core.RandString(8, 62),
+		segmenters: segmenters,
 	}
 
-	// two segments important for Chromecast
-	if _, ok := cons.(*mp4.Consumer); ok {
-		s.template = `#EXTM3U
-#EXT-X-VERSION:6
-#EXT-X-TARGETDURATION:1
-#EXT-X-MEDIA-SEQUENCE:%d
-#EXT-X-MAP:URI="init.mp4?id=` + s.id + `"
-#EXTINF:0.500,
-segment.m4s?id=` + s.id + `&n=%d
-#EXTINF:0.500,
-segment.m4s?id=` + s.id + `&n=%d`
-	} else {
-		s.template = `#EXTM3U
-#EXT-X-VERSION:3
-#EXT-X-TARGETDURATION:1
-#EXT-X-MEDIA-SEQUENCE:%d
-#EXTINF:0.500,
-segment.ts?id=` + s.id + `&n=%d
-#EXTINF:0.500,
-segment.ts?id=` + s.id + `&n=%d`
-	}
+	s.alive = time.AfterFunc(keepalive, s.close)
+
+	sessionsMu.Lock()
+	sessions[s.id] = s
+	sessionsMu.Unlock()
 
 	return s
 }
 
-func (s *Session) Write(p []byte) (n int, err error) {
+func (s *Session) close() {
 	s.mu.Lock()
-	if s.init == nil {
-		s.init = p
-	} else {
-		s.buffer = append(s.buffer, p...)
+	if s.closed {
+		s.mu.Unlock()
+		return
 	}
+	s.closed = true
 	s.mu.Unlock()
-	return len(p), nil
-}
 
-func (s *Session) Run() {
-	_, _ = s.cons.(io.WriterTo).WriteTo(s)
-}
+	sessionsMu.Lock()
+	delete(sessions, s.id)
+	sessionsMu.Unlock()
 
-func (s *Session) Main() []byte {
-	type withCodecs interface {
-		Codecs() []*core.Codec
+	for _, segmenter := range s.segmenters {
+		releaseSegmenter(segmenter)
 	}
-
-	codecs := mp4.MimeCodecs(s.cons.(withCodecs).Codecs())
-	codecs = strings.Replace(codecs, mp4.MimeFlac, "fLaC", 1)
-
-	// bandwidth important for Safari, codecs useful for smooth playback
-	return []byte(`#EXTM3U
-#EXT-X-STREAM-INF:BANDWIDTH=192000,CODECS="` + codecs + `"
-hls/playlist.m3u8?id=` + s.id)
 }
 
-func (s *Session) Playlist() []byte {
-	return []byte(fmt.Sprintf(s.template, s.seq, s.seq, s.seq+1))
+// Segmenter - variant segmenter, nil if index is wrong
+func (s *Session) Segmenter(i int) *Segmenter {
+	if i < 0 || i >= len(s.segmenters) {
+		return nil
+	}
+	return s.segmenters[i]
 }
 
-func (s *Session) Init() (init []byte) {
-	for i := 0; i < 60 && init == nil; i++ {
-		if i > 0 {
-			time.Sleep(50 * time.Millisecond)
-		}
+// Hold - stop keepalive timer while blocking request is running
+func (s *Session) Hold() (release func()) {
+	s.mu.Lock()
+	s.requests++
+	s.alive.Stop()
+	s.mu.Unlock()
 
+	return func() {
 		s.mu.Lock()
-		// return init only when have some buffer
-		if len(s.buffer) > 0 {
-			init = s.init
+		if s.requests--; s.requests == 0 && !s.closed {
+			// player reloads playlist once per target duration
+			var target time.Duration
+			for _, segmenter := range s.segmenters {
+				target = max(target, segmenter.TargetDuration())
+			}
+			s.alive.Reset(keepalive + target)
 		}
 		s.mu.Unlock()
 	}
-
-	return
 }
 
-func (s *Session) Segment() (segment []byte) {
-	for i := 0; i < 60 && segment == nil; i++ {
-		if i > 0 {
-			time.Sleep(50 * time.Millisecond)
-		}
-
-		s.mu.Lock()
-		if len(s.buffer) > 0 {
-			segment = s.buffer
-			if _, ok := s.cons.(*mp4.Consumer); ok {
-				s.buffer = nil
-			} else {
-				// for TS important to start new segment with init
-				s.buffer = s.init
-			}
-			s.seq++
-		}
-		s.mu.Unlock()
+func (s *Session) Main() []byte {
+	if len(s.segmenters) > 1 {
+		return s.mainABR()
 	}
 
-	return
+	// bandwidth important for Safari, codecs useful for smooth playback
+	return []byte(`#EXTM3U
+#EXT-X-STREAM-INF:BANDWIDTH=192000,CODECS="` + s.segmenters[0].Codecs() + `"
+hls/playlist.m3u8?id=` + s.id)
 }
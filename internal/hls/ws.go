@@ -2,11 +2,11 @@ package hls
 
 import (
 	"errors"
-	"time"
 
 	"github.com/hamza-farouk/go2rtc/internal/api"
 	"github.com/hamza-farouk/go2rtc/internal/api/ws"
 	"github.com/hamza-farouk/go2rtc/internal/streams"
+	"github.com/hamza-farouk/go2rtc/pkg/core"
 	"github.com/hamza-farouk/go2rtc/pkg/mp4"
 )
 
@@ -18,32 +18,20 @@ func handlerWSHLS(tr *ws.Transport, msg *ws.Message) error {
 
 	codecs := msg.String()
 	medias := mp4.ParseCodecs(codecs, true)
-	cons := mp4.NewConsumer(medias)
-	cons.FormatName = "hls/fmp4"
-	cons.WithRequest(tr.Request)
-
 	log.Trace().Msgf("[hls] new ws consumer codecs=%s", codecs)
 
-	if err := stream.AddConsumer(cons); err != nil {
+	segmenter, err := getSegmenter(stream, mediasKey("fmp4", medias), nil, func() core.Consumer {
+		cons := mp4.NewConsumer(medias)
+		cons.FormatName = "hls/fmp4"
+		cons.WithRequest(tr.Request)
+		return cons
+	})
+	if err != nil {
 		log.Error().Err(err).Caller().Send()
 		return err
 	}
 
-	session := NewSession(cons)
-
-	session.alive = time.AfterFunc(keepalive, func() {
-		sessionsMu.Lock()
-		delete(sessions, session.id)
-		sessionsMu.Unlock()
-
-		stream.RemoveConsumer(cons)
-	})
-
-	sessionsMu.Lock()
-	sessions[session.id] = session
-	sessionsMu.Unlock()
-
-	go session.Run()
+	session := NewSession(segmenter)
 
 	main := session.Main()
 	tr.Write(&ws.Message{Type: "hls", Value: string(main)})
@@ -10,6 +10,7 @@ import (
 	"github.com/hamza-farouk/go2rtc/internal/api"
 	"github.com/hamza-farouk/go2rtc/internal/streams"
 	"github.com/hamza-farouk/go2rtc/pkg/core"
+	"github.com/hamza-farouk/go2rtc/pkg/dash"
 	"github.com/hamza-farouk/go2rtc/pkg/hls"
 	"github.com/hamza-farouk/go2rtc/pkg/image"
 	"github.com/hamza-farouk/go2rtc/pkg/magic"
@@ -37,16 +38,16 @@ func handleHTTP(rawURL string) (core.Producer, error) {
 		return nil, err
 	}
 
-	if rawQuery != "" {
-		query := streams.ParseQuery(rawQuery)
+	query := streams.ParseQuery(rawQuery)
 
+	if rawQuery != "" {
 		for _, header := range query["header"] {
 			key, value, _ := strings.Cut(header, ":")
 			req.Header.Add(key, strings.TrimSpace(value))
 		}
 	}
 
-	prod, err := do(req)
+	prod, err := do(req, query)
 	if err != nil {
 		return nil, err
 	}
@@ -60,7 +61,7 @@ func handleHTTP(rawURL string) (core.Producer, error) {
 	return prod, nil
 }
 
-func do(req *http.Request) (core.Producer, error) {
+func do(req *http.Request, query url.Values) (core.Producer, error) {
 	res, err := tcp.Do(req)
 	if err != nil {
 		return nil, err
@@ -83,7 +84,9 @@ func do(req *http.Request) (core.Producer, error) {
 
 	switch {
 	case ct == "application/vnd.apple.mpegurl" || ext == "m3u8":
-		return hls.OpenURL(req.URL, res.Body)
+		return hls.OpenURL(req.URL, res.Body, query)
+	case ct == "application/dash+xml" || ext == "mpd":
+		return dash.OpenURL(req.URL, res.Body, query)
 	case ct == "image/jpeg":
 		return image.Open(res)
 	case ct == "multipart/x-mixed-replace":
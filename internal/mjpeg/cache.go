@@ -0,0 +1,232 @@
+package mjpeg
+
+import (
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/hamza-farouk/go2rtc/internal/api"
+	"github.com/hamza-farouk/go2rtc/internal/ffmpeg"
+	"github.com/hamza-farouk/go2rtc/internal/streams"
+	"github.com/hamza-farouk/go2rtc/pkg/core"
+	"github.com/hamza-farouk/go2rtc/pkg/magic"
+	"github.com/hamza-farouk/go2rtc/pkg/mjpeg"
+)
+
+// keyframeTimeout - max time to wait keyframe from the camera
+const keyframeTimeout = 10 * time.Second
+
+// keyframe - get one frame from stream and convert it to JPEG
+func keyframe(stream *streams.Stream, r *http.Request, hw string) ([]byte, error) {
+	cons := magic.NewKeyframe()
+	if r != nil {
+		cons.WithRequest(r)
+	}
+
+	if err := stream.AddConsumer(cons); err != nil {
+		return nil, err
+	}
+
+	// unblock WriteTo if camera doesn't send frames
+	timer := time.AfterFunc(keyframeTimeout, func() {
+		_ = cons.Stop()
+	})
+
+	once := &core.OnceBuffer{} // init and first frame
+	_, _ = cons.WriteTo(once)
+	b := once.Buffer()
+
+	timer.Stop()
+	stream.RemoveConsumer(cons)
+
+	if b == nil {
+		return nil, errors.New("mjpeg: can't get keyframe")
+	}
+
+	switch cons.CodecName() {
+	case core.CodecH264, core.CodecH265:
+		// FFmpeg only decodes keyframe, all processing done in Go
+		ts := time.Now()
+		var err error
+		if b, err = ffmpeg.JPEG(b, hw); err != nil {
+			return nil, err
+		}
+		log.Debug().Msgf("[mjpeg] transcoding time=%s", time.Since(ts))
+	case core.CodecJPEG:
+		b = mjpeg.FixJPEG(b)
+	}
+
+	return b, nil
+}
+
+// snapshot - cached JPEG of stream with processed variants for different params
+type snapshot struct {
+	name    string
+	data    []byte
+	time    time.Time
+	outputs map[string][]byte // by ETag
+	tags    []string          // outputs order, oldest first
+
+	mu    sync.Mutex // protects data
+	fetch sync.Mutex // one camera request at a time
+}
+
+var cache = map[string]*snapshot{}
+var cacheMu sync.Mutex
+
+func getSnapshot(name string) *snapshot {
+	cacheMu.Lock()
+	defer cacheMu.Unlock()
+
+	s := cache[name]
+	if s == nil {
+		s = &snapshot{name: name}
+		cache[name] = s
+	}
+	return s
+}
+
+// Get - cached JPEG if it's not older than maxAge, or new one from the stream.
+// Concurrent requests wait for the same camera request.
+func (s *snapshot) Get(stream *streams.Stream, maxAge time.Duration, hw string) ([]byte, time.Time, error) {
+	s.fetch.Lock()
+	defer s.fetch.Unlock()
+
+	s.mu.Lock()
+	data, ts := s.data, s.time
+	s.mu.Unlock()
+
+	if data != nil && time.Since(ts) < maxAge {
+		return data, ts, nil
+	}
+
+	return s.update(stream, hw)
+}
+
+func (s *snapshot) Refresh(stream *streams.Stream) error {
+	s.fetch.Lock()
+	defer s.fetch.Unlock()
+
+	_, _, err := s.update(stream, "")
+	return err
+}
+
+func (s *snapshot) update(stream *streams.Stream, hw string) ([]byte, time.Time, error) {
+	b, err := keyframe(stream, nil, hw)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	ts := time.Now()
+
+	s.mu.Lock()
+	s.data = b
+	s.time = ts
+	s.outputs = map[string][]byte{}
+	s.tags = nil
+	s.mu.Unlock()
+
+	return b, ts, nil
+}
+
+// maxOutputs - processed variants to keep for one snapshot
+const maxOutputs = 8
+
+// Output - processed JPEG from cache or new one
+func (s *snapshot) Output(etag string, data []byte, transform *mjpeg.Transform) ([]byte, error) {
+	s.mu.Lock()
+	b, ok := s.outputs[etag]
+	s.mu.Unlock()
+
+	if ok {
+		return b, nil
+	}
+
+	b = data
+	if !transform.IsEmpty() {
+		var err error
+		if b, err = transform.JPEG(b); err != nil {
+			return nil, err
+		}
+	}
+
+	s.mu.Lock()
+	if _, ok = s.outputs[etag]; !ok {
+		s.outputs[etag] = b
+		s.tags = append(s.tags, etag)
+		if len(s.tags) > maxOutputs {
+			delete(s.outputs, s.tags[0])
+			s.tags = s.tags[1:]
+		}
+	}
+	s.mu.Unlock()
+
+	return b, nil
+}
+
+// etag - unique for snapshot time and processing params, other query params
+// don't change the output, so they can't create new cache entries
+func etag(ts time.Time, query url.Values) string {
+	params := url.Values{}
+	for _, keys := range [][]string{
+		{"width", "w"}, {"height", "h"}, {"crop"}, {"rotate"}, {"text"}, {"timestamp"}, {"quality"},
+	} {
+		for _, key := range keys {
+			if query.Has(key) {
+				params.Set(keys[0], query.Get(key))
+				break
+			}
+		}
+	}
+	return fmt.Sprintf(`"%x-%08x"`, ts.UnixNano(), crc32.ChecksumIEEE([]byte(params.Encode())))
+}
+
+// refresher - update snapshot in background with interval
+func refresher(name string, interval time.Duration) {
+	s := getSnapshot(name)
+
+	for range time.Tick(interval) {
+		stream := streams.Get(name)
+		if stream == nil {
+			continue
+		}
+
+		if err := s.Refresh(stream); err != nil {
+			log.Debug().Err(err).Msgf("[mjpeg] refresh snapshot name=%s", name)
+		}
+	}
+}
+
+// apiSnapshots - cache state for all streams
+func apiSnapshots(w http.ResponseWriter, _ *http.Request) {
+	type info struct {
+		Time    time.Time `json:"time"`
+		Age     float64   `json:"age"` // in seconds
+		Size    int       `json:"size"`
+		MaxAge  int       `json:"max_age,omitempty"`
+		Refresh int       `json:"refresh,omitempty"`
+	}
+
+	cacheMu.Lock()
+	all := make(map[string]*info, len(cache))
+	for name, s := range cache {
+		s.mu.Lock()
+		if s.data != nil {
+			all[name] = &info{
+				Time:    s.time,
+				Age:     time.Since(s.time).Seconds(),
+				Size:    len(s.data),
+				MaxAge:  snapshots[name].MaxAge,
+				Refresh: snapshots[name].Refresh,
+			}
+		}
+		s.mu.Unlock()
+	}
+	cacheMu.Unlock()
+
+	api.ResponseJSON(w, all)
+}
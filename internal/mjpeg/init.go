@@ -11,11 +11,8 @@ import (
 	"github.com/hamza-farouk/go2rtc/internal/api"
 	"github.com/hamza-farouk/go2rtc/internal/api/ws"
 	"github.com/hamza-farouk/go2rtc/internal/app"
-	"github.com/hamza-farouk/go2rtc/internal/ffmpeg"
 	"github.com/hamza-farouk/go2rtc/internal/streams"
 	"github.com/hamza-farouk/go2rtc/pkg/ascii"
-	"github.com/hamza-farouk/go2rtc/pkg/core"
-	"github.com/hamza-farouk/go2rtc/pkg/magic"
 	"github.com/hamza-farouk/go2rtc/pkg/mjpeg"
 	"github.com/hamza-farouk/go2rtc/pkg/mpjpeg"
 	"github.com/hamza-farouk/go2rtc/pkg/y4m"
@@ -23,7 +20,27 @@ import (
 )
 
 func Init() {
+	var cfg struct {
+		Mod struct {
+			Snapshots map[string]snapshotConfig `yaml:"snapshots"`
+		} `yaml:"mjpeg"`
+	}
+
+	app.LoadConfig(&cfg)
+
+	snapshots = cfg.Mod.Snapshots
+
+	for name, conf := range snapshots {
+		if conf.Refresh > 0 {
+			go refresher(name, time.Duration(conf.Refresh)*time.Second)
+		}
+	}
+
 	api.HandleFunc("api/frame.jpeg", handlerKeyframe)
+	api.HandleFunc("api/snapshots", apiSnapshots)
+	api.HandleFunc("api/preview.gif", handlerPreview)
+	api.HandleFunc("api/preview.webp", handlerPreview)
+	api.HandleFunc("api/preview.jpeg", handlerPreview)
 	api.HandleFunc("api/stream.mjpeg", handlerStream)
 	api.HandleFunc("api/stream.ascii", handlerStream)
 	api.HandleFunc("api/stream.y4m", apiStreamY4M)
@@ -36,47 +53,75 @@ func Init() {
 var log zerolog.Logger
 
 func handlerKeyframe(w http.ResponseWriter, r *http.Request) {
-	stream := streams.GetOrPatch(r.URL.Query())
+	query := r.URL.Query()
+
+	// reject wrong processing params before capturing frame
+	if _, err := newTransform(query, time.Time{}); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	stream := streams.GetOrPatch(query)
 	if stream == nil {
 		http.Error(w, api.StreamNotFound, http.StatusNotFound)
 		return
 	}
 
-	cons := magic.NewKeyframe()
-	cons.WithRequest(r)
+	h := w.Header()
+	h.Set("Content-Type", "image/jpeg")
+	h.Set("Cache-Control", "no-cache")
+	h.Set("Connection", "close")
+	h.Set("Pragma", "no-cache")
 
-	if err := stream.AddConsumer(cons); err != nil {
-		log.Error().Err(err).Caller().Send()
-		return
-	}
+	var b []byte
+	var err error
 
-	once := &core.OnceBuffer{} // init and first frame
-	_, _ = cons.WriteTo(once)
-	b := once.Buffer()
+	src := query.Get("src")
 
-	stream.RemoveConsumer(cons)
+	if maxAge := snapshots[src].maxAge(); maxAge > 0 && query.Get("cache") != "0" {
+		s := getSnapshot(src)
+
+		var ts time.Time
+		if b, ts, err = s.Get(stream, maxAge, hardwareParam(query)); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
 
-	switch cons.CodecName() {
-	case core.CodecH264, core.CodecH265:
-		ts := time.Now()
-		var err error
-		if b, err = ffmpeg.JPEGWithQuery(b, r.URL.Query()); err != nil {
+		tag := etag(ts, query)
+		h.Set("ETag", tag)
+		h.Set("Last-Modified", ts.UTC().Format(http.TimeFormat))
+		h.Set("Age", strconv.Itoa(int(time.Since(ts).Seconds())))
+
+		if r.Header.Get("If-None-Match") == tag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
+		transform, _ := newTransform(query, ts)
+		if b, err = s.Output(tag, b, transform); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	} else {
+		if b, err = keyframe(stream, r, hardwareParam(query)); err != nil {
+			log.Error().Err(err).Caller().Send()
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
-		log.Debug().Msgf("[mjpeg] transcoding time=%s", time.Since(ts))
-	case core.CodecJPEG:
-		b = mjpeg.FixJPEG(b)
+
+		if transform, _ := newTransform(query, time.Now()); !transform.IsEmpty() {
+			ts := time.Now()
+			if b, err = transform.JPEG(b); err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			log.Debug().Msgf("[mjpeg] processing time=%s", time.Since(ts))
+		}
 	}
 
-	h := w.Header()
-	h.Set("Content-Type", "image/jpeg")
 	h.Set("Content-Length", strconv.Itoa(len(b)))
-	h.Set("Cache-Control", "no-cache")
-	h.Set("Connection", "close")
-	h.Set("Pragma", "no-cache")
 
-	if _, err := w.Write(b); err != nil {
+	if _, err = w.Write(b); err != nil {
 		log.Error().Err(err).Caller().Send()
 	}
 }
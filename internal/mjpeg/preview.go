@@ -0,0 +1,266 @@
+package mjpeg
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color/palette"
+	"image/draw"
+	"image/gif"
+	"image/jpeg"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hamza-farouk/go2rtc/internal/api"
+	"github.com/hamza-farouk/go2rtc/internal/ffmpeg"
+	"github.com/hamza-farouk/go2rtc/internal/streams"
+	"github.com/hamza-farouk/go2rtc/pkg/core"
+	"github.com/hamza-farouk/go2rtc/pkg/magic"
+	"github.com/hamza-farouk/go2rtc/pkg/mjpeg"
+)
+
+// limits for user params, protect from long requests and huge memory usage
+const (
+	previewMaxFrames   = 100
+	previewMaxDuration = time.Minute
+	previewMaxFPS      = 30
+	previewMaxPixels   = 32 << 20 // all frames, 128 MB for RGBA images
+)
+
+// handlerPreview - animated GIF/WebP or JPEG sprite sheet from N frames over T seconds
+func handlerPreview(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	// reject wrong processing params before capturing frames
+	if _, err := newTransform(query, time.Time{}); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	stream := streams.GetOrPatch(query)
+	if stream == nil {
+		http.Error(w, api.StreamNotFound, http.StatusNotFound)
+		return
+	}
+
+	count := core.Atoi(query.Get("frames"))
+	if count <= 0 {
+		count = 10
+	}
+	count = min(count, previewMaxFrames)
+
+	duration := time.Duration(core.Atoi(query.Get("duration"))) * time.Second
+	if duration <= 0 {
+		duration = 5 * time.Second
+	}
+	duration = min(duration, previewMaxDuration)
+
+	// playback frame rate, default - real time
+	fps := core.Atoi(query.Get("fps"))
+	if fps <= 0 {
+		fps = max(1, int(math.Round(float64(count)/duration.Seconds())))
+	}
+	fps = min(fps, previewMaxFPS)
+
+	// small size by default
+	if !query.Has("width") && !query.Has("w") && !query.Has("height") && !query.Has("h") {
+		query.Set("width", "320")
+	}
+
+	frames, err := sampleFrames(stream, r, count, duration, hardwareParam(query))
+	if err != nil {
+		log.Error().Err(err).Caller().Send()
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	images := make([]image.Image, 0, len(frames))
+	for _, frame := range frames {
+		src, err := jpeg.Decode(bytes.NewReader(frame.data))
+		if err != nil {
+			continue
+		}
+		transform, _ := newTransform(query, frame.time)
+		img := transform.Image(src)
+
+		// all frames have the same size, so check memory for all of them
+		if size := img.Bounds().Size(); size.X*size.Y*len(frames) > previewMaxPixels {
+			http.Error(w, "mjpeg: preview is too big", http.StatusBadRequest)
+			return
+		}
+
+		images = append(images, img)
+	}
+
+	if len(images) == 0 {
+		http.Error(w, "mjpeg: can't decode frames", http.StatusInternalServerError)
+		return
+	}
+
+	var b []byte
+	var contentType string
+
+	switch {
+	case strings.HasSuffix(r.URL.Path, ".gif"):
+		b, err = encodeGIF(images, fps)
+		contentType = "image/gif"
+	case strings.HasSuffix(r.URL.Path, ".webp"):
+		b, err = encodeWebP(images, fps)
+		contentType = "image/webp"
+	default:
+		b, err = encodeSprite(images, core.Atoi(query.Get("columns")))
+		contentType = "image/jpeg"
+	}
+
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	h := w.Header()
+	h.Set("Content-Type", contentType)
+	h.Set("Content-Length", strconv.Itoa(len(b)))
+	h.Set("Cache-Control", "no-cache")
+
+	if _, err = w.Write(b); err != nil {
+		log.Error().Err(err).Caller().Send()
+	}
+}
+
+type frame struct {
+	data []byte
+	time time.Time
+}
+
+// sampler - keeps one frame per interval from keyframe consumer
+type sampler struct {
+	frames   [][]byte
+	times    []time.Time
+	count    int
+	interval time.Duration
+	onFirst  func()
+}
+
+func (s *sampler) Write(b []byte) (int, error) {
+	now := time.Now()
+	if s.times == nil && s.onFirst != nil {
+		s.onFirst()
+	}
+	if n := len(s.times); n > 0 && now.Sub(s.times[n-1]) < s.interval {
+		return len(b), nil
+	}
+
+	s.frames = append(s.frames, append([]byte(nil), b...))
+	s.times = append(s.times, now)
+
+	if len(s.frames) >= s.count {
+		return 0, io.EOF // stop consumer
+	}
+	return len(b), nil
+}
+
+// sampleFrames - same keyframe path as snapshots: all frames for MJPEG,
+// keyframes for H264/H265 (decoded with FFmpeg)
+func sampleFrames(stream *streams.Stream, r *http.Request, count int, duration time.Duration, hw string) ([]frame, error) {
+	cons := magic.NewKeyframe()
+	cons.WithRequest(r)
+
+	if err := stream.AddConsumer(cons); err != nil {
+		return nil, err
+	}
+
+	stop := func() {
+		_ = cons.Stop()
+	}
+
+	// wait for the first frame, then sampling duration from it
+	timer := time.AfterFunc(keyframeTimeout, stop)
+
+	s := &sampler{count: count, interval: duration / time.Duration(count)}
+	s.onFirst = func() {
+		timer.Reset(duration)
+	}
+	_, _ = cons.WriteTo(s)
+
+	timer.Stop()
+	stream.RemoveConsumer(cons)
+
+	if len(s.frames) == 0 {
+		return nil, errors.New("mjpeg: can't get keyframe")
+	}
+
+	frames := make([]frame, 0, len(s.frames))
+
+	for i, b := range s.frames {
+		switch cons.CodecName() {
+		case core.CodecH264, core.CodecH265:
+			var err error
+			if b, err = ffmpeg.JPEG(b, hw); err != nil {
+				return nil, err
+			}
+		case core.CodecJPEG:
+			b = mjpeg.FixJPEG(b)
+		}
+		frames = append(frames, frame{data: b, time: s.times[i]})
+	}
+
+	return frames, nil
+}
+
+func encodeGIF(images []image.Image, fps int) ([]byte, error) {
+	anim := &gif.GIF{}
+	delay := max(2, 100/fps) // in 1/100 of second, browsers ignore lower values
+
+	for _, img := range images {
+		paletted := image.NewPaletted(img.Bounds(), palette.Plan9)
+		draw.FloydSteinberg.Draw(paletted, img.Bounds(), img, img.Bounds().Min)
+		anim.Image = append(anim.Image, paletted)
+		anim.Delay = append(anim.Delay, delay)
+	}
+
+	buf := bytes.NewBuffer(nil)
+	if err := gif.EncodeAll(buf, anim); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// encodeWebP - Go doesn't have WebP encoder, so FFmpeg used for animation
+func encodeWebP(images []image.Image, fps int) ([]byte, error) {
+	frames := make([][]byte, len(images))
+	for i, img := range images {
+		buf := bytes.NewBuffer(nil)
+		if err := jpeg.Encode(buf, img, nil); err != nil {
+			return nil, err
+		}
+		frames[i] = buf.Bytes()
+	}
+	return ffmpeg.WebP(frames, fps)
+}
+
+// encodeSprite - frames in a grid from left to right and from top to bottom
+func encodeSprite(images []image.Image, columns int) ([]byte, error) {
+	if columns <= 0 {
+		columns = int(math.Ceil(math.Sqrt(float64(len(images)))))
+	}
+	columns = min(columns, len(images))
+	rows := (len(images) + columns - 1) / columns
+
+	tile := images[0].Bounds().Size()
+	sprite := image.NewRGBA(image.Rect(0, 0, columns*tile.X, rows*tile.Y))
+
+	for i, img := range images {
+		pt := image.Pt(i%columns*tile.X, i/columns*tile.Y)
+		draw.Draw(sprite, image.Rectangle{Min: pt, Max: pt.Add(tile)}, img, img.Bounds().Min, draw.Src)
+	}
+
+	buf := bytes.NewBuffer(nil)
+	if err := jpeg.Encode(buf, sprite, nil); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
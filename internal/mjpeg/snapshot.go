@@ -0,0 +1,88 @@
+package mjpeg
+
+import (
+	"net/url"
+	"time"
+
+	"github.com/hamza-farouk/go2rtc/pkg/core"
+	"github.com/hamza-farouk/go2rtc/pkg/mjpeg"
+)
+
+// snapshots - per stream settings for JPEG snapshots from config
+var snapshots map[string]snapshotConfig
+
+type snapshotConfig struct {
+	Masks     []string `yaml:"masks"`     // privacy masks, "x,y,width,height" in source pixels
+	Text      string   `yaml:"text"`      // text in the top left corner
+	Timestamp string   `yaml:"timestamp"` // time layout in the top right corner
+	MaxAge    int      `yaml:"max_age"`   // reuse cached snapshot, in seconds
+	Refresh   int      `yaml:"refresh"`   // update cached snapshot in background, in seconds
+}
+
+// maxAge - cache lifetime, background refresh enables cache too
+func (c snapshotConfig) maxAge() time.Duration {
+	if c.MaxAge > 0 {
+		return time.Duration(c.MaxAge) * time.Second
+	}
+	return time.Duration(c.Refresh) * time.Second
+}
+
+const timestampLayout = "2006-01-02 15:04:05"
+
+// newTransform - snapshot processing from stream config and query params.
+// Privacy masks can't be disabled with query params. Timestamp is the capture time.
+func newTransform(query url.Values, ts time.Time) (*mjpeg.Transform, error) {
+	t := &mjpeg.Transform{}
+
+	var text, timestamp string
+
+	if conf, ok := snapshots[query.Get("src")]; ok {
+		for _, s := range conf.Masks {
+			if rect := mjpeg.ParseRect(s); !rect.Empty() {
+				t.Masks = append(t.Masks, rect)
+			}
+		}
+		text = conf.Text
+		timestamp = conf.Timestamp
+	}
+
+	for k, v := range query {
+		switch k {
+		case "width", "w":
+			t.Width = core.Atoi(v[0])
+		case "height", "h":
+			t.Height = core.Atoi(v[0])
+		case "rotate":
+			t.Rotate = core.Atoi(v[0])
+		case "crop":
+			t.Crop = mjpeg.ParseRect(v[0])
+		case "quality":
+			t.Quality = core.Atoi(v[0])
+		case "text":
+			text = v[0]
+		case "timestamp":
+			timestamp = v[0]
+		}
+	}
+
+	if text != "" {
+		t.Overlays = append(t.Overlays, mjpeg.Overlay{Text: text, Position: "top-left"})
+	}
+
+	switch timestamp {
+	case "", "0", "false":
+	case "1", "true":
+		t.Overlays = append(t.Overlays, mjpeg.Overlay{Text: ts.Format(timestampLayout), Position: "top-right"})
+	default:
+		t.Overlays = append(t.Overlays, mjpeg.Overlay{Text: ts.Format(timestamp), Position: "top-right"})
+	}
+
+	return t, t.Validate()
+}
+
+func hardwareParam(query url.Values) string {
+	if hw := query.Get("hardware"); hw != "" {
+		return hw
+	}
+	return query.Get("hw")
+}
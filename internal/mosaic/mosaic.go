@@ -0,0 +1,154 @@
+package mosaic
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hamza-farouk/go2rtc/internal/app"
+	"github.com/hamza-farouk/go2rtc/internal/ffmpeg"
+	"github.com/hamza-farouk/go2rtc/internal/streams"
+	"github.com/hamza-farouk/go2rtc/pkg/core"
+	pkg "github.com/hamza-farouk/go2rtc/pkg/ffmpeg"
+	"github.com/hamza-farouk/go2rtc/pkg/mosaic"
+	"github.com/hamza-farouk/go2rtc/pkg/probe"
+	"github.com/rs/zerolog"
+)
+
+func Init() {
+	log = app.GetLogger("mosaic")
+
+	streams.RedirectFunc("mosaic", func(url string) (string, error) {
+		cfg, err := parseURL(url)
+		if err != nil {
+			return "", err
+		}
+		if cfg.native {
+			return "", nil // force call streams.HandleFunc("mosaic")
+		}
+		if _, err = ffmpeg.Version(); err != nil {
+			return "", err
+		}
+		return "exec:" + cfg.args().String(), nil
+	})
+
+	streams.HandleFunc("mosaic", NewProducer)
+}
+
+var log zerolog.Logger
+
+type config struct {
+	url    string
+	names  []string
+	layout mosaic.Layout
+	width  int
+	height int
+	fps    int
+	video  string // FFmpeg codec template
+	native bool
+}
+
+// parseURL - mosaic:cam1,cam2,cam3,cam4#layout=2x2#width=1920#height=1080#fps=10
+func parseURL(url string) (*config, error) {
+	rawURL, rawQuery, _ := strings.Cut(url[7:], "#")
+	query := streams.ParseQuery(rawQuery)
+
+	cfg := &config{
+		url:    url,
+		width:  1920,
+		height: 1080,
+		fps:    10,
+		video:  "h264",
+		native: query.Has("native"),
+	}
+
+	if rawURL == "" {
+		return nil, errors.New("mosaic: empty streams list")
+	}
+	for _, name := range strings.Split(rawURL, ",") {
+		cfg.names = append(cfg.names, strings.TrimSpace(name))
+	}
+
+	cfg.layout = mosaic.ParseLayout(query.Get("layout"), len(cfg.names))
+
+	if i := core.Atoi(query.Get("width")); i > 0 {
+		cfg.width = i
+	}
+	if i := core.Atoi(query.Get("height")); i > 0 {
+		cfg.height = i
+	}
+	if i := core.Atoi(query.Get("fps")); i > 0 {
+		cfg.fps = i
+	}
+	if s := query.Get("video"); s != "" {
+		cfg.video = s
+	}
+
+	return cfg, nil
+}
+
+// args - managed FFmpeg with one input per tile from internal RTSP server
+// and xstack filter graph. Missing and offline at start tiles replaced with
+// black input. Input that stops later ends by RTSP timeout and xstack keeps
+// its last frame, so one offline camera doesn't stop the whole mosaic.
+func (c *config) args() *pkg.Args {
+	w, h := c.layout.TileSize(c.width, c.height)
+
+	// probe all streams together, so offline cameras don't delay each other
+	online := make([]bool, c.layout.Count())
+
+	var wg sync.WaitGroup
+	for i := range online {
+		if i >= len(c.names) || c.names[i] == "" {
+			continue
+		}
+		stream := streams.Get(c.names[i])
+		if stream == nil {
+			log.Warn().Msgf("[mosaic] stream not found: %s", c.names[i])
+			continue
+		}
+		wg.Add(1)
+		go func(i int) {
+			online[i] = probeStream(stream)
+			wg.Done()
+		}(i)
+	}
+	wg.Wait()
+
+	inputs := make([]string, len(online))
+	for i := range inputs {
+		if online[i] {
+			inputs[i] = ffmpeg.StreamInput(c.names[i], c.url)
+		} else {
+			if i < len(c.names) && c.names[i] != "" {
+				log.Warn().Msgf("[mosaic] stream offline: %s", c.names[i])
+			}
+			inputs[i] = fmt.Sprintf("-re -f lavfi -i color=c=black:s=%dx%d:r=%d", w, h, c.fps)
+		}
+	}
+
+	args := ffmpeg.NewArgs()
+	args.Input = strings.Join(inputs, " ")
+	args.AddCodec(`-filter_complex "` + mosaic.FilterComplex(c.layout, c.width, c.height, c.fps) + `" -map [v]`)
+	args.AddCodec(ffmpeg.Template(c.video))
+	args.AddCodec("-an")
+	return args
+}
+
+// probeHold - keep probed producers running until FFmpeg connects to them
+const probeHold = 10 * time.Second
+
+// probeStream - check that stream has video, so FFmpeg won't fail on its input
+func probeStream(stream *streams.Stream) bool {
+	cons := probe.NewProbe(url.Values{"video": []string{""}})
+	if err := stream.AddConsumer(cons); err != nil {
+		return false
+	}
+	time.AfterFunc(probeHold, func() {
+		stream.RemoveConsumer(cons)
+	})
+	return true
+}
@@ -0,0 +1,254 @@
+package mosaic
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/draw"
+	"image/jpeg"
+	"sync"
+	"time"
+
+	"github.com/hamza-farouk/go2rtc/internal/streams"
+	"github.com/hamza-farouk/go2rtc/pkg/core"
+	"github.com/hamza-farouk/go2rtc/pkg/mjpeg"
+	"github.com/pion/rtp"
+)
+
+const (
+	retryTimeout = 5 * time.Second // reconnect to missing stream
+	staleTimeout = 5 * time.Second // show "no signal" without new frames
+)
+
+// Producer - native MJPEG mosaic, composed in Go from MJPEG streams
+type Producer struct {
+	core.Connection
+	cfg   *config
+	tiles []*tile
+	done  chan struct{}
+	once  sync.Once
+}
+
+func NewProducer(url string) (core.Producer, error) {
+	cfg, err := parseURL(url)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &Producer{
+		Connection: core.Connection{
+			ID:         core.NewID(),
+			FormatName: "mosaic",
+			Medias: []*core.Media{
+				{
+					Kind:      core.KindVideo,
+					Direction: core.DirectionRecvonly,
+					Codecs: []*core.Codec{
+						{
+							Name:        core.CodecJPEG,
+							ClockRate:   90000,
+							PayloadType: core.PayloadTypeRAW,
+						},
+					},
+				},
+			},
+		},
+		cfg:  cfg,
+		done: make(chan struct{}),
+	}
+
+	for i := 0; i < cfg.layout.Count(); i++ {
+		t := &tile{rect: cfg.layout.Tile(i, cfg.width, cfg.height)}
+		if i < len(cfg.names) {
+			t.name = cfg.names[i]
+		}
+		p.tiles = append(p.tiles, t)
+	}
+
+	return p, nil
+}
+
+func (p *Producer) Start() error {
+	if len(p.Receivers) != 1 {
+		return errors.New("mosaic: no receivers")
+	}
+
+	for _, t := range p.tiles {
+		if t.name != "" {
+			go t.run(p.done)
+		}
+	}
+
+	img := image.NewRGBA(image.Rect(0, 0, p.cfg.width, p.cfg.height))
+
+	ticker := time.NewTicker(time.Second / time.Duration(p.cfg.fps))
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.done:
+			return nil
+		case <-ticker.C:
+		}
+
+		now := time.Now()
+		for _, t := range p.tiles {
+			src := t.image(now)
+			draw.Draw(img, t.rect, src, src.Bounds().Min, draw.Src)
+		}
+
+		buf := bytes.NewBuffer(nil)
+		if err := jpeg.Encode(buf, img, nil); err != nil {
+			return err
+		}
+
+		p.Recv += buf.Len()
+
+		packet := &rtp.Packet{
+			Header:  rtp.Header{Timestamp: core.Now90000()},
+			Payload: buf.Bytes(),
+		}
+		p.Receivers[0].WriteRTP(packet)
+	}
+}
+
+func (p *Producer) Stop() error {
+	p.once.Do(func() {
+		close(p.done)
+		for _, t := range p.tiles {
+			t.stop()
+		}
+	})
+	return p.Connection.Stop()
+}
+
+// tile - last frame from one stream, scaled to tile size on demand
+type tile struct {
+	name string
+	rect image.Rectangle
+
+	mu      sync.Mutex
+	cons    *mjpeg.Consumer
+	stopped bool
+	data    []byte
+	time    time.Time
+
+	img     image.Image // scaled frame or "no signal" placeholder
+	imgTime time.Time
+}
+
+// run - subscribe to the stream and reconnect if it's missing or fails
+func (t *tile) run(done <-chan struct{}) {
+	for {
+		if stream := streams.Get(t.name); stream != nil {
+			cons := mjpeg.NewConsumer()
+			if err := stream.AddConsumer(cons); err != nil {
+				log.Debug().Err(err).Msgf("[mosaic] tile=%s", t.name)
+			} else if t.start(cons) {
+				_, _ = cons.WriteTo(t)
+				stream.RemoveConsumer(cons)
+			} else {
+				stream.RemoveConsumer(cons)
+			}
+		} else {
+			log.Debug().Msgf("[mosaic] stream not found: %s", t.name)
+		}
+
+		select {
+		case <-done:
+			return
+		case <-time.After(retryTimeout):
+		}
+	}
+}
+
+func (t *tile) start(cons *mjpeg.Consumer) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.stopped {
+		return false
+	}
+	t.cons = cons
+	return true
+}
+
+func (t *tile) stop() {
+	t.mu.Lock()
+	t.stopped = true
+	if t.cons != nil {
+		_ = t.cons.Stop()
+	}
+	t.mu.Unlock()
+}
+
+func (t *tile) Write(b []byte) (int, error) {
+	t.mu.Lock()
+	t.data = append([]byte(nil), b...)
+	t.time = time.Now()
+	t.mu.Unlock()
+	return len(b), nil
+}
+
+func (t *tile) image(now time.Time) image.Image {
+	t.mu.Lock()
+	data, ts := t.data, t.time
+	t.mu.Unlock()
+
+	if data == nil || now.Sub(ts) > staleTimeout {
+		if t.img == nil || !t.imgTime.IsZero() {
+			t.img = t.placeholder()
+			t.imgTime = time.Time{}
+		}
+		return t.img
+	}
+
+	if ts != t.imgTime {
+		if img := t.decode(data); img != nil {
+			t.img = img
+			t.imgTime = ts
+		}
+	}
+
+	if t.img == nil {
+		t.img = t.placeholder()
+	}
+	return t.img
+}
+
+// decode - frame scaled with keeping aspect ratio and centered on black tile
+func (t *tile) decode(data []byte) image.Image {
+	src, err := jpeg.Decode(bytes.NewReader(mjpeg.FixJPEG(data)))
+	if err != nil {
+		return nil
+	}
+
+	w, h := t.rect.Dx(), t.rect.Dy()
+	sw, sh := src.Bounds().Dx(), src.Bounds().Dy()
+	if sw*h > sh*w {
+		h = max(1, sh*w/sw)
+	} else {
+		w = max(1, sw*h/sh)
+	}
+
+	transform := &mjpeg.Transform{Width: w, Height: h}
+	scaled := transform.Image(src)
+
+	dst := image.NewRGBA(image.Rect(0, 0, t.rect.Dx(), t.rect.Dy()))
+	pt := image.Pt((dst.Rect.Dx()-w)/2, (dst.Rect.Dy()-h)/2)
+	draw.Draw(dst, image.Rectangle{Min: pt, Max: pt.Add(image.Pt(w, h))}, scaled, scaled.Bounds().Min, draw.Src)
+	return dst
+}
+
+// placeholder - black tile with stream name for missing or offline stream
+func (t *tile) placeholder() image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, t.rect.Dx(), t.rect.Dy()))
+	draw.Draw(img, img.Rect, image.Black, image.Point{}, draw.Src)
+	if t.name == "" {
+		return img
+	}
+
+	transform := &mjpeg.Transform{
+		Overlays: []mjpeg.Overlay{{Text: "NO SIGNAL: " + t.name}},
+	}
+	return transform.Image(img)
+}
@@ -103,6 +103,10 @@ func handlerMP4(w http.ResponseWriter, r *http.Request) {
 	cons.Protocol = "http"
 	cons.WithRequest(r)
 
+	if layer := query.Get("layer"); layer != core.LayerAuto {
+		core.SetLayer(cons.Medias, layer)
+	}
+
 	if err := stream.AddConsumer(cons); err != nil {
 		log.Error().Err(err).Caller().Send()
 		http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -26,6 +26,11 @@ func handlerWSMSE(tr *ws.Transport, msg *ws.Message) error {
 	cons.FormatName = "mse/fmp4"
 	cons.WithRequest(tr.Request)
 
+	// MSE consumer can't switch between simulcast layers by itself
+	if layer := tr.Request.URL.Query().Get("layer"); layer != core.LayerAuto {
+		core.SetLayer(cons.Medias, layer)
+	}
+
 	if err := stream.AddConsumer(cons); err != nil {
 		log.Debug().Err(err).Msg("[mp4] add consumer")
 		return err
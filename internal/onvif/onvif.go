@@ -1,12 +1,15 @@
 package onvif
 
 import (
+	"encoding/json"
+	"errors"
 	"io"
 	"net"
 	"net/http"
 	"net/url"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/hamza-farouk/go2rtc/internal/api"
@@ -28,6 +31,12 @@ func Init() {
 
 	// ONVIF client autodiscovery
 	api.HandleFunc("api/onvif", apiOnvif)
+
+	// PTZ commands from stream messages
+	streams.HandleMessageFunc("ptz", ptzHandler)
+
+	// keyframe requests from viewers to RTSP producer of onvif source
+	streams.HandleKeyframeFunc("onvif", keyframeHandler)
 }
 
 var log zerolog.Logger
@@ -224,3 +233,83 @@ func apiOnvif(w http.ResponseWriter, r *http.Request) {
 
 	api.ResponseSources(w, items)
 }
+
+// ptzHandler - PTZ commands from stream messages (ex. WebRTC data channel)
+// {"type":"ptz","value":{"action":"move","pan":0.5,"tilt":0,"zoom":0}}
+// {"type":"ptz","value":{"action":"stop"}}
+// {"type":"ptz","value":{"action":"preset","preset":"1"}}
+func ptzHandler(stream *streams.Stream, msg *core.Message) error {
+	b, err := json.Marshal(msg.Value)
+	if err != nil {
+		return err
+	}
+
+	var cmd struct {
+		Action string  `json:"action"`
+		Pan    float64 `json:"pan"`
+		Tilt   float64 `json:"tilt"`
+		Zoom   float64 `json:"zoom"`
+		Preset string  `json:"preset"`
+	}
+	if err = json.Unmarshal(b, &cmd); err != nil {
+		return err
+	}
+
+	for _, source := range stream.Sources() {
+		if !strings.HasPrefix(source, "onvif:") {
+			continue
+		}
+
+		client, err := onvif.NewClient(source)
+		if err != nil {
+			return err
+		}
+
+		token, err := client.GetProfileToken()
+		if err != nil {
+			return err
+		}
+
+		log.Trace().Str("action", cmd.Action).Msg("[onvif] ptz")
+
+		switch cmd.Action {
+		case "move":
+			_, err = client.ContinuousMove(token, cmd.Pan, cmd.Tilt, cmd.Zoom)
+		case "stop":
+			_, err = client.StopMove(token)
+		case "preset":
+			// preset comes from viewers, so only camera presets are allowed
+			var presets []string
+			if presets, err = client.GetPresets(token); err != nil {
+				return err
+			}
+			if !core.Contains(presets, cmd.Preset) {
+				return errors.New("onvif: unknown preset: " + cmd.Preset)
+			}
+			_, err = client.GotoPreset(token, cmd.Preset)
+		default:
+			err = errors.New("onvif: unsupported ptz action: " + cmd.Action)
+		}
+
+		return err
+	}
+
+	return errors.New("onvif: stream without onvif source")
+}
+
+func keyframeHandler(source string) error {
+	client, err := onvif.NewClient(source)
+	if err != nil {
+		return err
+	}
+
+	token, err := client.GetProfileToken()
+	if err != nil {
+		return err
+	}
+
+	log.Trace().Msg("[onvif] keyframe")
+
+	_, err = client.SetSynchronizationPoint(token)
+	return err
+}
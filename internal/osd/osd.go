@@ -0,0 +1,274 @@
+package osd
+
+import (
+	"fmt"
+	"hash/crc32"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/expr-lang/expr/vm"
+	"github.com/hamza-farouk/go2rtc/internal/app"
+	"github.com/hamza-farouk/go2rtc/internal/ffmpeg/hardware"
+	"github.com/hamza-farouk/go2rtc/internal/streams"
+	"github.com/hamza-farouk/go2rtc/pkg/expr"
+	"github.com/rs/zerolog"
+)
+
+func Init() {
+	var cfg struct {
+		Mod map[string]*config `yaml:"osd"`
+	}
+
+	app.LoadConfig(&cfg)
+
+	log = app.GetLogger("osd")
+
+	if len(cfg.Mod) == 0 {
+		return
+	}
+
+	// private dir (0700), so other local users can't replace text files
+	var err error
+	if dir, err = os.MkdirTemp("", "go2rtc_osd_"); err != nil {
+		log.Error().Err(err).Caller().Send()
+		return
+	}
+
+	for name, conf := range cfg.Mod {
+		if err := newOSD(name, conf); err != nil {
+			log.Error().Err(err).Msgf("[osd] stream=%s", name)
+		}
+	}
+}
+
+var log zerolog.Logger
+
+// dir - private temp dir for text files
+var dir string
+
+type config struct {
+	Name     string            `yaml:"name"`     // variant stream name, default {stream}_osd
+	Text     string            `yaml:"text"`     // template with {name}, {time} and {value} placeholders
+	Values   map[string]string `yaml:"values"`   // expressions by value name
+	Interval int               `yaml:"interval"` // values update interval in seconds
+	Position string            `yaml:"position"` // top-left (default), top-right, bottom-left, bottom-right
+	Size     int               `yaml:"size"`     // font size
+	Video    string            `yaml:"video"`    // codec template, default h264
+	Hardware string            `yaml:"hardware"` // engine, empty for autoselect, software to disable
+	Drawtext string            `yaml:"drawtext"` // additional drawtext params (fontfile, fontcolor...)
+}
+
+type osd struct {
+	stream   string
+	text     string
+	file     string
+	programs map[string]*vm.Program
+	values   map[string]string
+	mu       sync.Mutex
+}
+
+// newOSD - creates variant stream with FFmpeg transcoding and drawtext filter.
+// Text is written to the file and FFmpeg reloads it every frame, so values
+// can be updated without restarting the transcoding.
+func newOSD(stream string, conf *config) error {
+	if conf == nil {
+		conf = &config{}
+	}
+	if conf.Name == "" {
+		conf.Name = stream + "_osd"
+	}
+	if conf.Text == "" {
+		conf.Text = "{name} {time}"
+	}
+	if conf.Interval <= 0 {
+		conf.Interval = 5
+	}
+
+	if streams.Get(conf.Name) != nil {
+		return fmt.Errorf("osd: stream already exists: %s", conf.Name)
+	}
+
+	o := &osd{
+		stream:   stream,
+		text:     conf.Text,
+		file:     filepath.Join(dir, fmt.Sprintf("%08x.txt", crc32.ChecksumIEEE([]byte(stream)))),
+		programs: map[string]*vm.Program{},
+		values:   map[string]string{},
+	}
+
+	for key, code := range conf.Values {
+		program, err := expr.Compile(code)
+		if err != nil {
+			return err
+		}
+		o.programs[key] = program
+	}
+
+	// file should exist before FFmpeg starts
+	if err := o.write(); err != nil {
+		return err
+	}
+
+	source := conf.source(stream, o.file)
+	if streams.New(conf.Name, source) == nil {
+		return fmt.Errorf("osd: invalid source: %s", source)
+	}
+
+	log.Debug().Msgf("[osd] stream=%s source=%s", conf.Name, source)
+
+	if len(o.programs) > 0 {
+		go o.worker(time.Duration(conf.Interval) * time.Second)
+	}
+
+	return nil
+}
+
+// source - FFmpeg source with the same args builder and hardware selection
+// as for any ffmpeg: link
+func (c *config) source(stream, file string) string {
+	s := "ffmpeg:" + stream
+
+	if c.Video != "" {
+		s += "#video=" + c.Video
+	} else {
+		s += "#video=h264"
+	}
+	s += "#audio=copy"
+
+	switch c.Hardware {
+	case "":
+		s += "#hardware"
+	case hardware.EngineSoftware:
+	default:
+		s += "#hardware=" + c.Hardware
+	}
+
+	return s + "#drawtext=" + c.drawtext(file)
+}
+
+func (c *config) drawtext(file string) string {
+	size := c.Size
+	if size <= 0 {
+		size = 24
+	}
+
+	s := "textfile=" + escapePath(file) + ":reload=1:fontsize=" + strconv.Itoa(size) +
+		":fontcolor=white:box=1:boxcolor=black@0.5:boxborderw=" + strconv.Itoa(max(1, size/4))
+
+	margin := strconv.Itoa(size / 2)
+	switch c.Position {
+	case "top-right":
+		s += ":x=w-tw-" + margin + ":y=" + margin
+	case "bottom-left":
+		s += ":x=" + margin + ":y=h-th-" + margin
+	case "bottom-right":
+		s += ":x=w-tw-" + margin + ":y=h-th-" + margin
+	default:
+		s += ":x=" + margin + ":y=" + margin
+	}
+
+	if c.Drawtext != "" {
+		s += ":" + c.Drawtext
+	}
+
+	return s
+}
+
+func (o *osd) worker(interval time.Duration) {
+	for {
+		o.update()
+		if err := o.write(); err != nil {
+			log.Warn().Err(err).Msgf("[osd] stream=%s", o.stream)
+		}
+		time.Sleep(interval)
+	}
+}
+
+// update - evaluate all values, keep the last good value on error
+func (o *osd) update() {
+	env := map[string]any{"name": o.stream}
+
+	for key, program := range o.programs {
+		v, err := expr.Run(program, env)
+		if err != nil {
+			log.Debug().Err(err).Msgf("[osd] stream=%s value=%s", o.stream, key)
+			continue
+		}
+
+		o.mu.Lock()
+		o.values[key] = fmt.Sprint(v)
+		o.mu.Unlock()
+	}
+}
+
+// write - replace file atomically, so FFmpeg never reads half of the text
+func (o *osd) write() error {
+	o.mu.Lock()
+	text := render(o.text, o.stream, o.values)
+	o.mu.Unlock()
+
+	tmp := o.file + ".tmp"
+	if err := os.WriteFile(tmp, []byte(text), 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, o.file)
+}
+
+// render - template to drawtext text with expansion:
+//   - {name} - stream name
+//   - {time} or {time:%H:%M:%S} - local time with strftime format
+//   - {key} - value from expressions
+func render(template, name string, values map[string]string) string {
+	sb := &strings.Builder{}
+
+	for {
+		i := strings.IndexByte(template, '{')
+		if i < 0 {
+			break
+		}
+		j := strings.IndexByte(template[i:], '}')
+		if j < 0 {
+			break
+		}
+
+		sb.WriteString(escapeText(template[:i]))
+
+		key := template[i+1 : i+j]
+		switch {
+		case key == "name":
+			sb.WriteString(escapeText(name))
+		case key == "time":
+			sb.WriteString(`%{localtime:%Y-%m-%d %X}`)
+		case strings.HasPrefix(key, "time:"):
+			sb.WriteString(`%{localtime:` + strings.ReplaceAll(key[5:], ":", `\:`) + `}`)
+		default:
+			if v, ok := values[key]; ok {
+				sb.WriteString(escapeText(v))
+			} else {
+				sb.WriteString(escapeText(template[i : i+j+1]))
+			}
+		}
+
+		template = template[i+j+1:]
+	}
+
+	sb.WriteString(escapeText(template))
+
+	return sb.String()
+}
+
+// escapeText - drawtext expands %{...} sequences and backslashes in the text
+func escapeText(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	return strings.ReplaceAll(s, `%`, `\%`)
+}
+
+// escapePath - path inside filter params, colon is params separator
+func escapePath(s string) string {
+	s = filepath.ToSlash(s)
+	return strings.ReplaceAll(s, ":", `\:`)
+}
@@ -0,0 +1,34 @@
+package osd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRender(t *testing.T) {
+	values := map[string]string{"temp": "21.5"}
+
+	s := render("{name} {time} {temp}C", "cam1", values)
+	require.Equal(t, `cam1 %{localtime:%Y-%m-%d %X} 21.5C`, s)
+
+	s = render("{time:%H:%M} 100%", "cam1", values)
+	require.Equal(t, `%{localtime:%H\:%M} 100\%`, s)
+
+	s = render("{unknown} {", `cam\1`, nil)
+	require.Equal(t, `{unknown} {`, s)
+}
+
+func TestSource(t *testing.T) {
+	conf := &config{Position: "bottom-right", Size: 32, Hardware: "vaapi"}
+	require.Equal(t,
+		`ffmpeg:cam1#video=h264#audio=copy#hardware=vaapi#drawtext=textfile=C\:/tmp/osd.txt:reload=1:fontsize=32:fontcolor=white:box=1:boxcolor=black@0.5:boxborderw=8:x=w-tw-16:y=h-th-16`,
+		conf.source("cam1", "C:/tmp/osd.txt"),
+	)
+
+	conf = &config{Hardware: "software", Drawtext: "fontcolor=yellow"}
+	require.Equal(t,
+		`ffmpeg:cam1#video=h264#audio=copy#drawtext=textfile=/tmp/osd.txt:reload=1:fontsize=24:fontcolor=white:box=1:boxcolor=black@0.5:boxborderw=6:x=12:y=12:fontcolor=yellow`,
+		conf.source("cam1", "/tmp/osd.txt"),
+	)
+}
@@ -1,10 +1,14 @@
 package rtmp
 
 import (
+	"crypto/subtle"
+	"crypto/tls"
 	"errors"
 	"io"
 	"net"
 	"net/http"
+	"net/url"
+	"strings"
 
 	"github.com/hamza-farouk/go2rtc/internal/api"
 	"github.com/hamza-farouk/go2rtc/internal/app"
@@ -18,7 +22,14 @@ import (
 func Init() {
 	var conf struct {
 		Mod struct {
-			Listen string `yaml:"listen" json:"listen"`
+			Listen       string            `yaml:"listen" json:"listen"`
+			PublishKeys  map[string]string `yaml:"publish_keys" json:"-"`
+			StreamKeys   map[string]string `yaml:"stream_keys" json:"-"`
+			RequireKey   bool              `yaml:"require_key" json:"require_key,omitempty"`
+			PlayPassword string            `yaml:"play_password" json:"-"`
+			TLSListen    string            `yaml:"tls_listen" json:"tls_listen,omitempty"`
+			TLSCert      string            `yaml:"tls_cert" json:"-"`
+			TLSKey       string            `yaml:"tls_key" json:"-"`
 		} `yaml:"rtmp"`
 	}
 
@@ -26,6 +37,11 @@ func Init() {
 
 	log = app.GetLogger("rtmp")
 
+	publishKeys = conf.Mod.PublishKeys
+	streamKeys = conf.Mod.StreamKeys
+	requireKey = conf.Mod.RequireKey
+	playPassword = conf.Mod.PlayPassword
+
 	streams.HandleFunc("rtmp", streamsHandle)
 	streams.HandleFunc("rtmps", streamsHandle)
 	streams.HandleFunc("rtmpx", streamsHandle)
@@ -36,53 +52,96 @@ func Init() {
 	streams.HandleConsumerFunc("rtmps", streamsConsumerHandle)
 	streams.HandleConsumerFunc("rtmpx", streamsConsumerHandle)
 
-	address := conf.Mod.Listen
-	if address == "" {
-		return
+	if conf.Mod.Listen != "" {
+		ln, err := net.Listen("tcp", conf.Mod.Listen)
+		if err != nil {
+			log.Error().Err(err).Caller().Send()
+		} else {
+			log.Info().Str("addr", conf.Mod.Listen).Msg("[rtmp] listen")
+			go serve(ln)
+		}
+	}
+
+	if conf.Mod.TLSListen != "" {
+		ln, err := tlsListen(conf.Mod.TLSListen, conf.Mod.TLSCert, conf.Mod.TLSKey)
+		if err != nil {
+			log.Error().Err(err).Msg("[rtmp] tls listen")
+		} else {
+			log.Info().Str("addr", conf.Mod.TLSListen).Msg("[rtmp] tls listen")
+			go serve(ln)
+		}
 	}
+}
 
-	ln, err := net.Listen("tcp", address)
+func tlsListen(address, certFile, keyFile string) (net.Listener, error) {
+	config, err := api.TLSConfig(certFile, keyFile)
 	if err != nil {
-		log.Error().Err(err).Caller().Send()
-		return
+		return nil, err
 	}
+	return tls.Listen("tcp", address, config)
+}
 
-	log.Info().Str("addr", address).Msg("[rtmp] listen")
+func serve(ln net.Listener) {
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
 
-	go func() {
-		for {
-			conn, err := ln.Accept()
-			if err != nil {
-				return
+		go func() {
+			if err := tcpHandle(conn); err != nil {
+				log.Error().Err(err).Caller().Send()
 			}
-
-			go func() {
-				if err = tcpHandle(conn); err != nil {
-					log.Error().Err(err).Caller().Send()
-				}
-			}()
-		}
-	}()
+		}()
+	}
 }
 
+var publishKeys map[string]string // stream name => publish key
+var streamKeys map[string]string  // stream key => stream name
+var requireKey bool
+var playPassword string
+
 func tcpHandle(netConn net.Conn) error {
 	rtmpConn, err := rtmp.NewServer(netConn)
 	if err != nil {
 		return err
 	}
 
+	// skip check play password for localhost
+	local := netConn.RemoteAddr().(*net.TCPAddr).IP.IsLoopback()
+
+	_, secure := netConn.(*tls.Conn)
+
+	var name string
+
+	rtmpConn.Validate = func(c *rtmp.Conn) (err error) {
+		if c.Intent == rtmp.CommandPublish {
+			name, err = publishName(c)
+		} else {
+			name, err = playName(c, local)
+		}
+		return
+	}
+
 	if err = rtmpConn.ReadCommands(); err != nil {
 		return err
 	}
 
 	switch rtmpConn.Intent {
 	case rtmp.CommandPlay:
-		stream := streams.Get(rtmpConn.App)
+		stream := streams.Get(name)
 		if stream == nil {
-			return errors.New("stream not found: " + rtmpConn.App)
+			return errors.New("stream not found: " + name)
 		}
 
 		cons := flv.NewConsumer()
+		cons.FormatName = "rtmp"
+		cons.Protocol = "rtmp"
+		cons.RemoteAddr = netConn.RemoteAddr().String()
+		if secure {
+			cons.Protocol = "rtmps"
+		}
+
 		if err = stream.AddConsumer(cons); err != nil {
 			return err
 		}
@@ -98,11 +157,13 @@ func tcpHandle(netConn net.Conn) error {
 		return nil
 
 	case rtmp.CommandPublish:
-		stream := streams.Get(rtmpConn.App)
+		stream := streams.Get(name)
 		if stream == nil {
-			return errors.New("stream not found: " + rtmpConn.App)
+			return errors.New("stream not found: " + name)
 		}
 
+		log.Debug().Str("stream", name).Msg("[rtmp] new producer")
+
 		if err = rtmpConn.WriteStart(); err != nil {
 			return err
 		}
@@ -112,6 +173,10 @@ func tcpHandle(netConn net.Conn) error {
 			return err
 		}
 
+		if secure {
+			prod.Protocol = "rtmps"
+		}
+
 		stream.AddProducer(prod)
 
 		defer stream.RemoveProducer(prod)
@@ -124,6 +189,66 @@ func tcpHandle(netConn net.Conn) error {
 	return errors.New("rtmp: unknown command: " + rtmpConn.Intent)
 }
 
+// publishName - get stream name from publish command and check stream key
+func publishName(c *rtmp.Conn) (string, error) {
+	key, query := splitQuery(c.Stream)
+
+	// stream key can be mapped to any stream name
+	if name, ok := streamKeys[key]; ok {
+		return name, nil
+	}
+
+	// key can be in the App query (rtmp://host/camera1?key=secret1)
+	// or in the Stream query (rtmp://host/app/camera1?key=secret1)
+	name, appQuery := splitQuery(c.App)
+	if name == "" {
+		name = key
+	}
+
+	if want, ok := publishKeys[name]; ok {
+		if !equalKey(key, want) && !equalKey(query.Get("key"), want) && !equalKey(appQuery.Get("key"), want) {
+			return "", errors.New("rtmp: wrong publish key for stream: " + name)
+		}
+		return name, nil
+	}
+
+	if requireKey {
+		return "", errors.New("rtmp: publish key required for stream: " + name)
+	}
+
+	return name, nil
+}
+
+// playName - get stream name from play command and check play password
+// password can be passed as stream key or as `password` query param
+func playName(c *rtmp.Conn, local bool) (string, error) {
+	name, query1 := splitQuery(c.App)
+	key, query2 := splitQuery(c.Stream)
+
+	if name == "" {
+		name = key
+	}
+
+	if playPassword != "" && !local {
+		if !equalKey(key, playPassword) && !equalKey(query1.Get("password"), playPassword) && !equalKey(query2.Get("password"), playPassword) {
+			return "", errors.New("rtmp: wrong play password for stream: " + name)
+		}
+	}
+
+	return name, nil
+}
+
+// equalKey - constant time compare for keys and passwords
+func equalKey(s, want string) bool {
+	return subtle.ConstantTimeCompare([]byte(s), []byte(want)) == 1
+}
+
+func splitQuery(s string) (string, url.Values) {
+	s, rawQuery, _ := strings.Cut(s, "?")
+	query, _ := url.ParseQuery(rawQuery)
+	return s, query
+}
+
 var log zerolog.Logger
 
 func streamsHandle(url string) (core.Producer, error) {
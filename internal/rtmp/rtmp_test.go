@@ -0,0 +1,42 @@
+package rtmp
+
+import (
+	"testing"
+
+	"github.com/hamza-farouk/go2rtc/pkg/rtmp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPublishName(t *testing.T) {
+	publishKeys = map[string]string{"camera1": "secret1"}
+	streamKeys = map[string]string{"secret2": "camera2"}
+	defer func() {
+		publishKeys, streamKeys = nil, nil
+	}()
+
+	// rtmp://host/camera1?key=secret1
+	name, err := publishName(&rtmp.Conn{App: "camera1?key=secret1"})
+	require.Nil(t, err)
+	require.Equal(t, "camera1", name)
+
+	// rtmp://host/camera1/secret1 (OBS server and stream key)
+	name, err = publishName(&rtmp.Conn{App: "camera1", Stream: "secret1"})
+	require.Nil(t, err)
+	require.Equal(t, "camera1", name)
+
+	// rtmp://host/camera1/stream?key=secret1
+	name, err = publishName(&rtmp.Conn{App: "camera1", Stream: "stream?key=secret1"})
+	require.Nil(t, err)
+	require.Equal(t, "camera1", name)
+
+	// rtmp://host/live/secret2
+	name, err = publishName(&rtmp.Conn{App: "live", Stream: "secret2"})
+	require.Nil(t, err)
+	require.Equal(t, "camera2", name)
+
+	_, err = publishName(&rtmp.Conn{App: "camera1?key=wrong"})
+	require.NotNil(t, err)
+
+	_, err = publishName(&rtmp.Conn{App: "camera1", Stream: "wrong"})
+	require.NotNil(t, err)
+}
@@ -6,7 +6,9 @@ import (
 	"net"
 	"net/url"
 	"strings"
+	"sync"
 
+	"github.com/hamza-farouk/go2rtc/internal/api"
 	"github.com/hamza-farouk/go2rtc/internal/app"
 	"github.com/hamza-farouk/go2rtc/internal/streams"
 	"github.com/hamza-farouk/go2rtc/pkg/core"
@@ -24,6 +26,10 @@ func Init() {
 			DefaultQuery string `yaml:"default_query" json:"default_query"`
 			PacketSize   uint16 `yaml:"pkt_size" json:"pkt_size,omitempty"`
 			ForceSprop   bool   `yaml:"force_sprop" json:"force_sprop,omitempty"` // NEW: Force sprop parameters
+
+			PublishAuth        map[string]string `yaml:"publish_auth" json:"-"`
+			PublishDenySources bool              `yaml:"publish_deny_sources" json:"publish_deny_sources,omitempty"`
+			PublishCreate      bool              `yaml:"publish_create" json:"publish_create,omitempty"`
 		} `yaml:"rtsp"`
 	}
 
@@ -40,6 +46,8 @@ func Init() {
 	streams.HandleFunc("rtsp", rtspHandler)
 	streams.HandleFunc("rtsps", rtspHandler)
 	streams.HandleFunc("rtspx", rtspHandler)
+	streams.HandleFunc("rtsp+http", rtspHandler)
+	streams.HandleFunc("rtsp+https", rtspHandler)
 
 	// RTSP server support
 	address := conf.Mod.Listen
@@ -47,6 +55,24 @@ func Init() {
 		return
 	}
 
+	if query, err := url.ParseQuery(conf.Mod.DefaultQuery); err == nil {
+		defaultMedias = ParseQuery(query)
+	}
+
+	// Store the force_sprop setting globally
+	forceSpropParams = conf.Mod.ForceSprop
+
+	username = conf.Mod.Username
+	password = conf.Mod.Password
+	packetSize = conf.Mod.PacketSize
+
+	publishAuth = conf.Mod.PublishAuth
+	publishDenySources = conf.Mod.PublishDenySources
+	publishCreate = conf.Mod.PublishCreate
+
+	// RTSP over HTTP tunneling on the API port
+	api.HandleHeaderFunc("X-Sessioncookie", tunnelHandler)
+
 	ln, err := net.Listen("tcp", address)
 	if err != nil {
 		log.Error().Err(err).Msg("[rtsp] listen")
@@ -57,13 +83,6 @@ func Init() {
 
 	log.Info().Str("addr", address).Msg("[rtsp] listen")
 
-	if query, err := url.ParseQuery(conf.Mod.DefaultQuery); err == nil {
-		defaultMedias = ParseQuery(query)
-	}
-
-	// Store the force_sprop setting globally
-	forceSpropParams = conf.Mod.ForceSprop
-
 	go func() {
 		for {
 			conn, err := ln.Accept()
@@ -71,13 +90,7 @@ func Init() {
 				return
 			}
 
-			c := rtsp.NewServer(conn)
-			c.PacketSize = conf.Mod.PacketSize
-			// skip check auth for localhost
-			if conf.Mod.Username != "" && !conn.RemoteAddr().(*net.TCPAddr).IP.IsLoopback() {
-				c.Auth(conf.Mod.Username, conf.Mod.Password)
-			}
-			go tcpHandler(c)
+			handle(conn)
 		}
 	}()
 }
@@ -97,6 +110,19 @@ var handlers []Handler
 var defaultMedias []*core.Media
 var forceSpropParams bool // NEW: Global setting for forcing sprop parameters
 
+var username, password string
+var packetSize uint16
+
+var publishAuth map[string]string // stream name => "user:pass"
+var publishDenySources bool
+var publishCreate bool
+
+// publishMu - protects adding and removing publishers of created streams
+var publishMu sync.Mutex
+
+// publishCreated - streams created on publish, removed when last publisher leaves
+var publishCreated = map[string]*streams.Stream{}
+
 func rtspHandler(rawURL string) (core.Producer, error) {
 	rawURL, rawQuery, _ := strings.Cut(rawURL, "#")
 
@@ -148,6 +174,19 @@ func rtspHandler(rawURL string) (core.Producer, error) {
 	return conn, nil
 }
 
+func handle(conn net.Conn) {
+	c := rtsp.NewServer(conn)
+	c.PacketSize = packetSize
+	if _, ok := conn.(*rtsp.Tunnel); ok {
+		c.Protocol = "rtsp+http"
+	}
+	// skip check auth for localhost
+	if username != "" && !isLoopback(c) {
+		c.Auth(username, password)
+	}
+	go tcpHandler(c)
+}
+
 func tcpHandler(conn *rtsp.Conn) {
 	var name string
 	var closer func()
@@ -165,6 +204,20 @@ func tcpHandler(conn *rtsp.Conn) {
 			}
 		}
 
+		// per-stream publish credentials replace global credentials before auth check
+		if req, ok := msg.(*tcp.Request); ok && len(req.URL.Path) > 1 {
+			if auth, ok := publishAuth[req.URL.Path[1:]]; ok && !isLoopback(conn) {
+				switch req.Method {
+				case rtsp.MethodOptions:
+					// credentials for publish path are known only from the next ANNOUNCE
+					conn.SkipAuth()
+				case rtsp.MethodAnnounce:
+					username, password, _ := strings.Cut(auth, ":")
+					conn.Auth(username, password)
+				}
+			}
+		}
+
 		switch msg {
 		case rtsp.MethodDescribe:
 			if len(conn.URL.Path) == 0 {
@@ -191,6 +244,11 @@ func tcpHandler(conn *rtsp.Conn) {
 				}
 			}
 
+			// RTSP consumer can't switch between simulcast layers by itself
+			if layer := query.Get("layer"); layer != core.LayerAuto {
+				core.SetLayer(conn.Medias, layer)
+			}
+
 			// NEW: Check for sprop parameter forcing in query or global config
 			forceSprop := forceSpropParams || query.Get("force_sprop") == "1"
 			if forceSprop {
@@ -246,8 +304,24 @@ func tcpHandler(conn *rtsp.Conn) {
 
 			name = conn.URL.Path[1:]
 
+			publishMu.Lock()
+			defer publishMu.Unlock()
+
 			stream := streams.Get(name)
 			if stream == nil {
+				if !publishCreate || streams.Validate(name) != nil {
+					conn.Reject("404 Not Found")
+					return
+				}
+
+				var created bool
+				if stream, created = streams.GetOrNew(name); created {
+					log.Info().Str("stream", name).Msg("[rtsp] create new stream on publish")
+					publishCreated[name] = stream
+				}
+			} else if publishDenySources && stream.HasSources() {
+				log.Warn().Str("stream", name).Msg("[rtsp] publish to stream with sources is forbidden")
+				conn.Reject("403 Forbidden")
 				return
 			}
 
@@ -261,7 +335,13 @@ func tcpHandler(conn *rtsp.Conn) {
 			stream.AddProducer(conn)
 
 			closer = func() {
+				publishMu.Lock()
 				stream.RemoveProducer(conn)
+				if publishCreated[name] == stream && streams.DeleteIfEmpty(name, stream) {
+					log.Info().Str("stream", name).Msg("[rtsp] remove stream created on publish")
+					delete(publishCreated, name)
+				}
+				publishMu.Unlock()
 			}
 		}
 	})
@@ -298,6 +378,12 @@ func tcpHandler(conn *rtsp.Conn) {
 	_ = conn.Close()
 }
 
+func isLoopback(conn *rtsp.Conn) bool {
+	host, _, _ := net.SplitHostPort(conn.Connection.RemoteAddr)
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
 func ParseQuery(query map[string][]string) []*core.Media {
 	if v := query["mp4"]; v != nil {
 		return []*core.Media{
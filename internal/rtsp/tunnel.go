@@ -0,0 +1,127 @@
+package rtsp
+
+import (
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/hamza-farouk/go2rtc/pkg/rtsp"
+)
+
+// tunnelHandler - RTSP over HTTP tunneling on the API port.
+// GET request becomes server => client channel, POST request with same
+// x-sessioncookie becomes client => server channel with base64 data.
+func tunnelHandler(w http.ResponseWriter, r *http.Request) {
+	cookie := r.Header.Get("X-Sessioncookie")
+
+	switch r.Method {
+	case "GET":
+		if cookie == "" {
+			http.Error(w, "", http.StatusBadRequest)
+			return
+		}
+
+		// reserve cookie before hijacking, so the same cookie can't replace
+		// another pending tunnel
+		if status := reserveTunnel(cookie); status != http.StatusOK {
+			http.Error(w, "", status)
+			return
+		}
+
+		conn, _, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			removeTunnel(cookie, nil)
+			log.Error().Err(err).Caller().Send()
+			return
+		}
+
+		res := "HTTP/1.0 200 OK\r\n" +
+			"Content-Type: " + rtsp.MimeTunnel + "\r\n" +
+			"Cache-Control: no-cache\r\n" +
+			"Pragma: no-cache\r\n\r\n"
+		if _, err = conn.Write([]byte(res)); err != nil {
+			removeTunnel(cookie, nil)
+			_ = conn.Close()
+			return
+		}
+
+		tunnelsMu.Lock()
+		tunnels[cookie] = conn
+		tunnelsMu.Unlock()
+
+		// close GET connection if client won't send POST request
+		time.AfterFunc(tunnelTimeout, func() {
+			if removeTunnel(cookie, conn) {
+				_ = conn.Close()
+			}
+		})
+
+	case "POST":
+		get := popTunnel(cookie)
+		if get == nil {
+			http.Error(w, "", http.StatusNotFound)
+			return
+		}
+
+		post, rw, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			_ = get.Close()
+			log.Error().Err(err).Caller().Send()
+			return
+		}
+
+		log.Trace().Str("remote_addr", r.RemoteAddr).Msg("[rtsp] new tunnel")
+
+		handle(rtsp.NewServerTunnel(get, post, rw.Reader))
+
+	default:
+		http.Error(w, "", http.StatusMethodNotAllowed)
+	}
+}
+
+const tunnelTimeout = 10 * time.Second
+const tunnelsLimit = 100 // max pending GET requests
+
+// tunnels - pending GET connections by cookie, nil for reserved cookie
+var tunnels = map[string]net.Conn{}
+var tunnelsMu sync.Mutex
+
+func reserveTunnel(cookie string) int {
+	tunnelsMu.Lock()
+	defer tunnelsMu.Unlock()
+
+	if _, ok := tunnels[cookie]; ok {
+		return http.StatusConflict
+	}
+	if len(tunnels) >= tunnelsLimit {
+		return http.StatusServiceUnavailable
+	}
+
+	tunnels[cookie] = nil
+	return http.StatusOK
+}
+
+// popTunnel - get and remove GET connection, reserved cookie stays untouched
+func popTunnel(cookie string) net.Conn {
+	tunnelsMu.Lock()
+	defer tunnelsMu.Unlock()
+
+	conn := tunnels[cookie]
+	if conn != nil {
+		delete(tunnels, cookie)
+	}
+	return conn
+}
+
+// removeTunnel - remove cookie only if it still points to the same connection
+func removeTunnel(cookie string, conn net.Conn) bool {
+	tunnelsMu.Lock()
+	defer tunnelsMu.Unlock()
+
+	if c, ok := tunnels[cookie]; ok && c == conn {
+		delete(tunnels, cookie)
+		return true
+	}
+	return false
+}
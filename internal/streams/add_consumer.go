@@ -20,6 +20,8 @@ func (s *Stream) AddConsumer(cons core.Consumer) (err error) {
 	for _, consMedia := range consMedias {
 		log.Trace().Msgf("[streams] check cons=%d media=%s", consN, consMedia)
 
+		var matched bool
+
 	producers:
 		for prodN, prod := range s.producers {
 			// check for loop request, ex. `camera1: ffmpeg:camera1`
@@ -86,10 +88,20 @@ func (s *Stream) AddConsumer(cons core.Consumer) (err error) {
 
 				prodStarts = append(prodStarts, prod)
 
+				// auto layer consumer gets all simulcast layers from this producer
+				if consMedia.Layer == core.LayerAuto {
+					matched = true
+					continue
+				}
+
 				if !consMedia.MatchAll() {
 					break producers
 				}
 			}
+
+			if matched {
+				break
+			}
 		}
 	}
 
@@ -106,6 +118,8 @@ func (s *Stream) AddConsumer(cons core.Consumer) (err error) {
 	s.consumers = append(s.consumers, cons)
 	s.mu.Unlock()
 
+	s.listenMessages(cons, cons)
+
 	// there may be duplicates, but that's not a problem
 	for _, prod := range prodStarts {
 		prod.start()
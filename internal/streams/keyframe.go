@@ -0,0 +1,70 @@
+package streams
+
+import (
+	"strings"
+	"time"
+
+	"github.com/hamza-farouk/go2rtc/pkg/core"
+)
+
+type KeyframeHandler func(source string) error
+
+// HandleKeyframeFunc - request keyframe for sources with this scheme, if their
+// producers can't do it by themselves (ex. onvif source with RTSP producer)
+func HandleKeyframeFunc(scheme string, handler KeyframeHandler) {
+	keyframeHandlers[scheme] = handler
+}
+
+var keyframeHandlers = map[string]KeyframeHandler{}
+
+// keyframeInterval - viewers can send many requests, so limit them for producers
+const keyframeInterval = time.Second
+
+// RequestKeyframe - request keyframe from all active producers of the stream
+func (s *Stream) RequestKeyframe() {
+	s.mu.Lock()
+	if time.Since(s.keyframeTS) < keyframeInterval {
+		s.mu.Unlock()
+		return
+	}
+	s.keyframeTS = time.Now()
+
+	var requesters []core.KeyframeRequester
+	var sources []string
+
+	for _, prod := range s.producers {
+		prod.mu.Lock()
+		if prod.conn != nil {
+			if r, ok := prod.conn.(core.KeyframeRequester); ok {
+				requesters = append(requesters, r)
+			} else {
+				sources = append(sources, prod.url)
+			}
+		}
+		prod.mu.Unlock()
+	}
+	s.mu.Unlock()
+
+	for _, r := range requesters {
+		if err := r.RequestKeyframe(); err != nil {
+			log.Trace().Err(err).Msg("[streams] keyframe")
+		}
+	}
+
+	for _, source := range sources {
+		i := strings.IndexByte(source, ':')
+		if i <= 0 {
+			continue
+		}
+		if handler, ok := keyframeHandlers[source[:i]]; ok {
+			if err := handler(source); err != nil {
+				log.Trace().Err(err).Msg("[streams] keyframe")
+			}
+		}
+	}
+}
+
+func keyframeHandler(stream *Stream, _ *core.Message) error {
+	stream.RequestKeyframe()
+	return nil
+}
@@ -0,0 +1,73 @@
+package streams
+
+import (
+	"github.com/hamza-farouk/go2rtc/pkg/core"
+)
+
+type MessageHandler func(stream *Stream, msg *core.Message) error
+
+// HandleMessageFunc - handle messages with this type instead of sending them
+// to other consumers and producers of the stream (ex. PTZ commands)
+func HandleMessageFunc(msgType string, handler MessageHandler) {
+	messageHandlers[msgType] = handler
+}
+
+var messageHandlers = map[string]MessageHandler{
+	"keyframe": keyframeHandler, // ex. PLI/FIR from WebRTC viewer
+}
+
+// SendMessage - send message from one consumer or producer to all other
+// consumers and producers of the stream that support messages
+func (s *Stream) SendMessage(msg *core.Message, from any) {
+	if handler, ok := messageHandlers[msg.Type]; ok {
+		// handlers can be slow (ex. HTTP requests to camera)
+		go func() {
+			if err := handler(s, msg); err != nil {
+				log.Warn().Err(err).Str("type", msg.Type).Msg("[streams] message")
+			}
+		}()
+		return
+	}
+
+	var targets []core.Messenger
+
+	s.mu.Lock()
+	for _, cons := range s.consumers {
+		if m, ok := cons.(core.Messenger); ok && cons != from {
+			targets = append(targets, m)
+		}
+	}
+	for _, prod := range s.producers {
+		if m, ok := prod.conn.(core.Messenger); ok && prod != from {
+			targets = append(targets, m)
+		}
+	}
+	s.mu.Unlock()
+
+	for _, target := range targets {
+		if err := target.SendMessage(msg); err != nil {
+			log.Trace().Err(err).Msg("[streams] message")
+		}
+	}
+}
+
+// listenMessages - route messages from consumer or producer connection to the stream
+func (s *Stream) listenMessages(conn any, from any) {
+	if l, ok := conn.(interface{ Listen(core.EventFunc) }); ok {
+		l.Listen(func(msg any) {
+			if m, ok := msg.(*core.Message); ok {
+				s.SendMessage(m, from)
+			}
+		})
+	}
+}
+
+// listenProducer - route producer messages and producer state events
+func (s *Stream) listenProducer(prod *Producer) {
+	prod.Listen(func(msg any) {
+		if m, ok := msg.(*core.Message); ok {
+			// producer fire events under own lock, so send them async
+			go s.SendMessage(m, prod)
+		}
+	})
+}
@@ -64,6 +64,8 @@ func (p *Producer) Dial() error {
 			return err
 		}
 
+		p.listenConn(conn)
+
 		p.conn = conn
 		p.state = stateMedias
 	}
@@ -141,6 +143,23 @@ func (p *Producer) MarshalJSON() ([]byte, error) {
 
 // internals
 
+// listenConn - forward messages from connection (ex. WebRTC data channel) to producer listeners
+func (p *Producer) listenConn(conn core.Producer) {
+	if l, ok := conn.(interface{ Listen(core.EventFunc) }); ok {
+		l.Listen(func(msg any) {
+			if m, ok := msg.(*core.Message); ok {
+				p.Fire(m)
+			}
+		})
+	}
+}
+
+// fireState - producer online/offline events for stream messages
+func (p *Producer) fireState(state string) {
+	scheme, _, _ := strings.Cut(p.url, ":")
+	p.Fire(&core.Message{Type: "producer/" + state, Value: scheme})
+}
+
 func (p *Producer) start() {
 	p.mu.Lock()
 	defer p.mu.Unlock()
@@ -154,6 +173,8 @@ func (p *Producer) start() {
 	p.state = stateStart
 	p.workerID++
 
+	p.fireState("online")
+
 	go p.worker(p.conn, p.workerID)
 }
 
@@ -170,6 +191,8 @@ func (p *Producer) worker(conn core.Producer, workerID int) {
 		log.Warn().Err(err).Str("url", p.url).Caller().Send()
 	}
 
+	p.fireState("offline")
+
 	p.reconnect(workerID, 0)
 }
 
@@ -237,8 +260,11 @@ func (p *Producer) reconnect(workerID, retry int) {
 	// stop previous connection after moving tracks (fix ghost exec/ffmpeg)
 	_ = p.conn.Stop()
 	// swap connections
+	p.listenConn(conn)
 	p.conn = conn
 
+	p.fireState("online")
+
 	go p.worker(conn, workerID)
 }
 
@@ -255,6 +281,7 @@ func (p *Producer) stop() {
 		return
 	case stateStart:
 		p.workerID++
+		p.fireState("offline")
 	}
 
 	log.Debug().Msgf("[streams] stop producer url=%s", p.url)
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/hamza-farouk/go2rtc/pkg/core"
 )
@@ -13,9 +14,19 @@ type Stream struct {
 	consumers []core.Consumer
 	mu        sync.Mutex
 	pending   atomic.Int32
+
+	keyframeTS time.Time // last keyframe request
 }
 
 func NewStream(source any) *Stream {
+	s := newStream(source)
+	for _, prod := range s.producers {
+		s.listenProducer(prod)
+	}
+	return s
+}
+
+func newStream(source any) *Stream {
 	switch source := source.(type) {
 	case string:
 		return &Stream{
@@ -39,7 +50,7 @@ func NewStream(source any) *Stream {
 		}
 		return s
 	case map[string]any:
-		return NewStream(source["url"])
+		return newStream(source["url"])
 	case nil:
 		return new(Stream)
 	default:
@@ -55,6 +66,40 @@ func (s *Stream) Sources() []string {
 	return sources
 }
 
+// HasSources - check if stream has its own sources (ex. camera), not only pushed producers
+func (s *Stream) HasSources() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, prod := range s.producers {
+		switch prod.state {
+		case stateExternal, stateInternal:
+			continue
+		}
+		// template sources (ex. ffmpeg:{input}) used for processing published streams
+		if prod.url != "" && prod.template == "" {
+			return true
+		}
+	}
+	return false
+}
+
+// Connections - active producers and consumers, ex. for connection info APIs
+func (s *Stream) Connections() []any {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var conns []any
+	for _, prod := range s.producers {
+		if conn := prod.conn; conn != nil {
+			conns = append(conns, conn)
+		}
+	}
+	for _, cons := range s.consumers {
+		conns = append(conns, cons)
+	}
+	return conns
+}
+
 func (s *Stream) SetSource(source string) {
 	for _, prod := range s.producers {
 		prod.SetSource(source)
@@ -78,9 +123,14 @@ func (s *Stream) RemoveConsumer(cons core.Consumer) {
 
 func (s *Stream) AddProducer(prod core.Producer) {
 	producer := &Producer{conn: prod, state: stateExternal, url: "external"}
+	s.listenProducer(producer)
+	producer.listenConn(prod)
+
 	s.mu.Lock()
 	s.producers = append(s.producers, producer)
 	s.mu.Unlock()
+
+	producer.fireState("online")
 }
 
 func (s *Stream) RemoveProducer(prod core.Producer) {
@@ -88,6 +138,7 @@ func (s *Stream) RemoveProducer(prod core.Producer) {
 	for i, producer := range s.producers {
 		if producer.conn == prod {
 			s.producers = append(s.producers[:i], s.producers[i+1:]...)
+			producer.fireState("offline")
 			break
 		}
 	}
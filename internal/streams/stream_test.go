@@ -36,3 +36,101 @@ func TestTempate(t *testing.T) {
 	require.Equal(t, stream1, stream2)
 	require.Equal(t, "ffmpeg:rtsp://example.com#video=copy", stream1.producers[0].url)
 }
+
+type testMessenger struct {
+	core.Connection
+	core.Listener
+	messages []*core.Message
+}
+
+func (m *testMessenger) AddTrack(*core.Media, *core.Codec, *core.Receiver) error {
+	return nil
+}
+
+func (m *testMessenger) Stop() error {
+	return nil
+}
+
+func (m *testMessenger) SendMessage(msg *core.Message) error {
+	m.messages = append(m.messages, msg)
+	return nil
+}
+
+func TestSendMessage(t *testing.T) {
+	cons1 := &testMessenger{}
+	cons2 := &testMessenger{}
+
+	stream := NewStream(nil)
+	stream.consumers = []core.Consumer{cons1, cons2}
+	stream.listenMessages(cons1, cons1)
+
+	msg := &core.Message{Type: "custom", Value: "hello"}
+	cons1.Fire(msg)
+
+	// message should be sent to other consumers only
+	require.Len(t, cons1.messages, 0)
+	require.Equal(t, []*core.Message{msg}, cons2.messages)
+}
+
+type testProducer struct {
+	core.Connection
+}
+
+func (p *testProducer) Start() error {
+	return nil
+}
+
+func (p *testProducer) Stop() error {
+	return nil
+}
+
+type testKeyframer struct {
+	testProducer
+	requests int
+}
+
+func (k *testKeyframer) RequestKeyframe() error {
+	k.requests++
+	return nil
+}
+
+func TestRequestKeyframe(t *testing.T) {
+	prod1 := &testKeyframer{}
+
+	var sources []string
+	HandleKeyframeFunc("test", func(source string) error {
+		sources = append(sources, source)
+		return nil
+	})
+
+	stream := NewStream(nil)
+	stream.producers = []*Producer{
+		{url: "prod1", conn: prod1},
+		{url: "test:prod2", conn: &testProducer{}},
+		{url: "test:prod3"}, // not started producer
+	}
+
+	stream.RequestKeyframe()
+	stream.RequestKeyframe() // too often
+
+	require.Equal(t, 1, prod1.requests)
+	require.Equal(t, []string{"test:prod2"}, sources)
+}
+
+func TestGetOrNew(t *testing.T) {
+	stream1, created := GetOrNew("on_publish")
+	require.True(t, created)
+
+	stream2, created := GetOrNew("on_publish")
+	require.False(t, created)
+	require.Equal(t, stream1, stream2)
+
+	prod := &testProducer{}
+	stream1.AddProducer(prod)
+	require.False(t, DeleteIfEmpty("on_publish", stream1))
+
+	stream1.RemoveProducer(prod)
+	require.False(t, DeleteIfEmpty("on_publish", NewStream(nil))) // other stream
+	require.True(t, DeleteIfEmpty("on_publish", stream1))
+	require.Nil(t, Get("on_publish"))
+}
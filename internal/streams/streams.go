@@ -155,6 +155,39 @@ func Delete(name string) {
 	delete(streams, name)
 }
 
+// GetOrNew - atomic get stream or create new stream without sources
+func GetOrNew(name string) (stream *Stream, created bool) {
+	streamsMu.Lock()
+	defer streamsMu.Unlock()
+
+	if stream = streams[name]; stream != nil {
+		return stream, false
+	}
+
+	stream = new(Stream)
+	streams[name] = stream
+	return stream, true
+}
+
+// DeleteIfEmpty - delete stream if it's still registered with this name and has no producers
+func DeleteIfEmpty(name string, stream *Stream) bool {
+	streamsMu.Lock()
+	defer streamsMu.Unlock()
+
+	if streams[name] != stream {
+		return false
+	}
+
+	stream.mu.Lock()
+	empty := len(stream.producers) == 0
+	stream.mu.Unlock()
+
+	if empty {
+		delete(streams, name)
+	}
+	return empty
+}
+
 func GetAllNames() []string {
 	streamsMu.Lock()
 	names := make([]string, 0, len(streams))
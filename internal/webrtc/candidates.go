@@ -3,6 +3,8 @@ package webrtc
 import (
 	"net"
 	"strings"
+	"sync/atomic"
+	"time"
 
 	"github.com/hamza-farouk/go2rtc/internal/api/ws"
 	"github.com/hamza-farouk/go2rtc/pkg/core"
@@ -19,12 +21,22 @@ type Address struct {
 }
 
 func (a *Address) Host() string {
-	if a.host == "stun" {
+	switch a.host {
+	case "stun":
+		if ip := publicIP.Load(); ip != nil {
+			return ip.String()
+		}
+		// public IP worker hasn't finished first discovery yet
 		ip, err := webrtc.GetCachedPublicIP()
 		if err != nil {
 			return ""
 		}
 		return ip.String()
+	case "stun6":
+		if ip := publicIP6.Load(); ip != nil {
+			return ip.String()
+		}
+		return ""
 	}
 	return a.host
 }
@@ -59,6 +71,55 @@ func AddCandidate(network, address string) {
 	addresses = append(addresses, &Address{host, port, network, priority})
 }
 
+var publicIP, publicIP6 atomic.Pointer[net.IP]
+
+// initPublicIP - periodic re-discovery of public IPs for `stun` and `stun6` candidates,
+// so dynamic IP change doesn't require restart
+func initPublicIP(interval time.Duration) {
+	var ip4, ip6 bool
+	for _, address := range addresses {
+		switch address.host {
+		case "stun":
+			ip4 = true
+		case "stun6":
+			ip6 = true
+		}
+	}
+
+	if !ip4 && !ip6 {
+		return
+	}
+
+	if interval <= 0 {
+		interval = 5 * time.Minute
+	}
+
+	go func() {
+		for {
+			if ip4 {
+				updatePublicIP(&publicIP, webrtc.GetPublicIP)
+			}
+			if ip6 {
+				updatePublicIP(&publicIP6, webrtc.GetPublicIP6)
+			}
+			time.Sleep(interval)
+		}
+	}()
+}
+
+func updatePublicIP(ptr *atomic.Pointer[net.IP], discover func() (net.IP, error)) {
+	ip, err := discover()
+	if err != nil {
+		log.Debug().Err(err).Msg("[webrtc] public IP")
+		return // keep previous IP
+	}
+
+	if prev := ptr.Load(); prev == nil || !prev.Equal(ip) {
+		log.Info().Str("ip", ip.String()).Msg("[webrtc] public IP")
+		ptr.Store(&ip)
+	}
+}
+
 func GetCandidates() (candidates []string) {
 	for _, address := range addresses {
 		if candidate := address.Marshal(); candidate != "" {
@@ -74,9 +135,17 @@ func FilterCandidate(candidate *pion.ICECandidate) bool {
 		return false
 	}
 
-	// remove any Docker-like IP from candidates
-	if ip := net.ParseIP(candidate.Address); ip != nil && xnet.Docker.Contains(ip) {
-		return false
+	if ip := net.ParseIP(candidate.Address); ip != nil {
+		// remove any Docker-like IP from candidates
+		if xnet.Docker.Contains(ip) {
+			return false
+		}
+
+		// IPv6 link-local address works only inside local network segment
+		// and requires interface zone, remote peers can't use it
+		if ip.To4() == nil && ip.IsLinkLocalUnicast() {
+			return false
+		}
 	}
 
 	// host candidate should be in the hosts list
@@ -105,12 +174,30 @@ func NetworkType(network, host string) string {
 	}
 }
 
+// addRemoteCandidate - add candidate from remote peer. Browsers hide local IPs behind
+// random mDNS names (xxx.local). Pion resolves them with multicast queries,
+// but with disabled mDNS (ex. Docker bridge network) such candidates are useless.
+func addRemoteCandidate(conn *webrtc.Conn, candidate string) error {
+	if filters.MDNS == "disabled" && IsMDNSCandidate(candidate) {
+		log.Trace().Str("candidate", candidate).Msg("[webrtc] skip mDNS")
+		return nil
+	}
+	return conn.AddCandidate(candidate)
+}
+
+// IsMDNSCandidate - check if candidate address is mDNS name
+func IsMDNSCandidate(candidate string) bool {
+	// candidate:foundation component protocol priority address port typ ...
+	fields := strings.Fields(candidate)
+	return len(fields) > 4 && strings.HasSuffix(fields[4], ".local")
+}
+
 func asyncCandidates(tr *ws.Transport, cons *webrtc.Conn) {
 	tr.WithContext(func(ctx map[any]any) {
 		if candidates, ok := ctx["candidate"].([]string); ok {
 			// process candidates that receive before this moment
 			for _, candidate := range candidates {
-				_ = cons.AddCandidate(candidate)
+				_ = addRemoteCandidate(cons, candidate)
 			}
 
 			// remove already processed candidates
@@ -135,7 +222,7 @@ func candidateHandler(tr *ws.Transport, msg *ws.Message) error {
 
 		if cons, ok := ctx["webrtc"].(*webrtc.Conn); ok {
 			// if webrtc.Server already initialized - process candidate
-			_ = cons.AddCandidate(candidate)
+			_ = addRemoteCandidate(cons, candidate)
 		} else {
 			// or collect candidate and process it later
 			list, _ := ctx["candidate"].([]string)
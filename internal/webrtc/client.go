@@ -23,6 +23,10 @@ import (
 //  2. go2rtc:  webrtc:ws://192.168.1.123:1984/api/ws?src=camera1
 //  3. Wyze:    webrtc:http://192.168.1.123:5000/signaling/camera1?kvs#format=wyze
 //  4. Kinesis: webrtc:wss://...amazonaws.com/?...#format=kinesis#client_id=...#ice_servers=[{...},{...}]
+//  5. Custom:  webrtc:https://example.com/offer#format=mycam (format from `webrtc.signaling` config)
+//
+// Optional `#jitter=200` - jitter buffer latency in milliseconds with NACK for lost packets
+// Optional `#e2ee` - encrypted media (insertable streams) passthrough to WebRTC viewers
 func streamsHandler(rawURL string) (core.Producer, error) {
 	var query url.Values
 	if i := strings.IndexByte(rawURL, '#'); i > 0 {
@@ -30,11 +34,26 @@ func streamsHandler(rawURL string) (core.Producer, error) {
 		rawURL = rawURL[:i]
 	}
 
-	rawURL = rawURL[7:] // remove webrtc:
+	prod, err := streamsClient(rawURL[7:], query) // remove webrtc:
+	if err != nil {
+		return nil, err
+	}
+
+	setJitter(prod, query.Get("jitter"))
+	setOpaque(prod, query.Get("e2ee"))
+
+	return prod, nil
+}
+
+func streamsClient(rawURL string, query url.Values) (core.Producer, error) {
 	if i := strings.IndexByte(rawURL, ':'); i > 0 {
 		scheme := rawURL[:i]
 		format := query.Get("format")
 
+		if s := signalings[format]; s != nil {
+			return signalingClient(rawURL, query, format, s)
+		}
+
 		switch scheme {
 		case "ws", "wss":
 			if format == "kinesis" {
@@ -66,6 +85,26 @@ func streamsHandler(rawURL string) (core.Producer, error) {
 	return nil, errors.New("unsupported url: " + rawURL)
 }
 
+// setOpaque - mark connection medias as end-to-end encrypted, any non-empty value
+func setOpaque(conn core.Producer, value string) {
+	if value == "" {
+		return
+	}
+	if conn, ok := conn.(*webrtc.Conn); ok {
+		webrtc.SetOpaque(conn.Medias)
+	}
+}
+
+// setJitter - enable jitter buffer for WebRTC producer, value in milliseconds
+func setJitter(prod core.Producer, value string) {
+	if value == "" {
+		return
+	}
+	if conn, ok := prod.(*webrtc.Conn); ok {
+		conn.SetJitter(time.Duration(core.Atoi(value)) * time.Millisecond)
+	}
+}
+
 // go2rtcClient can connect only to go2rtc server
 // ex: ws://localhost:1984/api/ws?src=camera1
 func go2rtcClient(url string) (core.Producer, error) {
@@ -63,7 +63,7 @@ func kinesisClient(
 		return nil, err
 	}
 
-	pc, err := api.NewPeerConnection(conf)
+	pc, err := webrtc.NewPeerConnection(api, conf)
 	if err != nil {
 		return nil, err
 	}
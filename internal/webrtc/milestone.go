@@ -187,7 +187,7 @@ func milestoneClient(rawURL string, query url.Values) (core.Producer, error) {
 	}
 
 	conf := pion.Configuration{}
-	pc, err := api.NewPeerConnection(conf)
+	pc, err := webrtc.NewPeerConnection(api, conf)
 	if err != nil {
 		return nil, err
 	}
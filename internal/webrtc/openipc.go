@@ -38,7 +38,7 @@ func openIPCClient(rawURL string, query url.Values) (core.Producer, error) {
 		return nil, err
 	}
 
-	pc, err := api.NewPeerConnection(conf)
+	pc, err := webrtc.NewPeerConnection(api, conf)
 	if err != nil {
 		return nil, err
 	}
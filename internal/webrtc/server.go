@@ -3,10 +3,11 @@ package webrtc
 import (
 	"encoding/base64"
 	"encoding/json"
+	"fmt"
 	"io"
 	"net/http"
-	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/hamza-farouk/go2rtc/internal/api"
@@ -18,8 +19,6 @@ import (
 
 const MimeSDP = "application/sdp"
 
-var sessions = map[string]*webrtc.Conn{}
-
 func syncHandler(w http.ResponseWriter, r *http.Request) {
 	switch r.Method {
 	case "POST":
@@ -35,14 +34,13 @@ func syncHandler(w http.ResponseWriter, r *http.Request) {
 		}
 
 	case "PATCH":
-		// TODO: WHEP/WHIP
-		http.Error(w, "", http.StatusMethodNotAllowed)
+		// WHIP/WHEP trickle ICE and ICE restart
+		patchSession(w, r)
 
 	case "DELETE":
 		if id := r.URL.Query().Get("id"); id != "" {
-			if conn, ok := sessions[id]; ok {
-				delete(sessions, id)
-				_ = conn.Close()
+			if s := popSession(id); s != nil {
+				_ = s.conn.Close()
 			} else {
 				http.Error(w, "", http.StatusNotFound)
 			}
@@ -51,6 +49,8 @@ func syncHandler(w http.ResponseWriter, r *http.Request) {
 		}
 
 	case "OPTIONS":
+		// without TURN credentials, because OPTIONS may be unauthenticated
+		setLinkHeaders(w, iceServers)
 		w.WriteHeader(http.StatusNoContent)
 
 	default:
@@ -124,7 +124,7 @@ func outputWebRTC(w http.ResponseWriter, r *http.Request) {
 		desc = "webrtc/post"
 	}
 
-	answer, err := ExchangeSDP(stream, offer, desc, r.UserAgent())
+	conn, answer, err := exchangeSDP(stream, offer, desc, r.UserAgent(), r.URL.Query())
 	if err != nil {
 		log.Error().Err(err).Caller().Send()
 		http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -147,6 +147,7 @@ func outputWebRTC(w http.ResponseWriter, r *http.Request) {
 
 	case MimeSDP:
 		w.Header().Set("Content-Type", mediaType)
+		setSessionHeaders(w, addSession(conn))
 		w.WriteHeader(http.StatusCreated)
 
 		_, err = w.Write([]byte(answer))
@@ -193,6 +194,7 @@ func inputWebRTC(w http.ResponseWriter, r *http.Request) {
 	prod.Mode = core.ModePassiveProducer
 	prod.Protocol = "http"
 	prod.UserAgent = r.UserAgent()
+	setJitter(prod, r.URL.Query().Get("jitter"))
 
 	if err = prod.SetOffer(string(offer)); err != nil {
 		log.Warn().Err(err).Caller().Send()
@@ -200,6 +202,8 @@ func inputWebRTC(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	setOpaque(prod, r.URL.Query().Get("e2ee"))
+
 	answer, err := prod.GetCompleteAnswer(GetCandidates(), FilterCandidate)
 	if err != nil {
 		log.Warn().Err(err).Caller().Send()
@@ -209,15 +213,11 @@ func inputWebRTC(w http.ResponseWriter, r *http.Request) {
 
 	log.Trace().Msgf("[webrtc] WHIP answer\n%s", answer)
 
-	id := strconv.FormatInt(time.Now().UnixNano(), 36)
-	sessions[id] = prod
-
 	prod.Listen(func(msg any) {
 		switch msg := msg.(type) {
 		case pion.PeerConnectionState:
 			if msg == pion.PeerConnectionStateClosed {
 				stream.RemoveProducer(prod)
-				delete(sessions, id)
 			}
 		}
 	})
@@ -225,7 +225,7 @@ func inputWebRTC(w http.ResponseWriter, r *http.Request) {
 	stream.AddProducer(prod)
 
 	w.Header().Set("Content-Type", MimeSDP)
-	w.Header().Set("Location", "webrtc?id="+id)
+	setSessionHeaders(w, addSession(prod))
 	w.WriteHeader(http.StatusCreated)
 
 	if _, err = w.Write([]byte(answer)); err != nil {
@@ -233,3 +233,183 @@ func inputWebRTC(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 }
+
+// session - WHIP/WHEP session, removed on connection close
+// or if connection not established during timeout
+type session struct {
+	id   string
+	conn *webrtc.Conn
+	etag string
+	mu   sync.Mutex // one PATCH at a time, protects etag and conn offer
+}
+
+var sessions = map[string]*session{}
+var sessionsMu sync.Mutex
+var sessionTimeout time.Duration
+
+var iceServers []pion.ICEServer
+
+func addSession(conn *webrtc.Conn) *session {
+	s := &session{
+		id:   core.RandString(22, 62), // about 128 bit, session ID is the only credential for PATCH and DELETE
+		conn: conn,
+		etag: newETag(conn),
+	}
+
+	sessionsMu.Lock()
+	sessions[s.id] = s
+	sessionsMu.Unlock()
+
+	conn.Listen(func(msg any) {
+		if msg == pion.PeerConnectionStateClosed {
+			popSession(s.id)
+		}
+	})
+
+	if sessionTimeout > 0 {
+		timer := time.AfterFunc(sessionTimeout, func() {
+			if popSession(s.id) != nil {
+				log.Debug().Str("id", s.id).Msg("[webrtc] session timeout")
+				_ = conn.Close()
+			}
+		})
+
+		conn.Listen(func(msg any) {
+			if msg == pion.PeerConnectionStateConnected {
+				timer.Stop()
+			}
+		})
+	}
+
+	return s
+}
+
+func getSession(id string) *session {
+	sessionsMu.Lock()
+	defer sessionsMu.Unlock()
+	return sessions[id]
+}
+
+func popSession(id string) *session {
+	sessionsMu.Lock()
+	defer sessionsMu.Unlock()
+	if s, ok := sessions[id]; ok {
+		delete(sessions, id)
+		return s
+	}
+	return nil
+}
+
+// newETag - entity tag of ICE session, changes after ICE restart
+func newETag(conn *webrtc.Conn) string {
+	ufrag, _ := conn.LocalICE()
+	return `"` + ufrag + `"`
+}
+
+func setSessionHeaders(w http.ResponseWriter, s *session) {
+	w.Header().Set("Location", "webrtc?id="+s.id)
+	w.Header().Set("ETag", s.etag)
+	w.Header().Set("Accept-Patch", webrtc.MimeSDPFrag)
+	setLinkHeaders(w, withTURN(iceServers, false))
+}
+
+// setLinkHeaders - ICE servers for WHIP/WHEP clients
+// https://www.rfc-editor.org/rfc/rfc9725#section-4.6
+func setLinkHeaders(w http.ResponseWriter, servers []pion.ICEServer) {
+	for _, server := range servers {
+		for _, u := range server.URLs {
+			link := "<" + u + `>; rel="ice-server"`
+			if server.Username != "" {
+				link += fmt.Sprintf(`; username="%s"; credential="%v"; credential-type="password"`, server.Username, server.Credential)
+			}
+			w.Header().Add("Link", link)
+		}
+	}
+}
+
+func patchSession(w http.ResponseWriter, r *http.Request) {
+	id := r.URL.Query().Get("id")
+	if id == "" {
+		http.Error(w, "", http.StatusBadRequest)
+		return
+	}
+
+	s := getSession(id)
+	if s == nil {
+		http.Error(w, "", http.StatusNotFound)
+		return
+	}
+
+	mediaType, _, _ := strings.Cut(r.Header.Get("Content-Type"), ";")
+	if strings.TrimSpace(mediaType) != webrtc.MimeSDPFrag {
+		http.Error(w, "", http.StatusUnsupportedMediaType)
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	log.Trace().Msgf("[webrtc] PATCH %s\n%s", id, body)
+
+	frag := webrtc.ParseFragment(string(body))
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	match := r.Header.Get("If-Match")
+
+	ufrag, _ := s.conn.RemoteICE()
+
+	// ICE restart - client sends new ICE credentials
+	if frag.Ufrag != "" && frag.Ufrag != ufrag {
+		if frag.Pwd == "" {
+			http.Error(w, "", http.StatusBadRequest)
+			return
+		}
+
+		// https://www.rfc-editor.org/rfc/rfc9725#section-4.3.2
+		if match == "" {
+			http.Error(w, "", http.StatusPreconditionRequired)
+			return
+		}
+		if match != "*" && match != s.etag {
+			http.Error(w, "", http.StatusPreconditionFailed)
+			return
+		}
+
+		answer, err := s.conn.RestartICE(frag.Ufrag, frag.Pwd, GetCandidates(), FilterCandidate)
+		if err != nil {
+			log.Warn().Err(err).Caller().Send()
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		for _, candidate := range frag.Candidates {
+			_ = addRemoteCandidate(s.conn, candidate)
+		}
+
+		s.etag = `"` + answer.Ufrag + `"`
+
+		w.Header().Set("Content-Type", webrtc.MimeSDPFrag)
+		w.Header().Set("ETag", s.etag)
+		_, _ = w.Write([]byte(answer.Marshal()))
+		return
+	}
+
+	// trickle ICE - should match current ICE session
+	if match != "" && match != "*" && match != s.etag {
+		http.Error(w, "", http.StatusPreconditionFailed)
+		return
+	}
+
+	for _, candidate := range frag.Candidates {
+		if err = addRemoteCandidate(s.conn, candidate); err != nil {
+			log.Debug().Err(err).Str("candidate", candidate).Msg("[webrtc] PATCH")
+		}
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,350 @@
+package webrtc
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/hamza-farouk/go2rtc/pkg/core"
+	"github.com/hamza-farouk/go2rtc/pkg/expr"
+	"github.com/hamza-farouk/go2rtc/pkg/webrtc"
+	pion "github.com/pion/webrtc/v4"
+)
+
+// Signaling - declarative description of offer/answer exchange with any
+// HTTP or WebSocket signaling server. All fields except Method and Headers
+// are pkg/expr expressions. Templates have env: sdp, candidate, url, query.
+// Response paths have env: text, json (parsed text).
+type Signaling struct {
+	Method  string            `yaml:"method"` // HTTP method, default POST
+	Headers map[string]string `yaml:"headers"`
+
+	Offer  string `yaml:"offer"`  // HTTP body or WS message with local offer, default raw SDP
+	Answer string `yaml:"answer"` // SDP answer from HTTP response or WS message, default raw text
+
+	Candidate       string `yaml:"candidate"`        // WS message with local candidate, default - no trickle
+	RemoteCandidate string `yaml:"remote_candidate"` // remote candidate(s) from WS message
+}
+
+var signalings map[string]*Signaling
+
+const signalingTimeout = 5 * time.Second
+
+// signalingClient - WebRTC client with signaling from config
+// ex: webrtc:https://example.com/api/offer#format=mycam#token=secret
+func signalingClient(rawURL string, query url.Values, format string, s *Signaling) (core.Producer, error) {
+	pc, err := PeerConnection(true)
+	if err != nil {
+		log.Error().Err(err).Caller().Send()
+		return nil, err
+	}
+
+	prod := webrtc.NewConn(pc)
+	prod.FormatName = "webrtc/" + format
+	prod.Mode = core.ModeActiveProducer
+	prod.URL = rawURL
+
+	env := map[string]any{"url": rawURL, "query": signalingQuery(query)}
+
+	if strings.HasPrefix(rawURL, "ws") {
+		prod.Protocol = "ws"
+		err = s.exchangeWS(prod, env)
+	} else {
+		prod.Protocol = "http"
+		err = s.exchangeHTTP(prod, env)
+	}
+
+	if err != nil {
+		_ = prod.Close()
+		return nil, err
+	}
+
+	return prod, nil
+}
+
+func (s *Signaling) exchangeHTTP(prod *webrtc.Conn, env map[string]any) error {
+	offer, err := prod.CreateCompleteOffer(signalingMedias())
+	if err != nil {
+		return err
+	}
+
+	env["sdp"] = offer
+
+	body, contentType, err := s.build(s.Offer, env)
+	if err != nil {
+		return err
+	}
+
+	method := s.Method
+	if method == "" {
+		method = "POST"
+	}
+
+	req, err := http.NewRequest(method, env["url"].(string), strings.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", contentType)
+	for k, v := range s.Headers {
+		req.Header.Set(k, v)
+	}
+
+	client := http.Client{Timeout: signalingTimeout}
+	defer client.CloseIdleConnections()
+
+	res, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	b, err := io.ReadAll(res.Body)
+	if err != nil {
+		return err
+	}
+
+	if res.StatusCode >= 400 {
+		return errors.New("webrtc: signaling " + res.Status)
+	}
+
+	answer, err := s.parse(s.Answer, b)
+	if err != nil {
+		return err
+	}
+
+	return prod.SetAnswer(answer)
+}
+
+func (s *Signaling) exchangeWS(prod *webrtc.Conn, env map[string]any) error {
+	header := http.Header{}
+	for k, v := range s.Headers {
+		header.Set(k, v)
+	}
+
+	conn, _, err := websocket.DefaultDialer.Dial(env["url"].(string), header)
+	if err != nil {
+		return err
+	}
+
+	// close websocket when we ready return Producer or connection error
+	defer conn.Close()
+
+	// protect from server that never answers, exchange should finish with connected
+	// state, so give ICE some more time than single HTTP request
+	deadline := time.Now().Add(3 * signalingTimeout)
+	_ = conn.SetReadDeadline(deadline)
+	_ = conn.SetWriteDeadline(deadline)
+
+	// protect from sending ICE candidate before Offer
+	var sendOffer core.Waiter
+
+	// protect from blocking on errors
+	defer sendOffer.Done(nil)
+
+	// waiter will wait PC error or WS error or nil (connection OK)
+	var connState core.Waiter
+
+	prod.Listen(func(msg any) {
+		switch msg := msg.(type) {
+		case *pion.ICECandidate:
+			if s.Candidate == "" {
+				return
+			}
+
+			_ = sendOffer.Wait()
+
+			b, _, err := s.build(s.Candidate, map[string]any{
+				"candidate": msg.ToJSON().Candidate, "url": env["url"], "query": env["query"],
+			})
+			if err == nil {
+				err = conn.WriteMessage(websocket.TextMessage, []byte(b))
+			}
+			if err != nil {
+				connState.Done(err)
+				return
+			}
+
+			log.Trace().Msgf("[webrtc] signaling send: %s", b)
+
+		case pion.PeerConnectionState:
+			switch msg {
+			case pion.PeerConnectionStateConnecting:
+			case pion.PeerConnectionStateConnected:
+				connState.Done(nil)
+			default:
+				connState.Done(errors.New("webrtc: " + msg.String()))
+			}
+		}
+	})
+
+	// without candidate template - send offer with all candidates inside
+	var offer string
+	if s.Candidate != "" {
+		offer, err = prod.CreateOffer(signalingMedias())
+	} else {
+		offer, err = prod.CreateCompleteOffer(signalingMedias())
+	}
+	if err != nil {
+		return err
+	}
+
+	env["sdp"] = offer
+
+	b, _, err := s.build(s.Offer, env)
+	if err != nil {
+		return err
+	}
+
+	if err = conn.WriteMessage(websocket.TextMessage, []byte(b)); err != nil {
+		return err
+	}
+
+	log.Trace().Msgf("[webrtc] signaling send: %s", b)
+
+	sendOffer.Done(nil)
+
+	go func() {
+		var err error
+
+		// will be closed when conn will be closed
+		for err == nil {
+			var b []byte
+			if _, b, err = conn.ReadMessage(); err != nil {
+				break
+			}
+
+			log.Trace().Msgf("[webrtc] signaling recv: %s", b)
+
+			var answer string
+			if answer, err = s.parse(s.Answer, b); err != nil {
+				break
+			}
+
+			if answer != "" && strings.HasPrefix(answer, "v=") {
+				if err = prod.SetAnswer(answer); err != nil {
+					break
+				}
+			}
+
+			if s.RemoteCandidate == "" {
+				continue
+			}
+
+			var candidates []string
+			if candidates, err = s.parseCandidates(b); err != nil {
+				break
+			}
+
+			for _, candidate := range candidates {
+				if err = prod.AddCandidate(candidate); err != nil {
+					break
+				}
+			}
+		}
+
+		connState.Done(err)
+	}()
+
+	return connState.Wait()
+}
+
+// build - evaluate template, strings are sent as is, other values as JSON
+func (s *Signaling) build(template string, env map[string]any) (string, string, error) {
+	if template == "" {
+		return env["sdp"].(string), MimeSDP, nil
+	}
+
+	v, err := expr.Eval(template, env)
+	if err != nil {
+		return "", "", err
+	}
+
+	if str, ok := v.(string); ok {
+		return str, MimeSDP, nil
+	}
+
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "", "", err
+	}
+
+	return string(b), "application/json", nil
+}
+
+// parse - evaluate response path, empty path - raw text
+func (s *Signaling) parse(path string, b []byte) (string, error) {
+	if path == "" {
+		return string(b), nil
+	}
+
+	v, err := expr.Eval(path, signalingEnv(b))
+	if err != nil {
+		return "", err
+	}
+
+	switch v := v.(type) {
+	case string:
+		return v, nil
+	case nil:
+		return "", nil
+	}
+
+	return "", fmt.Errorf("webrtc: signaling wrong answer type: %T", v)
+}
+
+func (s *Signaling) parseCandidates(b []byte) ([]string, error) {
+	v, err := expr.Eval(s.RemoteCandidate, signalingEnv(b))
+	if err != nil {
+		return nil, err
+	}
+
+	switch v := v.(type) {
+	case string:
+		if v != "" {
+			return []string{v}, nil
+		}
+	case []any:
+		var candidates []string
+		for _, item := range v {
+			if str, ok := item.(string); ok && str != "" {
+				candidates = append(candidates, str)
+			}
+		}
+		return candidates, nil
+	}
+
+	return nil, nil
+}
+
+func signalingEnv(b []byte) map[string]any {
+	env := map[string]any{"text": string(b), "json": nil}
+
+	var v any
+	if err := json.Unmarshal(b, &v); err == nil {
+		env["json"] = v
+	}
+
+	return env
+}
+
+func signalingQuery(query url.Values) map[string]string {
+	m := make(map[string]string, len(query))
+	for k, v := range query {
+		m[k] = v[0]
+	}
+	return m
+}
+
+func signalingMedias() []*core.Media {
+	return []*core.Media{
+		{Kind: core.KindVideo, Direction: core.DirectionRecvonly},
+		{Kind: core.KindAudio, Direction: core.DirectionRecvonly},
+	}
+}
@@ -0,0 +1,53 @@
+package webrtc
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/hamza-farouk/go2rtc/internal/api"
+	"github.com/hamza-farouk/go2rtc/internal/streams"
+	"github.com/hamza-farouk/go2rtc/pkg/webrtc"
+)
+
+// statsHandler - GET /api/webrtc/stats?id=123 - stats for one connection,
+// without id - stats for all WebRTC connections by stream names
+func statsHandler(w http.ResponseWriter, r *http.Request) {
+	var id uint64
+	if s := r.URL.Query().Get("id"); s != "" {
+		var err error
+		if id, err = strconv.ParseUint(s, 10, 32); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	all := map[string][]*webrtc.Stats{}
+
+	for _, name := range streams.GetAllNames() {
+		stream := streams.Get(name)
+		if stream == nil {
+			continue
+		}
+
+		for _, conn := range stream.Connections() {
+			conn, ok := conn.(*webrtc.Conn)
+			if !ok {
+				continue
+			}
+
+			if id == 0 {
+				all[name] = append(all[name], conn.GetStats())
+			} else if conn.ID == uint32(id) {
+				api.ResponseJSON(w, conn.GetStats())
+				return
+			}
+		}
+	}
+
+	if id != 0 {
+		http.Error(w, "", http.StatusNotFound)
+		return
+	}
+
+	api.ResponseJSON(w, all)
+}
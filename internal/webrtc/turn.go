@@ -0,0 +1,361 @@
+package webrtc
+
+import (
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/hamza-farouk/go2rtc/internal/api"
+	"github.com/hamza-farouk/go2rtc/pkg/core"
+	"github.com/hamza-farouk/go2rtc/pkg/webrtc"
+	"github.com/pion/turn/v4"
+	pion "github.com/pion/webrtc/v4"
+)
+
+type TURNConfig struct {
+	Listen     string   `yaml:"listen"`      // UDP and TCP address, ex. ":3478"
+	PublicIP   string   `yaml:"public_ip"`   // relay address, default - auto detect with STUN
+	RelayPorts []uint16 `yaml:"relay_ports"` // [min, max] range for relay ports, default - random
+	Realm      string   `yaml:"realm"`
+	TTL        int      `yaml:"ttl"` // credentials lifetime, in seconds, default - 3600
+}
+
+func initTURN(cfg *TURNConfig) {
+	if cfg.Listen == "" {
+		return
+	}
+
+	var ip net.IP
+	if cfg.PublicIP != "" {
+		ip = net.ParseIP(cfg.PublicIP)
+	} else {
+		var err error
+		if ip, err = webrtc.GetCachedPublicIP(); err != nil {
+			log.Error().Err(err).Msg("[webrtc] turn public ip")
+			return
+		}
+	}
+
+	if ip == nil {
+		log.Error().Str("public_ip", cfg.PublicIP).Msg("[webrtc] turn wrong public ip")
+		return
+	}
+
+	_, port, err := net.SplitHostPort(cfg.Listen)
+	if err != nil {
+		log.Error().Err(err).Caller().Send()
+		return
+	}
+
+	var generator turn.RelayAddressGenerator
+	if len(cfg.RelayPorts) == 2 {
+		generator = &turn.RelayAddressGeneratorPortRange{
+			RelayAddress: ip,
+			Address:      "0.0.0.0",
+			MinPort:      cfg.RelayPorts[0],
+			MaxPort:      cfg.RelayPorts[1],
+		}
+	} else {
+		generator = &turn.RelayAddressGeneratorStatic{
+			RelayAddress: ip,
+			Address:      "0.0.0.0",
+		}
+	}
+
+	udp, err := net.ListenPacket("udp", cfg.Listen)
+	if err != nil {
+		log.Error().Err(err).Msg("[webrtc] turn listen")
+		return
+	}
+
+	tcp, err := net.Listen("tcp", cfg.Listen)
+	if err != nil {
+		_ = udp.Close()
+		log.Error().Err(err).Msg("[webrtc] turn listen")
+		return
+	}
+
+	secret := core.RandString(32, 62)
+
+	stats := &relayStats{generator: generator, relays: map[*relayConn]struct{}{}}
+
+	permission := turnPermission(append(localIPs(), ip), sessionPeer)
+
+	turnServer, err = turn.NewServer(turn.ServerConfig{
+		Realm:       cfg.Realm,
+		AuthHandler: turn.NewLongTermAuthHandler(secret, nil),
+		PacketConnConfigs: []turn.PacketConnConfig{
+			{PacketConn: udp, RelayAddressGenerator: stats, PermissionHandler: permission},
+		},
+		ListenerConfigs: []turn.ListenerConfig{
+			{Listener: tcp, RelayAddressGenerator: stats, PermissionHandler: permission},
+		},
+	})
+	if err != nil {
+		_ = udp.Close()
+		_ = tcp.Close()
+		log.Error().Err(err).Caller().Send()
+		return
+	}
+
+	log.Info().Str("addr", cfg.Listen).Str("relay", ip.String()).Msg("[webrtc] turn listen")
+
+	turnStats = stats
+	turnSecret = secret
+	if cfg.TTL > 0 {
+		turnTTL = time.Duration(cfg.TTL) * time.Second
+	} else {
+		turnTTL = time.Hour
+	}
+	turnURLs = []string{
+		"turn:" + net.JoinHostPort(ip.String(), port) + "?transport=udp",
+		"turn:" + net.JoinHostPort(ip.String(), port) + "?transport=tcp",
+	}
+	// local PeerConnections use loopback for allocations
+	turnLocalURLs = []string{"turn:127.0.0.1:" + port + "?transport=udp"}
+
+	api.HandleFunc("api/webrtc/turn", turnHandler)
+}
+
+var turnServer *turn.Server
+var turnStats *relayStats
+var turnSecret string
+var turnTTL time.Duration
+var turnURLs, turnLocalURLs []string
+
+// turnPermission - remote clients can relay only to addresses of this host
+// and to peers of active WebRTC sessions. Local PeerConnections (loopback
+// clients) can relay to any peer.
+func turnPermission(hostIPs []net.IP, sessionPeer func(ip net.IP) bool) turn.PermissionHandler {
+	return func(clientAddr net.Addr, peerIP net.IP) bool {
+		if ip := addrIP(clientAddr); ip != nil && ip.IsLoopback() {
+			return true
+		}
+
+		if peerIP.IsLoopback() || peerIP.IsUnspecified() || peerIP.IsMulticast() {
+			return false
+		}
+
+		for _, ip := range hostIPs {
+			if ip.Equal(peerIP) {
+				return true
+			}
+		}
+
+		if sessionPeer(peerIP) {
+			return true
+		}
+
+		log.Debug().Stringer("client", clientAddr).Stringer("peer", peerIP).Msg("[webrtc] turn deny peer")
+
+		return false
+	}
+}
+
+var turnPeers = map[*pion.PeerConnection]struct{}{}
+var turnPeersMu sync.Mutex
+
+// addTURNPeer - remember PeerConnection, so remote clients can relay to its candidates
+func addTURNPeer(pc *pion.PeerConnection) {
+	if turnServer == nil {
+		return
+	}
+
+	turnPeersMu.Lock()
+	pruneTURNPeers()
+	turnPeers[pc] = struct{}{}
+	turnPeersMu.Unlock()
+}
+
+// pruneTURNPeers - remove closed PeerConnections, should be called under lock
+func pruneTURNPeers() {
+	for pc := range turnPeers {
+		if pc.ConnectionState() == pion.PeerConnectionStateClosed {
+			delete(turnPeers, pc)
+		}
+	}
+}
+
+// sessionPeer - check if IP is one of local candidates of active PeerConnections
+func sessionPeer(peerIP net.IP) bool {
+	turnPeersMu.Lock()
+	defer turnPeersMu.Unlock()
+
+	pruneTURNPeers()
+
+	for pc := range turnPeers {
+		if desc := pc.LocalDescription(); desc != nil && candidateIP(desc.SDP, peerIP) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// candidateIP - check if SDP has candidate with IP
+// a=candidate:foundation component transport priority address port typ type
+func candidateIP(sdp string, ip net.IP) bool {
+	for _, line := range strings.Split(sdp, "\n") {
+		if !strings.HasPrefix(line, "a=candidate:") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) > 4 && ip.Equal(net.ParseIP(fields[4])) {
+			return true
+		}
+	}
+	return false
+}
+
+func localIPs() (ips []net.IP) {
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return nil
+	}
+	for _, addr := range addrs {
+		if ipNet, ok := addr.(*net.IPNet); ok && !ipNet.IP.IsLoopback() {
+			ips = append(ips, ipNet.IP)
+		}
+	}
+	return
+}
+
+func addrIP(addr net.Addr) net.IP {
+	switch addr := addr.(type) {
+	case *net.UDPAddr:
+		return addr.IP
+	case *net.TCPAddr:
+		return addr.IP
+	}
+	return nil
+}
+
+// turnICEServer - embedded TURN server with new short-lived credentials
+func turnICEServer(local bool) (server pion.ICEServer, ok bool) {
+	if turnServer == nil {
+		return
+	}
+
+	username, password, err := turn.GenerateLongTermCredentials(turnSecret, turnTTL)
+	if err != nil {
+		return
+	}
+
+	server.Username = username
+	server.Credential = password
+	if local {
+		server.URLs = turnLocalURLs
+	} else {
+		server.URLs = turnURLs
+	}
+
+	return server, true
+}
+
+// withTURN - add embedded TURN server to ICE servers list
+func withTURN(servers []pion.ICEServer, local bool) []pion.ICEServer {
+	if server, ok := turnICEServer(local); ok {
+		return append(servers[:len(servers):len(servers)], server)
+	}
+	return servers
+}
+
+func turnHandler(w http.ResponseWriter, r *http.Request) {
+	type relay struct {
+		Network   string `json:"network"`
+		RelayAddr string `json:"relay_addr"`
+		Recv      int64  `json:"bytes_recv"`
+		Send      int64  `json:"bytes_send"`
+	}
+
+	var info struct {
+		Allocations int     `json:"allocations"`
+		Recv        int64   `json:"bytes_recv"`
+		Send        int64   `json:"bytes_send"`
+		Relays      []relay `json:"relays"`
+	}
+
+	info.Allocations = turnServer.AllocationCount()
+	info.Recv = turnStats.recv.Load()
+	info.Send = turnStats.send.Load()
+
+	turnStats.mu.Lock()
+	for conn := range turnStats.relays {
+		info.Relays = append(info.Relays, relay{
+			Network:   conn.LocalAddr().Network(),
+			RelayAddr: conn.relayAddr.String(),
+			Recv:      conn.recv.Load(),
+			Send:      conn.send.Load(),
+		})
+	}
+	turnStats.mu.Unlock()
+
+	api.ResponseJSON(w, info)
+}
+
+// relayStats - wrapper for relay generator with counting relayed bytes
+type relayStats struct {
+	generator turn.RelayAddressGenerator
+
+	relays map[*relayConn]struct{}
+	mu     sync.Mutex
+
+	recv, send atomic.Int64 // total for all relays
+}
+
+func (s *relayStats) Validate() error {
+	return s.generator.Validate()
+}
+
+func (s *relayStats) AllocatePacketConn(network string, requestedPort int) (net.PacketConn, net.Addr, error) {
+	conn, addr, err := s.generator.AllocatePacketConn(network, requestedPort)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	relay := &relayConn{PacketConn: conn, relayAddr: addr, stats: s}
+
+	s.mu.Lock()
+	s.relays[relay] = struct{}{}
+	s.mu.Unlock()
+
+	log.Debug().Str("relay", addr.String()).Msg("[webrtc] turn allocate")
+
+	return relay, addr, nil
+}
+
+func (s *relayStats) AllocateConn(network string, requestedPort int) (net.Conn, net.Addr, error) {
+	return s.generator.AllocateConn(network, requestedPort)
+}
+
+type relayConn struct {
+	net.PacketConn
+	relayAddr net.Addr
+	stats     *relayStats
+
+	recv, send atomic.Int64
+}
+
+func (c *relayConn) ReadFrom(b []byte) (int, net.Addr, error) {
+	n, addr, err := c.PacketConn.ReadFrom(b)
+	c.recv.Add(int64(n))
+	c.stats.recv.Add(int64(n))
+	return n, addr, err
+}
+
+func (c *relayConn) WriteTo(b []byte, addr net.Addr) (int, error) {
+	n, err := c.PacketConn.WriteTo(b, addr)
+	c.send.Add(int64(n))
+	c.stats.send.Add(int64(n))
+	return n, err
+}
+
+func (c *relayConn) Close() error {
+	c.stats.mu.Lock()
+	delete(c.stats.relays, c)
+	c.stats.mu.Unlock()
+	return c.PacketConn.Close()
+}
@@ -2,7 +2,9 @@ package webrtc
 
 import (
 	"errors"
+	"net/url"
 	"strings"
+	"time"
 
 	"github.com/hamza-farouk/go2rtc/internal/api"
 	"github.com/hamza-farouk/go2rtc/internal/api/ws"
@@ -21,6 +23,14 @@ func Init() {
 			Candidates []string         `yaml:"candidates"`
 			IceServers []pion.ICEServer `yaml:"ice_servers"`
 			Filters    webrtc.Filters   `yaml:"filters"`
+
+			SessionTimeout int `yaml:"session_timeout"` // WHIP/WHEP sessions, in seconds
+
+			PublicIPInterval int `yaml:"public_ip_interval"` // re-discovery for stun candidates, in seconds
+
+			TURN TURNConfig `yaml:"turn"` // embedded TURN server
+
+			Signaling map[string]*Signaling `yaml:"signaling"` // declarative signaling formats
 		} `yaml:"webrtc"`
 	}
 
@@ -28,18 +38,27 @@ func Init() {
 	cfg.Mod.IceServers = []pion.ICEServer{
 		{URLs: []string{"stun:stun.l.google.com:19302"}},
 	}
+	cfg.Mod.SessionTimeout = 30
+	cfg.Mod.PublicIPInterval = 300
+	cfg.Mod.TURN.Realm = "go2rtc"
+	cfg.Mod.TURN.TTL = 3600
 
 	app.LoadConfig(&cfg)
 
 	log = app.GetLogger("webrtc")
 
 	filters = cfg.Mod.Filters
+	iceServers = cfg.Mod.IceServers
+	sessionTimeout = time.Duration(cfg.Mod.SessionTimeout) * time.Second
+	signalings = cfg.Mod.Signaling
 
 	address, network, _ := strings.Cut(cfg.Mod.Listen, "/")
 	for _, candidate := range cfg.Mod.Candidates {
 		AddCandidate(network, candidate)
 	}
 
+	initPublicIP(time.Duration(cfg.Mod.PublicIPInterval) * time.Second)
+
 	var err error
 
 	// create pionAPI with custom codecs list and custom network settings
@@ -57,18 +76,33 @@ func Init() {
 		clientAPI, _ = webrtc.NewAPI()
 	}
 
+	initTURN(&cfg.Mod.TURN)
+
 	pionConf := pion.Configuration{
 		ICEServers:   cfg.Mod.IceServers,
 		SDPSemantics: pion.SDPSemanticsUnifiedPlanWithFallback,
 	}
 
 	PeerConnection = func(active bool) (*pion.PeerConnection, error) {
+		// embedded TURN server with new credentials for every connection
+		pionConf := pionConf
+		pionConf.ICEServers = withTURN(pionConf.ICEServers, true)
+
 		// active - client, passive - server
+		var pc *pion.PeerConnection
+		var err error
 		if active {
-			return clientAPI.NewPeerConnection(pionConf)
+			pc, err = webrtc.NewPeerConnection(clientAPI, pionConf)
 		} else {
-			return serverAPI.NewPeerConnection(pionConf)
+			pc, err = webrtc.NewPeerConnection(serverAPI, pionConf)
+		}
+		if err != nil {
+			return nil, err
 		}
+
+		addTURNPeer(pc)
+
+		return pc, nil
 	}
 
 	// async WebRTC server (two API versions)
@@ -78,6 +112,7 @@ func Init() {
 
 	// sync WebRTC server (two API versions)
 	api.HandleFunc("api/webrtc", syncHandler)
+	api.HandleFunc("api/webrtc/stats", statsHandler)
 
 	// WebRTC client
 	streams.HandleFunc("webrtc", streamsHandler)
@@ -130,7 +165,9 @@ func asyncHandler(tr *ws.Transport, msg *ws.Message) (err error) {
 	if offer.ICEServers == nil {
 		pc, err = PeerConnection(false)
 	} else {
-		pc, err = serverAPI.NewPeerConnection(pion.Configuration{ICEServers: offer.ICEServers})
+		if pc, err = webrtc.NewPeerConnection(serverAPI, pion.Configuration{ICEServers: offer.ICEServers}); err == nil {
+			addTURNPeer(pc)
+		}
 	}
 	if err != nil {
 		log.Error().Err(err).Caller().Send()
@@ -181,6 +218,9 @@ func asyncHandler(tr *ws.Transport, msg *ws.Message) (err error) {
 
 	switch mode {
 	case core.ModePassiveConsumer:
+		core.SetLayer(conn.Medias, query.Get("layer"))
+		setOpaque(conn, query.Get("e2ee"))
+
 		// 2. AddConsumer, so we get new tracks
 		if err = stream.AddConsumer(conn); err != nil {
 			log.Debug().Err(err).Msg("[webrtc] add consumer")
@@ -188,6 +228,8 @@ func asyncHandler(tr *ws.Transport, msg *ws.Message) (err error) {
 			return err
 		}
 	case core.ModePassiveProducer:
+		setJitter(conn, query.Get("jitter"))
+		setOpaque(conn, query.Get("e2ee"))
 		stream.AddProducer(conn)
 	}
 
@@ -215,6 +257,11 @@ func asyncHandler(tr *ws.Transport, msg *ws.Message) (err error) {
 }
 
 func ExchangeSDP(stream *streams.Stream, offer, desc, userAgent string) (answer string, err error) {
+	_, answer, err = exchangeSDP(stream, offer, desc, userAgent, nil)
+	return
+}
+
+func exchangeSDP(stream *streams.Stream, offer, desc, userAgent string, query url.Values) (conn *webrtc.Conn, answer string, err error) {
 	pc, err := PeerConnection(false)
 	if err != nil {
 		log.Error().Err(err).Caller().Send()
@@ -222,7 +269,7 @@ func ExchangeSDP(stream *streams.Stream, offer, desc, userAgent string) (answer
 	}
 
 	// create new webrtc instance
-	conn := webrtc.NewConn(pc)
+	conn = webrtc.NewConn(pc)
 	conn.FormatName = desc
 	conn.UserAgent = userAgent
 	conn.Protocol = "http"
@@ -250,6 +297,8 @@ func ExchangeSDP(stream *streams.Stream, offer, desc, userAgent string) (answer
 
 	if IsConsumer(conn) {
 		conn.Mode = core.ModePassiveConsumer
+		core.SetLayer(conn.Medias, query.Get("layer"))
+		setOpaque(conn, query.Get("e2ee"))
 
 		// 2. AddConsumer, so we get new tracks
 		if err = stream.AddConsumer(conn); err != nil {
@@ -259,6 +308,7 @@ func ExchangeSDP(stream *streams.Stream, offer, desc, userAgent string) (answer
 		}
 	} else {
 		conn.Mode = core.ModePassiveProducer
+		setOpaque(conn, query.Get("e2ee"))
 
 		stream.AddProducer(conn)
 	}
@@ -2,6 +2,7 @@ package webrtc
 
 import (
 	"encoding/json"
+	"net"
 	"strings"
 	"testing"
 
@@ -71,3 +72,88 @@ a=candidate:3 1 TCP 1010827519 172.22.233.10 60677 typ host tcptype passive
 	require.Nil(t, err)
 	require.False(t, strings.Contains(sdp, "x-google-max-bitrate"))
 }
+
+func TestSignaling(t *testing.T) {
+	s := &Signaling{
+		Offer:           `{"action":"offer","token":query.token,"payload":{"type":"offer","sdp":sdp}}`,
+		Answer:          `json?.action == "answer" ? json.payload.sdp : nil`,
+		Candidate:       `{"action":"candidate","candidate":candidate}`,
+		RemoteCandidate: `json?.action == "candidates" ? json.payload : nil`,
+	}
+
+	env := map[string]any{"sdp": "v=0\n...", "query": map[string]string{"token": "secret"}}
+	body, contentType, err := s.build(s.Offer, env)
+	require.Nil(t, err)
+	require.Equal(t, "application/json", contentType)
+	require.Equal(t, `{"action":"offer","payload":{"sdp":"v=0\n...","type":"offer"},"token":"secret"}`, body)
+
+	answer, err := s.parse(s.Answer, []byte(`{"action":"answer","payload":{"sdp":"v=0\r\n"}}`))
+	require.Nil(t, err)
+	require.Equal(t, "v=0\r\n", answer)
+
+	answer, err = s.parse(s.Answer, []byte(`{"action":"ping"}`))
+	require.Nil(t, err)
+	require.Equal(t, "", answer)
+
+	candidates, err := s.parseCandidates([]byte(`{"action":"candidates","payload":["candidate:1","candidate:2"]}`))
+	require.Nil(t, err)
+	require.Equal(t, []string{"candidate:1", "candidate:2"}, candidates)
+
+	// empty templates - raw SDP in both directions
+	s = &Signaling{}
+	body, contentType, err = s.build(s.Offer, env)
+	require.Nil(t, err)
+	require.Equal(t, MimeSDP, contentType)
+	require.Equal(t, "v=0\n...", body)
+
+	answer, err = s.parse(s.Answer, []byte("v=0\r\n"))
+	require.Nil(t, err)
+	require.Equal(t, "v=0\r\n", answer)
+}
+
+func TestCandidates(t *testing.T) {
+	require.True(t, IsMDNSCandidate("candidate:1 1 udp 2122262783 1f7ae4e3-77b5-4ea3-a5c4-ae0b2e6b3e11.local 54321 typ host"))
+	require.False(t, IsMDNSCandidate("candidate:1 1 udp 2122262783 192.168.1.123 54321 typ host"))
+
+	candidate := &pion.ICECandidate{Address: "fe80::1", Protocol: pion.ICEProtocolUDP, Typ: pion.ICECandidateTypeHost}
+	require.False(t, FilterCandidate(candidate))
+
+	candidate.Address = "2001:db8::1"
+	require.True(t, FilterCandidate(candidate))
+
+	candidate.Address = "172.17.0.2"
+	require.False(t, FilterCandidate(candidate))
+}
+
+func TestTURNPermission(t *testing.T) {
+	host := net.ParseIP("192.168.1.10")
+	session := net.ParseIP("198.51.100.8")
+	permission := turnPermission([]net.IP{host}, func(ip net.IP) bool {
+		return ip.Equal(session)
+	})
+
+	remote := &net.UDPAddr{IP: net.ParseIP("203.0.113.5"), Port: 50000}
+	require.True(t, permission(remote, host))
+	require.True(t, permission(remote, session))
+	require.False(t, permission(remote, net.ParseIP("198.51.100.7")))
+	require.False(t, permission(remote, net.ParseIP("127.0.0.1")))
+	require.False(t, permission(remote, net.ParseIP("192.168.1.1")))
+	require.False(t, permission(remote, net.ParseIP("10.0.0.1")))
+	require.False(t, permission(remote, net.ParseIP("169.254.1.1")))
+	require.False(t, permission(remote, net.ParseIP("fe80::1")))
+
+	// local PeerConnections can relay to any peers
+	local := &net.TCPAddr{IP: net.ParseIP("127.0.0.1"), Port: 50000}
+	require.True(t, permission(local, net.ParseIP("192.168.1.1")))
+	require.True(t, permission(local, net.ParseIP("198.51.100.7")))
+}
+
+func TestCandidateIP(t *testing.T) {
+	sdp := "v=0\r\n" +
+		"a=candidate:1 1 udp 2130706431 192.168.1.10 8555 typ host\r\n" +
+		"a=candidate:2 1 udp 1694498815 198.51.100.8 8555 typ srflx raddr 0.0.0.0 rport 8555\r\n"
+	require.True(t, candidateIP(sdp, net.ParseIP("192.168.1.10")))
+	require.True(t, candidateIP(sdp, net.ParseIP("198.51.100.8")))
+	require.False(t, candidateIP(sdp, net.ParseIP("0.0.0.0")))
+	require.False(t, candidateIP(sdp, net.ParseIP("198.51.100.7")))
+}
@@ -23,11 +23,13 @@ import (
 	"github.com/hamza-farouk/go2rtc/internal/isapi"
 	"github.com/hamza-farouk/go2rtc/internal/ivideon"
 	"github.com/hamza-farouk/go2rtc/internal/mjpeg"
+	"github.com/hamza-farouk/go2rtc/internal/mosaic"
 	"github.com/hamza-farouk/go2rtc/internal/mp4"
 	"github.com/hamza-farouk/go2rtc/internal/mpegts"
 	"github.com/hamza-farouk/go2rtc/internal/nest"
 	"github.com/hamza-farouk/go2rtc/internal/ngrok"
 	"github.com/hamza-farouk/go2rtc/internal/onvif"
+	"github.com/hamza-farouk/go2rtc/internal/osd"
 	"github.com/hamza-farouk/go2rtc/internal/ring"
 	"github.com/hamza-farouk/go2rtc/internal/roborock"
 	"github.com/hamza-farouk/go2rtc/internal/rtmp"
@@ -93,6 +95,7 @@ func main() {
 	expr.Init()     // expr source
 	gopro.Init()    // gopro source
 	doorbird.Init() // doorbird source
+	mosaic.Init()   // mosaic source
 	v4l2.Init()     // v4l2 source
 	alsa.Init()     // alsa source
 	flussonic.Init()
@@ -103,6 +106,7 @@ func main() {
 
 	ngrok.Init() // ngrok module
 	srtp.Init()  // SRTP server
+	osd.Init()   // OSD streams
 	debug.Init() // debug API
 
 	// 7. Go
@@ -0,0 +1,188 @@
+// Package av1 - AV1 bitstream related functions
+package av1
+
+import (
+	"fmt"
+
+	"github.com/hamza-farouk/go2rtc/pkg/bits"
+	"github.com/hamza-farouk/go2rtc/pkg/core"
+)
+
+const (
+	OBUTypeSequenceHeader     = 1
+	OBUTypeTemporalDelimiter  = 2
+	OBUTypeFrameHeader        = 3
+	OBUTypeTileGroup          = 4
+	OBUTypeMetadata           = 5
+	OBUTypeFrame              = 6
+	OBUTypeRedundantFrameHead = 7
+	OBUTypePadding            = 15
+)
+
+func OBUType(b []byte) byte {
+	return (b[0] >> 3) & 0b1111
+}
+
+// SplitOBU - split low overhead bitstream format (OBUs with size field) to separate OBUs
+func SplitOBU(b []byte) (obus [][]byte) {
+	for len(b) > 0 {
+		i := 1
+		if b[0]&0b100 != 0 {
+			i++ // extension header
+		}
+
+		if b[0]&0b10 == 0 {
+			return append(obus, b) // OBU without size field should be last
+		}
+
+		if len(b) < i {
+			return
+		}
+
+		size, n := ReadLEB128(b[i:])
+		if n == 0 || len(b) < i+n+int(size) {
+			return
+		}
+
+		i += n + int(size)
+		obus = append(obus, b[:i])
+		b = b[i:]
+	}
+	return
+}
+
+func IsKeyframe(b []byte) bool {
+	for _, obu := range SplitOBU(b) {
+		if OBUType(obu) == OBUTypeSequenceHeader {
+			return true
+		}
+	}
+	return false
+}
+
+// GetSequenceHeader - return sequence header OBU from low overhead bitstream format
+func GetSequenceHeader(b []byte) []byte {
+	for _, obu := range SplitOBU(b) {
+		if OBUType(obu) == OBUTypeSequenceHeader {
+			return obu
+		}
+	}
+	return nil
+}
+
+// EncodeConfig - build AV1CodecConfigurationRecord (av1C) from sequence header OBU
+// https://aomediacodec.github.io/av1-isobmff/#av1codecconfigurationrecord
+func EncodeConfig(seqHeader []byte) []byte {
+	profile, level, tier := decodeSequenceHeader(seqHeader)
+
+	return append([]byte{
+		0x81, // marker + version
+		profile<<5 | level,
+		tier<<7 | 0b1100, // chroma_subsampling_x + chroma_subsampling_y (4:2:0)
+		0,                // no initial_presentation_delay
+	}, seqHeader...)
+}
+
+// ConfigToCodec - codec for AV1 from FLV/RTMP. There is no RTP packetizer,
+// so only FLV/RTMP consumers support this codec.
+func ConfigToCodec(conf []byte) *core.Codec {
+	codec := &core.Codec{
+		Name:        core.CodecAV1,
+		ClockRate:   90000,
+		PayloadType: core.PayloadTypeRAW,
+	}
+
+	if len(conf) >= 3 {
+		codec.FmtpLine = fmt.Sprintf(
+			"profile=%d;level-idx=%d;tier=%d", conf[1]>>5, conf[1]&0b11111, conf[2]>>7,
+		)
+	}
+
+	return codec
+}
+
+// ReadLEB128 - return value and number of read bytes (zero on error)
+func ReadLEB128(b []byte) (v uint32, n int) {
+	for i := 0; i < 8 && i < len(b); i++ {
+		v |= uint32(b[i]&0x7F) << (i * 7)
+		if b[i]&0x80 == 0 {
+			return v, i + 1
+		}
+	}
+	return 0, 0
+}
+
+func AppendLEB128(b []byte, v uint32) []byte {
+	for {
+		if v < 0x80 {
+			return append(b, byte(v))
+		}
+		b = append(b, byte(v)|0x80)
+		v >>= 7
+	}
+}
+
+// decodeSequenceHeader - read only first operating point profile, level and tier
+// https://aomediacodec.github.io/av1-spec/#sequence-header-obu-syntax
+func decodeSequenceHeader(obu []byte) (profile, level, tier byte) {
+	if len(obu) < 2 {
+		return
+	}
+
+	i := 1
+	if obu[0]&0b100 != 0 {
+		i++ // extension header
+	}
+	if obu[0]&0b10 != 0 {
+		_, n := ReadLEB128(obu[i:])
+		i += n
+	}
+	if len(obu) <= i {
+		return
+	}
+
+	r := bits.NewReader(obu[i:])
+
+	profile = r.ReadBits8(3) // seq_profile
+	_ = r.ReadBit()          // still_picture
+
+	if r.ReadBit() != 0 { // reduced_still_picture_header
+		level = r.ReadBits8(5) // seq_level_idx[0]
+		return
+	}
+
+	if r.ReadBit() != 0 { // timing_info_present_flag
+		_ = r.ReadUint32()    // num_units_in_display_tick
+		_ = r.ReadUint32()    // time_scale
+		if r.ReadBit() != 0 { // equal_picture_interval
+			readUVLC(r) // num_ticks_per_picture_minus_1
+		}
+
+		if r.ReadBit() != 0 { // decoder_model_info_present_flag
+			_ = r.ReadBits8(5) // buffer_delay_length_minus_1
+			_ = r.ReadUint32() // num_units_in_decoding_tick
+			_ = r.ReadBits8(5) // buffer_removal_time_length_minus_1
+			_ = r.ReadBits8(5) // frame_presentation_time_length_minus_1
+		}
+	}
+
+	_ = r.ReadBit()        // initial_display_delay_present_flag
+	_ = r.ReadBits8(5)     // operating_points_cnt_minus_1
+	_ = r.ReadBits(12)     // operating_point_idc[0]
+	level = r.ReadBits8(5) // seq_level_idx[0]
+	if level > 7 {
+		tier = r.ReadBit() // seq_tier[0]
+	}
+
+	return
+}
+
+func readUVLC(r *bits.Reader) {
+	var leadingZeros byte
+	for r.ReadBit() == 0 && leadingZeros < 32 {
+		leadingZeros++
+	}
+	if leadingZeros < 32 {
+		_ = r.ReadBits(leadingZeros)
+	}
+}
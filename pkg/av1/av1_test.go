@@ -0,0 +1,36 @@
+package av1
+
+import (
+	"testing"
+
+	"github.com/pion/rtp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncodeConfig(t *testing.T) {
+	// sequence header OBU with size field, reduced still picture header, level 8
+	seqHeader := []byte{0x0A, 0x02, 0x0A, 0x00}
+
+	conf := EncodeConfig(seqHeader)
+	require.Equal(t, []byte{0x81, 0x08, 0x0C, 0x00, 0x0A, 0x02, 0x0A, 0x00}, conf)
+
+	codec := ConfigToCodec(conf)
+	require.Equal(t, "profile=0;level-idx=8;tier=0", codec.FmtpLine)
+}
+
+func TestRTPDepay(t *testing.T) {
+	var frames [][]byte
+	depay := RTPDepay(func(packet *rtp.Packet) {
+		frames = append(frames, packet.Payload)
+	})
+
+	// W=2: sequence header (with length) + first part of frame OBU (without length), Y=1
+	depay(&rtp.Packet{Payload: []byte{0b0110_0000, 2, 0x08, 0x00, 0x30, 0xAA}})
+	// W=1, Z=1: last part of frame OBU
+	depay(&rtp.Packet{Header: rtp.Header{Marker: true}, Payload: []byte{0b1001_0000, 0xBB, 0xCC}})
+
+	require.Len(t, frames, 1)
+	require.Equal(t, []byte{0x0A, 0x01, 0x00, 0x32, 0x03, 0xAA, 0xBB, 0xCC}, frames[0])
+	require.True(t, IsKeyframe(frames[0]))
+	require.Equal(t, []byte{0x0A, 0x01, 0x00}, GetSequenceHeader(frames[0]))
+}
@@ -0,0 +1,97 @@
+package av1
+
+import (
+	"github.com/hamza-farouk/go2rtc/pkg/core"
+	"github.com/pion/rtp"
+)
+
+// RTPDepay - convert RTP packets to low overhead bitstream format (one temporal unit per packet)
+// https://aomediacodec.github.io/av1-rtp-spec/
+func RTPDepay(handler core.HandlerFunc) core.HandlerFunc {
+	var buf, frag []byte
+
+	return func(packet *rtp.Packet) {
+		b := packet.Payload
+		if len(b) < 2 {
+			return
+		}
+
+		z := b[0]&0b1000_0000 != 0 // first OBU element is a continuation
+		y := b[0]&0b0100_0000 != 0 // last OBU element will continue
+		w := int(b[0]>>4) & 0b11   // number of OBU elements
+		b = b[1:]
+
+		for i := 1; len(b) > 0; i++ {
+			var elem []byte
+
+			if w == 0 || i < w {
+				size, n := ReadLEB128(b)
+				if n == 0 || len(b) < n+int(size) {
+					buf, frag = buf[:0], frag[:0]
+					return
+				}
+				elem = b[n : n+int(size)]
+				b = b[n+int(size):]
+			} else {
+				elem, b = b, nil
+			}
+
+			if i == 1 && z {
+				if len(frag) == 0 {
+					continue // lost start of fragment
+				}
+				elem = append(frag, elem...)
+				frag = frag[:0]
+			} else if i == 1 {
+				frag = frag[:0]
+			}
+
+			if len(b) == 0 && y {
+				frag = append(frag[:0], elem...)
+				break
+			}
+
+			buf = appendOBU(buf, elem)
+		}
+
+		if !packet.Marker || len(buf) == 0 {
+			return
+		}
+
+		clone := *packet
+		clone.Version = 0
+		clone.Payload = buf
+		handler(&clone)
+
+		buf = nil
+	}
+}
+
+// appendOBU - add size field to OBU and skip temporal delimiters and padding
+func appendOBU(buf, obu []byte) []byte {
+	if len(obu) == 0 {
+		return buf
+	}
+
+	switch OBUType(obu) {
+	case OBUTypeTemporalDelimiter, OBUTypePadding:
+		return buf
+	}
+
+	if obu[0]&0b10 != 0 {
+		return append(buf, obu...) // already has size field
+	}
+
+	i := 1
+	if obu[0]&0b100 != 0 {
+		i++ // extension header
+	}
+	if len(obu) < i {
+		return buf
+	}
+
+	buf = append(buf, obu[0]|0b10)
+	buf = append(buf, obu[1:i]...)
+	buf = AppendLEB128(buf, uint32(len(obu)-i))
+	return append(buf, obu[i:]...)
+}
@@ -69,6 +69,24 @@ type Consumer interface {
 	Stop() error
 }
 
+// Message - custom message between consumers and producers of one stream
+// (ex. WebRTC data channel), incoming messages are fired as *Message event
+type Message struct {
+	Type  string `json:"type"`
+	Value any    `json:"value,omitempty"`
+}
+
+// Messenger - optional interface for consumers and producers that can send messages
+type Messenger interface {
+	SendMessage(msg *Message) error
+}
+
+// KeyframeRequester - optional interface for producers that can send keyframe
+// on request (ex. PLI/FIR from WebRTC viewer)
+type KeyframeRequester interface {
+	RequestKeyframe() error
+}
+
 type Mode byte
 
 const (
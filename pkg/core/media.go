@@ -17,8 +17,15 @@ type Media struct {
 	Codecs    []*Codec `json:"codecs,omitempty"`
 
 	ID string `json:"id,omitempty"` // MID for WebRTC, Control for RTSP
+
+	Layer string `json:"layer,omitempty"` // simulcast layer (RID) for WebRTC
+
+	Opaque bool `json:"opaque,omitempty"` // encrypted payload (E2EE), can be passed only as is
 }
 
+// LayerAuto - consumer media accepts all simulcast layers and switch between them by itself
+const LayerAuto = "auto"
+
 func (m *Media) String() string {
 	s := fmt.Sprintf("%s, %s", m.Kind, m.Direction)
 	for _, codec := range m.Codecs {
@@ -30,6 +37,12 @@ func (m *Media) String() string {
 
 		s += ", " + name
 	}
+	if m.Layer != "" {
+		s += ", layer=" + m.Layer
+	}
+	if m.Opaque {
+		s += ", opaque"
+	}
 	return s
 }
 
@@ -54,6 +67,17 @@ func (m *Media) MatchMedia(remote *Media) (codec, remoteCodec *Codec) {
 		return nil, nil
 	}
 
+	// check simulcast layer if both medias have it
+	if m.Layer != "" && remote.Layer != "" && m.Layer != remote.Layer &&
+		m.Layer != LayerAuto && remote.Layer != LayerAuto {
+		return nil, nil
+	}
+
+	// opaque (encrypted) media can be passed only to opaque media
+	if m.Opaque != remote.Opaque {
+		return nil, nil
+	}
+
 	for _, codec = range m.Codecs {
 		for _, remoteCodec = range remote.Codecs {
 			if codec.Match(remoteCodec) {
@@ -90,6 +114,18 @@ func (m *Media) Equal(media *Media) bool {
 	return m.String() == media.String()
 }
 
+// SetLayer - select simulcast layer for all video medias (ex. from "layer" query param)
+func SetLayer(medias []*Media, layer string) {
+	if layer == "" {
+		return
+	}
+	for _, media := range medias {
+		if media.Kind == KindVideo {
+			media.Layer = layer
+		}
+	}
+}
+
 func GetKind(name string) string {
 	switch name {
 	case CodecH264, CodecH265, CodecVP8, CodecVP9, CodecAV1, CodecJPEG, CodecRAW:
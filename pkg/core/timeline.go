@@ -0,0 +1,75 @@
+package core
+
+import (
+	"sync"
+	"time"
+)
+
+// Timeline - shared wall clock grid for segmenters of different streams (ex. ABR
+// variants). Segments are cut on the first keyframe in the next grid slot, and
+// segments started in the same slot get the same sequence number and start time.
+type Timeline struct {
+	Zero     time.Time
+	Duration time.Duration // grid step, same as segment duration
+
+	slots map[int]timelineSlot
+	next  int
+	mu    sync.Mutex
+}
+
+type timelineSlot struct {
+	sequence int
+	time     time.Time
+}
+
+func NewTimeline(duration time.Duration) *Timeline {
+	return &Timeline{
+		Zero:     time.Now(),
+		Duration: duration,
+		slots:    map[int]timelineSlot{},
+	}
+}
+
+// Slot - grid index for wall time
+func (t *Timeline) Slot(ts time.Time) int {
+	return int(ts.Sub(t.Zero) / t.Duration)
+}
+
+// Segment - sequence number and start time for segment started at ts.
+// prev - sequence of previous segment of the same segmenter, -1 for first one.
+// Sequence numbers of one segmenter are always continuous.
+func (t *Timeline) Segment(ts time.Time, prev int) (sequence int, start time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	slot := t.Slot(ts)
+
+	known, ok := t.slots[slot]
+	switch {
+	case prev >= 0:
+		sequence = prev + 1
+	case ok:
+		sequence = known.sequence
+	default:
+		sequence = t.next
+	}
+
+	if !ok {
+		known = timelineSlot{sequence: sequence, time: ts}
+		t.slots[slot] = known
+
+		// keep only recent slots
+		for i := range t.slots {
+			if i < slot-64 {
+				delete(t.slots, i)
+			}
+		}
+	}
+
+	t.next = max(t.next, sequence+1)
+
+	if known.sequence == sequence {
+		return sequence, known.time
+	}
+	return sequence, ts
+}
@@ -0,0 +1,44 @@
+package core
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTimeline(t *testing.T) {
+	timeline := NewTimeline(time.Second)
+	zero := timeline.Zero
+
+	ts := zero.Add(100 * time.Millisecond)
+	require.Equal(t, 0, timeline.Slot(ts))
+	require.Equal(t, 2, timeline.Slot(zero.Add(2500*time.Millisecond)))
+
+	// first variant
+	seq, start := timeline.Segment(ts, -1)
+	require.Equal(t, 0, seq)
+	require.Equal(t, ts, start)
+
+	seq, start = timeline.Segment(zero.Add(2100*time.Millisecond), 0)
+	require.Equal(t, 1, seq)
+	require.Equal(t, zero.Add(2100*time.Millisecond), start)
+
+	// second variant with a bit later keyframes gets same sequence and time
+	seq, start = timeline.Segment(zero.Add(120*time.Millisecond), -1)
+	require.Equal(t, 0, seq)
+	require.Equal(t, ts, start)
+
+	seq, start = timeline.Segment(zero.Add(2130*time.Millisecond), 0)
+	require.Equal(t, 1, seq)
+	require.Equal(t, zero.Add(2100*time.Millisecond), start)
+
+	// variant started later joins in known slot
+	seq, _ = timeline.Segment(zero.Add(2150*time.Millisecond), -1)
+	require.Equal(t, 1, seq)
+
+	// variant started in unknown slot gets next sequence
+	seq, start = timeline.Segment(zero.Add(5*time.Second), -1)
+	require.Equal(t, 2, seq)
+	require.Equal(t, zero.Add(5*time.Second), start)
+}
@@ -0,0 +1,255 @@
+package dash
+
+import (
+	"encoding/xml"
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MPD - Media Presentation Description, only SegmentTemplate addressing is supported
+// https://dashif.org/docs/DASH-IF-IOP-v4.3.pdf
+type MPD struct {
+	Type                      string   `xml:"type,attr"` // static or dynamic
+	AvailabilityStartTime     string   `xml:"availabilityStartTime,attr"`
+	MediaPresentationDuration string   `xml:"mediaPresentationDuration,attr"`
+	MinimumUpdatePeriod       string   `xml:"minimumUpdatePeriod,attr"`
+	BaseURL                   string   `xml:"BaseURL"`
+	Periods                   []Period `xml:"Period"`
+}
+
+type Period struct {
+	ID             string          `xml:"id,attr"`
+	Start          string          `xml:"start,attr"`
+	BaseURL        string          `xml:"BaseURL"`
+	AdaptationSets []AdaptationSet `xml:"AdaptationSet"`
+}
+
+type AdaptationSet struct {
+	ContentType     string           `xml:"contentType,attr"`
+	MimeType        string           `xml:"mimeType,attr"`
+	BaseURL         string           `xml:"BaseURL"`
+	SegmentTemplate *SegmentTemplate `xml:"SegmentTemplate"`
+	Representations []Representation `xml:"Representation"`
+}
+
+type Representation struct {
+	ID              string           `xml:"id,attr"`
+	Bandwidth       int              `xml:"bandwidth,attr"`
+	Width           int              `xml:"width,attr"`
+	Height          int              `xml:"height,attr"`
+	MimeType        string           `xml:"mimeType,attr"`
+	Codecs          string           `xml:"codecs,attr"`
+	BaseURL         string           `xml:"BaseURL"`
+	SegmentTemplate *SegmentTemplate `xml:"SegmentTemplate"`
+}
+
+type SegmentTemplate struct {
+	Timescale              uint64           `xml:"timescale,attr"`
+	Duration               uint64           `xml:"duration,attr"`
+	StartNumber            *int             `xml:"startNumber,attr"`
+	PresentationTimeOffset uint64           `xml:"presentationTimeOffset,attr"`
+	Initialization         string           `xml:"initialization,attr"`
+	Media                  string           `xml:"media,attr"`
+	SegmentTimeline        *SegmentTimeline `xml:"SegmentTimeline"`
+}
+
+type SegmentTimeline struct {
+	S []struct {
+		T *uint64 `xml:"t,attr"`
+		D uint64  `xml:"d,attr"`
+		R int     `xml:"r,attr"`
+	} `xml:"S"`
+}
+
+// Segment - media segment address
+type Segment struct {
+	Number int
+	Time   uint64
+}
+
+func ParseMPD(b []byte) (*MPD, error) {
+	mpd := &MPD{}
+	if err := xml.Unmarshal(b, mpd); err != nil {
+		return nil, err
+	}
+	if len(mpd.Periods) == 0 {
+		return nil, errors.New("dash: no periods")
+	}
+	return mpd, nil
+}
+
+func (m *MPD) Dynamic() bool {
+	return m.Type == "dynamic"
+}
+
+// Kind - video, audio or other content of adaptation set
+func (a *AdaptationSet) Kind() string {
+	if a.ContentType != "" {
+		return a.ContentType
+	}
+
+	mimeType := a.MimeType
+	if mimeType == "" && len(a.Representations) > 0 {
+		mimeType = a.Representations[0].MimeType
+	}
+
+	kind, _, _ := strings.Cut(mimeType, "/")
+	return kind
+}
+
+// Template - representation template with inherited attributes from adaptation set
+func (a *AdaptationSet) Template(rep *Representation) *SegmentTemplate {
+	if rep.SegmentTemplate == nil {
+		return a.SegmentTemplate
+	}
+	if a.SegmentTemplate == nil {
+		return rep.SegmentTemplate
+	}
+
+	t := *a.SegmentTemplate
+	if rep.SegmentTemplate.Timescale != 0 {
+		t.Timescale = rep.SegmentTemplate.Timescale
+	}
+	if rep.SegmentTemplate.Duration != 0 {
+		t.Duration = rep.SegmentTemplate.Duration
+	}
+	if rep.SegmentTemplate.StartNumber != nil {
+		t.StartNumber = rep.SegmentTemplate.StartNumber
+	}
+	if rep.SegmentTemplate.Initialization != "" {
+		t.Initialization = rep.SegmentTemplate.Initialization
+	}
+	if rep.SegmentTemplate.Media != "" {
+		t.Media = rep.SegmentTemplate.Media
+	}
+	if rep.SegmentTemplate.SegmentTimeline != nil {
+		t.SegmentTimeline = rep.SegmentTemplate.SegmentTimeline
+	}
+	return &t
+}
+
+func (t *SegmentTemplate) startNumber() int {
+	if t.StartNumber != nil {
+		return *t.StartNumber
+	}
+	return 1
+}
+
+func (t *SegmentTemplate) timescale() uint64 {
+	if t.Timescale != 0 {
+		return t.Timescale
+	}
+	return 1
+}
+
+// Segments - all segments from timeline
+func (t *SegmentTemplate) Segments() (segments []Segment) {
+	if t.SegmentTimeline == nil {
+		return nil
+	}
+
+	number := t.startNumber()
+
+	var ts uint64
+	for _, s := range t.SegmentTimeline.S {
+		if s.T != nil {
+			ts = *s.T
+		}
+		// negative repeat (till next S or period end) is not supported
+		for i := 0; i <= s.R; i++ {
+			segments = append(segments, Segment{Number: number, Time: ts})
+			number++
+			ts += s.D
+		}
+	}
+
+	return
+}
+
+// LastNumber - last available segment number for template without timeline
+func (t *SegmentTemplate) LastNumber(start time.Time, now time.Time) int {
+	if t.Duration == 0 {
+		return t.startNumber()
+	}
+
+	duration := time.Duration(t.Duration) * time.Second / time.Duration(t.timescale())
+	n := int(now.Sub(start)/duration) - 1
+	if n < 0 {
+		n = 0
+	}
+	return t.startNumber() + n
+}
+
+// Count - number of segments for static presentation without timeline
+func (t *SegmentTemplate) Count(duration time.Duration) int {
+	if t.Duration == 0 {
+		return 0
+	}
+	segment := time.Duration(t.Duration) * time.Second / time.Duration(t.timescale())
+	return int((duration + segment - 1) / segment)
+}
+
+// SegmentDuration - for template without timeline
+func (t *SegmentTemplate) SegmentDuration() time.Duration {
+	return time.Duration(t.Duration) * time.Second / time.Duration(t.timescale())
+}
+
+var reTemplate = regexp.MustCompile(`\$(RepresentationID|Number|Time|Bandwidth)(%0(\d+)d)?\$`)
+
+// Expand - replace template identifiers: $RepresentationID$, $Number%05d$, etc.
+func Expand(template string, rep *Representation, segment Segment) string {
+	s := reTemplate.ReplaceAllStringFunc(template, func(s string) string {
+		m := reTemplate.FindStringSubmatch(s)
+
+		var value string
+		switch m[1] {
+		case "RepresentationID":
+			return rep.ID
+		case "Number":
+			value = strconv.Itoa(segment.Number)
+		case "Time":
+			value = strconv.FormatUint(segment.Time, 10)
+		case "Bandwidth":
+			value = strconv.Itoa(rep.Bandwidth)
+		}
+
+		if width, _ := strconv.Atoi(m[3]); width > len(value) {
+			value = strings.Repeat("0", width-len(value)) + value
+		}
+
+		return value
+	})
+	return strings.ReplaceAll(s, "$$", "$")
+}
+
+var reDuration = regexp.MustCompile(`^P(?:(\d+)D)?(?:T(?:(\d+)H)?(?:(\d+)M)?(?:([\d.]+)S)?)?$`)
+
+// ParseDuration - ISO 8601 duration, ex. PT1H2M3.5S
+func ParseDuration(s string) time.Duration {
+	m := reDuration.FindStringSubmatch(s)
+	if m == nil {
+		return 0
+	}
+
+	var d time.Duration
+	if m[1] != "" {
+		days, _ := strconv.Atoi(m[1])
+		d += time.Duration(days) * 24 * time.Hour
+	}
+	if m[2] != "" {
+		hours, _ := strconv.Atoi(m[2])
+		d += time.Duration(hours) * time.Hour
+	}
+	if m[3] != "" {
+		minutes, _ := strconv.Atoi(m[3])
+		d += time.Duration(minutes) * time.Minute
+	}
+	if m[4] != "" {
+		seconds, _ := strconv.ParseFloat(m[4], 64)
+		d += time.Duration(seconds * float64(time.Second))
+	}
+	return d
+}
@@ -0,0 +1,68 @@
+package dash
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseMPD(t *testing.T) {
+	mpd, err := ParseMPD([]byte(`<?xml version="1.0" encoding="utf-8"?>
+<MPD xmlns="urn:mpeg:dash:schema:mpd:2011" type="dynamic" availabilityStartTime="2024-01-01T00:00:00Z" minimumUpdatePeriod="PT2S">
+  <Period id="0" start="PT0S">
+    <AdaptationSet contentType="video" mimeType="video/mp4">
+      <SegmentTemplate timescale="90000" initialization="init-$RepresentationID$.mp4" media="chunk-$RepresentationID$-$Number%05d$.m4s" startNumber="10">
+        <SegmentTimeline>
+          <S t="900000" d="180000" r="2"/>
+          <S d="90000"/>
+        </SegmentTimeline>
+      </SegmentTemplate>
+      <Representation id="v0" bandwidth="2000000" width="1920" height="1080"/>
+      <Representation id="v1" bandwidth="800000" width="1280" height="720"/>
+    </AdaptationSet>
+    <AdaptationSet mimeType="audio/mp4">
+      <Representation id="a0" bandwidth="128000">
+        <SegmentTemplate timescale="48000" duration="96000" initialization="$RepresentationID$/init.mp4" media="$RepresentationID$/$Time$.m4s"/>
+      </Representation>
+    </AdaptationSet>
+  </Period>
+</MPD>`))
+	require.Nil(t, err)
+	require.True(t, mpd.Dynamic())
+
+	video := &mpd.Periods[0].AdaptationSets[0]
+	require.Equal(t, "video", video.Kind())
+
+	rep := &video.Representations[1]
+	tpl := video.Template(rep)
+	require.Equal(t, "init-v1.mp4", Expand(tpl.Initialization, rep, Segment{}))
+
+	segments := tpl.Segments()
+	require.Equal(t, []Segment{
+		{Number: 10, Time: 900000},
+		{Number: 11, Time: 1080000},
+		{Number: 12, Time: 1260000},
+		{Number: 13, Time: 1440000},
+	}, segments)
+	require.Equal(t, "chunk-v1-00013.m4s", Expand(tpl.Media, rep, segments[3]))
+
+	audio := &mpd.Periods[0].AdaptationSets[1]
+	require.Equal(t, "audio", audio.Kind())
+
+	rep = &audio.Representations[0]
+	tpl = audio.Template(rep)
+	require.Equal(t, 2*time.Second, tpl.SegmentDuration())
+	require.Equal(t, "a0/96000.m4s", Expand(tpl.Media, rep, Segment{Time: 96000}))
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	require.Equal(t, 5, tpl.LastNumber(start, start.Add(10*time.Second)))
+	require.Equal(t, 3, tpl.Count(5*time.Second))
+}
+
+func TestParseDuration(t *testing.T) {
+	require.Equal(t, 3723500*time.Millisecond, ParseDuration("PT1H2M3.5S"))
+	require.Equal(t, 24*time.Hour+time.Second, ParseDuration("P1DT1S"))
+	require.Equal(t, 2*time.Second, ParseDuration("PT2S"))
+	require.Zero(t, ParseDuration("wrong"))
+}
@@ -0,0 +1,401 @@
+package dash
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/hamza-farouk/go2rtc/pkg/core"
+	"github.com/hamza-farouk/go2rtc/pkg/hls"
+	"github.com/hamza-farouk/go2rtc/pkg/mp4"
+)
+
+type Producer struct {
+	core.Connection
+
+	client *http.Client
+	mpdURL *url.URL
+	mpd    *MPD
+
+	tracks []*track
+
+	done chan struct{}
+	mu   sync.Mutex // protects mpd and track templates
+}
+
+// track - selected representation of one adaptation set
+type track struct {
+	kind    string
+	repID   string
+	baseURL *url.URL
+	rep     *Representation
+	tpl     *SegmentTemplate
+
+	dem *mp4.Demuxer
+
+	// last downloaded segment
+	number int
+	time   uint64
+	start  bool
+}
+
+// OpenURL - MPEG-DASH source with fMP4/CMAF segments and SegmentTemplate addressing.
+// Video representation selected by query (read more in hls.SelectVariant).
+func OpenURL(u *url.URL, body io.ReadCloser, query url.Values) (*Producer, error) {
+	b, err := io.ReadAll(body)
+	if err != nil {
+		return nil, err
+	}
+
+	mpd, err := ParseMPD(b)
+	if err != nil {
+		return nil, err
+	}
+
+	prod := &Producer{
+		Connection: core.Connection{
+			ID:         core.NewID(),
+			FormatName: "dash",
+			Protocol:   "http",
+			RemoteAddr: u.Host,
+			URL:        u.String(),
+		},
+		client: &http.Client{Timeout: core.ConnDialTimeout},
+		mpdURL: u,
+		mpd:    mpd,
+		done:   make(chan struct{}),
+	}
+	prod.Transport = prod
+
+	if err = prod.probe(query); err != nil {
+		return nil, err
+	}
+
+	return prod, nil
+}
+
+func (p *Producer) probe(query url.Values) error {
+	period := p.period(p.mpd)
+	base := resolve(resolve(p.mpdURL, p.mpd.BaseURL), period.BaseURL)
+
+	kinds := map[string]bool{}
+
+	for i := range period.AdaptationSets {
+		set := &period.AdaptationSets[i]
+
+		// one adaptation set for each kind
+		kind := set.Kind()
+		if kind != core.KindVideo && kind != core.KindAudio || kinds[kind] {
+			continue
+		}
+
+		rep := selectRepresentation(set, kind, query)
+		if rep == nil {
+			continue
+		}
+
+		tpl := set.Template(rep)
+		if tpl == nil || tpl.Media == "" {
+			continue // only SegmentTemplate supported
+		}
+
+		t := &track{
+			kind:    kind,
+			repID:   rep.ID,
+			baseURL: resolve(resolve(base, set.BaseURL), rep.BaseURL),
+			rep:     rep,
+			tpl:     tpl,
+			dem:     &mp4.Demuxer{},
+		}
+
+		init, err := p.get(t.baseURL, Expand(tpl.Initialization, rep, Segment{}))
+		if err != nil {
+			return err
+		}
+
+		medias := t.dem.Probe(init)
+		if len(medias) == 0 {
+			continue // unsupported codec
+		}
+
+		p.Medias = append(p.Medias, medias...)
+		p.tracks = append(p.tracks, t)
+		kinds[kind] = true
+	}
+
+	if len(p.tracks) == 0 {
+		return errors.New("dash: unsupported representations")
+	}
+
+	return nil
+}
+
+func (p *Producer) Start() error {
+	var wg sync.WaitGroup
+	errs := make(chan error, len(p.tracks))
+
+	for _, t := range p.tracks {
+		receivers := map[uint32]*core.Receiver{}
+		for _, receiver := range p.Receivers {
+			if trackID := t.dem.GetTrackID(receiver.Codec); trackID != 0 {
+				receivers[trackID] = receiver
+			}
+		}
+
+		if len(receivers) == 0 {
+			continue
+		}
+
+		wg.Add(1)
+		go func(t *track) {
+			errs <- p.run(t, receivers)
+			wg.Done()
+		}(t)
+	}
+
+	var err error
+
+	// first error stops all tracks
+	select {
+	case err = <-errs:
+	case <-p.done:
+		err = io.EOF
+	}
+
+	_ = p.Close()
+	wg.Wait()
+
+	return err
+}
+
+func (p *Producer) Close() error {
+	select {
+	case <-p.done:
+	default:
+		close(p.done)
+	}
+	return nil
+}
+
+func (p *Producer) run(t *track, receivers map[uint32]*core.Receiver) error {
+	for {
+		segments, err := p.next(t)
+		if err != nil {
+			return err
+		}
+
+		p.mu.Lock()
+		media, rep := t.tpl.Media, t.rep
+		p.mu.Unlock()
+
+		for _, segment := range segments {
+			b, err := p.get(t.baseURL, Expand(media, rep, segment))
+			if err != nil {
+				return err
+			}
+
+			// tracks are downloaded in parallel
+			p.mu.Lock()
+			p.Recv += len(b)
+			p.mu.Unlock()
+
+			t.dem.DemuxSegment(b, func(trackID uint32, packet *core.Packet) {
+				if receiver := receivers[trackID]; receiver != nil {
+					receiver.WriteRTP(packet)
+				}
+			})
+		}
+	}
+}
+
+// next - wait and return new segments for track
+func (p *Producer) next(t *track) ([]Segment, error) {
+	for {
+		select {
+		case <-p.done:
+			return nil, io.EOF
+		default:
+		}
+
+		p.mu.Lock()
+		segments := p.segments(t)
+		dynamic := p.mpd.Dynamic()
+		timeline := t.tpl.SegmentTimeline != nil
+		duration := t.tpl.SegmentDuration()
+		update := ParseDuration(p.mpd.MinimumUpdatePeriod)
+		p.mu.Unlock()
+
+		if len(segments) > 0 {
+			last := segments[len(segments)-1]
+			t.number, t.time, t.start = last.Number, last.Time, true
+			return segments, nil
+		}
+
+		if !dynamic {
+			return nil, io.EOF
+		}
+
+		if !timeline {
+			// wait next segment by template duration
+			p.sleep(duration / 2)
+			continue
+		}
+
+		// timeline is updated only with new MPD
+		if update < time.Second {
+			update = time.Second
+		}
+		p.sleep(update)
+
+		if err := p.update(); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// liveSegments - how many segments from live edge download at start
+const liveSegments = 2
+
+func (p *Producer) segments(t *track) (segments []Segment) {
+	if t.tpl.SegmentTimeline != nil {
+		for _, segment := range t.tpl.Segments() {
+			if !t.start || segment.Time > t.time {
+				segments = append(segments, segment)
+			}
+		}
+
+		if !t.start && p.mpd.Dynamic() && len(segments) > liveSegments {
+			segments = segments[len(segments)-liveSegments:]
+		}
+
+		return
+	}
+
+	first := t.tpl.startNumber()
+	last := first + t.tpl.Count(ParseDuration(p.mpd.MediaPresentationDuration)) - 1
+
+	if p.mpd.Dynamic() {
+		ast, err := time.Parse(time.RFC3339, p.mpd.AvailabilityStartTime)
+		if err != nil {
+			return nil
+		}
+		ast = ast.Add(ParseDuration(p.period(p.mpd).Start))
+
+		last = t.tpl.LastNumber(ast, time.Now())
+		first = last - liveSegments + 1
+	}
+
+	if t.start {
+		first = t.number + 1
+	}
+
+	for number := first; number <= last; number++ {
+		segments = append(segments, Segment{Number: number})
+	}
+
+	return
+}
+
+// update - reload MPD and update templates of selected representations
+func (p *Producer) update() error {
+	b, err := p.get(p.mpdURL, "")
+	if err != nil {
+		return err
+	}
+
+	mpd, err := ParseMPD(b)
+	if err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	period := p.period(mpd)
+
+	for _, t := range p.tracks {
+		for i := range period.AdaptationSets {
+			set := &period.AdaptationSets[i]
+			for j := range set.Representations {
+				if rep := &set.Representations[j]; rep.ID == t.repID {
+					if tpl := set.Template(rep); tpl != nil {
+						t.rep, t.tpl = rep, tpl
+					}
+				}
+			}
+		}
+	}
+
+	p.mpd = mpd
+
+	return nil
+}
+
+// period - last period for live and first for static
+func (p *Producer) period(mpd *MPD) *Period {
+	if mpd.Dynamic() {
+		return &mpd.Periods[len(mpd.Periods)-1]
+	}
+	return &mpd.Periods[0]
+}
+
+func (p *Producer) get(base *url.URL, rawURL string) ([]byte, error) {
+	res, err := p.client.Get(resolve(base, rawURL).String())
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, errors.New("dash: " + res.Status)
+	}
+
+	return io.ReadAll(res.Body)
+}
+
+func (p *Producer) sleep(d time.Duration) {
+	select {
+	case <-p.done:
+	case <-time.After(d):
+	}
+}
+
+func selectRepresentation(set *AdaptationSet, kind string, query url.Values) *Representation {
+	if len(set.Representations) == 0 {
+		return nil
+	}
+
+	// audio - first representation
+	if kind != core.KindVideo {
+		return &set.Representations[0]
+	}
+
+	variants := make([]*hls.Variant, len(set.Representations))
+	for i, rep := range set.Representations {
+		variants[i] = &hls.Variant{
+			URI: rep.ID, Bandwidth: rep.Bandwidth, Width: rep.Width, Height: rep.Height,
+		}
+	}
+
+	variant := hls.SelectVariant(variants, query)
+	for i := range set.Representations {
+		if set.Representations[i].ID == variant.URI {
+			return &set.Representations[i]
+		}
+	}
+	return nil
+}
+
+func resolve(base *url.URL, rawURL string) *url.URL {
+	if rawURL == "" {
+		return base
+	}
+	ref, err := url.Parse(rawURL)
+	if err != nil {
+		return base
+	}
+	return base.ResolveReference(ref)
+}
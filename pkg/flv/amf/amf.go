@@ -11,9 +11,10 @@ const (
 	TypeBoolean
 	TypeString
 	TypeObject
-	TypeNull      = 5
-	TypeEcmaArray = 8
-	TypeObjectEnd = 9
+	TypeNull        = 5
+	TypeEcmaArray   = 8
+	TypeObjectEnd   = 9
+	TypeStrictArray = 10
 )
 
 // AMF spec: http://download.macromedia.com/pub/labs/amf/amf0_spec_121207.pdf
@@ -63,6 +64,9 @@ func (a *AMF) ReadItem() (any, error) {
 	case TypeEcmaArray:
 		return a.ReadEcmaArray()
 
+	case TypeStrictArray:
+		return a.ReadStrictArray()
+
 	case TypeNull:
 		return nil, nil
 
@@ -144,6 +148,26 @@ func (a *AMF) ReadEcmaArray() (map[string]any, error) {
 	return a.ReadObject()
 }
 
+func (a *AMF) ReadStrictArray() ([]any, error) {
+	if a.pos+4 > len(a.buf) {
+		return nil, ErrRead
+	}
+
+	n := int(binary.BigEndian.Uint32(a.buf[a.pos:]))
+	a.pos += 4
+
+	var arr []any
+	for i := 0; i < n; i++ {
+		v, err := a.ReadItem()
+		if err != nil {
+			return nil, err
+		}
+		arr = append(arr, v)
+	}
+
+	return arr, nil
+}
+
 func NewWriter() *AMF {
 	return &AMF{}
 }
@@ -188,6 +212,14 @@ func (a *AMF) WriteEcmaArray(obj map[string]any) {
 	a.buf = append(a.buf, 0, 0, TypeObjectEnd)
 }
 
+func (a *AMF) WriteStrictArray(arr []string) {
+	n := len(arr)
+	a.buf = append(a.buf, TypeStrictArray, byte(n>>24), byte(n>>16), byte(n>>8), byte(n))
+	for _, s := range arr {
+		a.WriteString(s)
+	}
+}
+
 func (a *AMF) writeKV(obj map[string]any) {
 	for k, v := range obj {
 		n := len(k)
@@ -207,6 +239,8 @@ func (a *AMF) writeKV(obj map[string]any) {
 			a.WriteNumber(v)
 		case bool:
 			a.WriteBool(v)
+		case []string:
+			a.WriteStrictArray(v)
 		default:
 			panic(v)
 		}
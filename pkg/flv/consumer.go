@@ -1,11 +1,14 @@
 package flv
 
 import (
+	"errors"
 	"io"
 
 	"github.com/hamza-farouk/go2rtc/pkg/aac"
+	"github.com/hamza-farouk/go2rtc/pkg/av1"
 	"github.com/hamza-farouk/go2rtc/pkg/core"
 	"github.com/hamza-farouk/go2rtc/pkg/h264"
+	"github.com/hamza-farouk/go2rtc/pkg/h265"
 	"github.com/pion/rtp"
 )
 
@@ -22,6 +25,8 @@ func NewConsumer() *Consumer {
 			Direction: core.DirectionSendonly,
 			Codecs: []*core.Codec{
 				{Name: core.CodecH264},
+				{Name: core.CodecH265},
+				{Name: core.CodecAV1},
 			},
 		},
 		{
@@ -29,6 +34,7 @@ func NewConsumer() *Consumer {
 			Direction: core.DirectionSendonly,
 			Codecs: []*core.Codec{
 				{Name: core.CodecAAC},
+				{Name: core.CodecOpus},
 			},
 		},
 	}
@@ -48,33 +54,40 @@ func NewConsumer() *Consumer {
 func (c *Consumer) AddTrack(media *core.Media, codec *core.Codec, track *core.Receiver) error {
 	sender := core.NewSender(media, track.Codec)
 
-	switch track.Codec.Name {
-	case core.CodecH264:
-		payload := c.muxer.GetPayloader(track.Codec)
+	payload := c.muxer.GetPayloader(track.Codec)
+	if payload == nil {
+		return errors.New("flv: unsupported codec: " + track.Codec.String())
+	}
 
-		sender.Handler = func(pkt *rtp.Packet) {
-			b := payload(pkt)
+	sender.Handler = func(pkt *rtp.Packet) {
+		if b := payload(pkt); b != nil {
 			if n, err := c.wr.Write(b); err == nil {
 				c.Send += n
 			}
 		}
+	}
 
+	switch track.Codec.Name {
+	case core.CodecH264:
 		if track.Codec.IsRTP() {
 			sender.Handler = h264.RTPDepay(track.Codec, sender.Handler)
 		} else {
 			sender.Handler = h264.RepairAVCC(track.Codec, sender.Handler)
 		}
 
-	case core.CodecAAC:
-		payload := c.muxer.GetPayloader(track.Codec)
+	case core.CodecH265:
+		if track.Codec.IsRTP() {
+			sender.Handler = h265.RTPDepay(track.Codec, sender.Handler)
+		} else {
+			sender.Handler = h265.RepairAVCC(track.Codec, sender.Handler)
+		}
 
-		sender.Handler = func(pkt *rtp.Packet) {
-			b := payload(pkt)
-			if n, err := c.wr.Write(b); err == nil {
-				c.Send += n
-			}
+	case core.CodecAV1:
+		if track.Codec.IsRTP() {
+			sender.Handler = av1.RTPDepay(sender.Handler)
 		}
 
+	case core.CodecAAC:
 		if track.Codec.IsRTP() {
 			sender.Handler = aac.RTPDepay(sender.Handler)
 		}
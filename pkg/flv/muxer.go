@@ -4,9 +4,11 @@ import (
 	"encoding/binary"
 	"encoding/hex"
 
+	"github.com/hamza-farouk/go2rtc/pkg/av1"
 	"github.com/hamza-farouk/go2rtc/pkg/core"
 	"github.com/hamza-farouk/go2rtc/pkg/flv/amf"
 	"github.com/hamza-farouk/go2rtc/pkg/h264"
+	"github.com/hamza-farouk/go2rtc/pkg/h265"
 	"github.com/pion/rtp"
 )
 
@@ -36,12 +38,26 @@ func (m *Muxer) GetInit() []byte {
 			b[4] |= FlagsVideo
 			obj["videocodecid"] = CodecAVC
 
+		case core.CodecH265:
+			b[4] |= FlagsVideo
+			obj["videocodecid"] = binary.BigEndian.Uint32([]byte(FourCCHEVC))
+
+		case core.CodecAV1:
+			b[4] |= FlagsVideo
+			obj["videocodecid"] = binary.BigEndian.Uint32([]byte(FourCCAV1))
+
 		case core.CodecAAC:
 			b[4] |= FlagsAudio
 			obj["audiocodecid"] = CodecAAC
 			obj["audiosamplerate"] = codec.ClockRate
 			obj["audiosamplesize"] = 16
 			obj["stereo"] = codec.Channels == 2
+
+		case core.CodecOpus:
+			b[4] |= FlagsAudio
+			obj["audiocodecid"] = binary.BigEndian.Uint32([]byte(FourCCOpus))
+			obj["audiosamplerate"] = codec.ClockRate
+			obj["stereo"] = codec.Channels == 2
 		}
 	}
 
@@ -65,11 +81,26 @@ func (m *Muxer) GetInit() []byte {
 			video := append(encodeAVData(codec, 0), config...)
 			b = append(b, EncodeTag(TagVideo, 0, video)...)
 
+		case core.CodecH265:
+			// without parameter sets config will be sent with first keyframe
+			vps, sps, pps := h265.GetParameterSet(codec.FmtpLine)
+			if len(vps) == 0 || len(sps) < 6 || len(pps) == 0 {
+				continue
+			}
+
+			config := h265.EncodeConfig(vps, sps, pps)
+			video := append(encodeAVData(codec, PacketTypeSequenceStart), config...)
+			b = append(b, EncodeTag(TagVideo, 0, video)...)
+
 		case core.CodecAAC:
 			s := core.Between(codec.FmtpLine, "config=", ";")
 			config, _ := hex.DecodeString(s)
 			audio := append(encodeAVData(codec, 0), config...)
 			b = append(b, EncodeTag(TagAudio, 0, audio)...)
+
+		case core.CodecOpus:
+			audio := append(encodeAVData(codec, AudioPacketTypeSequenceStart), encodeOpusHead(codec)...)
+			b = append(b, EncodeTag(TagAudio, 0, audio)...)
 		}
 	}
 
@@ -103,6 +134,83 @@ func (m *Muxer) GetPayloader(codec *core.Codec) func(packet *rtp.Packet) []byte
 			return EncodeTag(TagVideo, timeMS, buf)
 		}
 
+	case core.CodecH265:
+		buf := encodeAVData(codec, PacketTypeCodedFrames)
+
+		vps, sps, pps := h265.GetParameterSet(codec.FmtpLine)
+		hasConfig := len(vps) > 0 && len(sps) >= 6 && len(pps) > 0
+
+		return func(packet *rtp.Packet) []byte {
+			var config []byte
+
+			if h265.IsKeyframe(packet.Payload) {
+				if !hasConfig {
+					if config = h265ConfigFromFrame(packet.Payload); config == nil {
+						return nil
+					}
+					hasConfig = true
+				}
+				buf[0] = 0b1000_0000 | 1<<4 | PacketTypeCodedFrames
+			} else {
+				if !hasConfig {
+					return nil // wait first keyframe
+				}
+				buf[0] = 0b1000_0000 | 2<<4 | PacketTypeCodedFrames
+			}
+
+			buf = append(buf[:8], packet.Payload...) // reset buffer to previous place
+
+			if ts0 == 0 {
+				ts0 = packet.Timestamp
+			}
+
+			timeMS := (packet.Timestamp - ts0) / k
+
+			if config != nil {
+				header := append(encodeAVData(codec, PacketTypeSequenceStart), config...)
+				return append(EncodeTag(TagVideo, timeMS, header), EncodeTag(TagVideo, timeMS, buf)...)
+			}
+
+			return EncodeTag(TagVideo, timeMS, buf)
+		}
+
+	case core.CodecAV1:
+		buf := encodeAVData(codec, PacketTypeCodedFrames)
+
+		var hasConfig bool
+
+		return func(packet *rtp.Packet) []byte {
+			var config []byte
+
+			if av1.IsKeyframe(packet.Payload) {
+				if !hasConfig {
+					config = av1.EncodeConfig(av1.GetSequenceHeader(packet.Payload))
+					hasConfig = true
+				}
+				buf[0] = 0b1000_0000 | 1<<4 | PacketTypeCodedFrames
+			} else {
+				if !hasConfig {
+					return nil // wait first keyframe
+				}
+				buf[0] = 0b1000_0000 | 2<<4 | PacketTypeCodedFrames
+			}
+
+			buf = append(buf[:5], packet.Payload...) // reset buffer to previous place
+
+			if ts0 == 0 {
+				ts0 = packet.Timestamp
+			}
+
+			timeMS := (packet.Timestamp - ts0) / k
+
+			if config != nil {
+				header := append(encodeAVData(codec, PacketTypeSequenceStart), config...)
+				return append(EncodeTag(TagVideo, timeMS, header), EncodeTag(TagVideo, timeMS, buf)...)
+			}
+
+			return EncodeTag(TagVideo, timeMS, buf)
+		}
+
 	case core.CodecAAC:
 		buf := encodeAVData(codec, 1)
 
@@ -113,6 +221,20 @@ func (m *Muxer) GetPayloader(codec *core.Codec) func(packet *rtp.Packet) []byte
 				ts0 = packet.Timestamp
 			}
 
+			timeMS := (packet.Timestamp - ts0) / k
+			return EncodeTag(TagAudio, timeMS, buf)
+		}
+
+	case core.CodecOpus:
+		buf := encodeAVData(codec, AudioPacketTypeCodedFrames)
+
+		return func(packet *rtp.Packet) []byte {
+			buf = append(buf[:5], packet.Payload...)
+
+			if ts0 == 0 {
+				ts0 = packet.Timestamp
+			}
+
 			timeMS := (packet.Timestamp - ts0) / k
 			return EncodeTag(TagAudio, timeMS, buf)
 		}
@@ -149,6 +271,20 @@ func encodeAVData(codec *core.Codec, isFrame byte) []byte {
 			0, 0, 0,  // composition time = 0
 		}
 
+	case core.CodecH265:
+		// ex header + keyframe + packet type, fourCC, composition time = 0
+		b := []byte{0b1000_0000 | 1<<4 | isFrame}
+		b = append(b, FourCCHEVC...)
+		if isFrame == PacketTypeCodedFrames {
+			b = append(b, 0, 0, 0)
+		}
+		return b
+
+	case core.CodecAV1:
+		// ex header + keyframe + packet type, fourCC
+		b := []byte{0b1000_0000 | 1<<4 | isFrame}
+		return append(b, FourCCAV1...)
+
 	case core.CodecAAC:
 		var b0 byte = 10 << 4 // AAC
 
@@ -168,7 +304,53 @@ func encodeAVData(codec *core.Codec, isFrame byte) []byte {
 		}
 
 		return []byte{b0, isFrame} // 0 - config, 1 - frame
+
+	case core.CodecOpus:
+		// ex header + packet type, fourCC
+		b := []byte{CodecExHeader<<4 | isFrame}
+		return append(b, FourCCOpus...)
 	}
 
 	return nil
 }
+
+// encodeOpusHead - Opus ID header for Enhanced RTMP sequence start
+// https://datatracker.ietf.org/doc/html/rfc7845#section-5.1
+func encodeOpusHead(codec *core.Codec) []byte {
+	channels := codec.Channels
+	if channels == 0 {
+		channels = 2
+	}
+
+	b := make([]byte, 19)
+	copy(b, "OpusHead")
+	b[8] = 1 // version
+	b[9] = channels
+	binary.LittleEndian.PutUint16(b[10:], 0)     // pre-skip
+	binary.LittleEndian.PutUint32(b[12:], 48000) // input sample rate
+	binary.LittleEndian.PutUint16(b[16:], 0)     // output gain
+	b[18] = 0                                    // channel mapping family
+	return b
+}
+
+// h265ConfigFromFrame - build HEVCDecoderConfigurationRecord from parameter sets inside keyframe
+func h265ConfigFromFrame(avcc []byte) []byte {
+	var vps, sps, pps []byte
+	for _, nalu := range h264.SplitNALU(avcc) {
+		if len(nalu) < 5 {
+			continue
+		}
+		switch h265.NALUType(nalu) {
+		case h265.NALUTypeVPS:
+			vps = nalu[4:]
+		case h265.NALUTypeSPS:
+			sps = nalu[4:]
+		case h265.NALUTypePPS:
+			pps = nalu[4:]
+		}
+	}
+	if len(vps) == 0 || len(sps) < 6 || len(pps) == 0 {
+		return nil
+	}
+	return h265.EncodeConfig(vps, sps, pps)
+}
@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/hamza-farouk/go2rtc/pkg/aac"
+	"github.com/hamza-farouk/go2rtc/pkg/av1"
 	"github.com/hamza-farouk/go2rtc/pkg/core"
 	"github.com/hamza-farouk/go2rtc/pkg/h264"
 	"github.com/hamza-farouk/go2rtc/pkg/h265"
@@ -45,6 +46,16 @@ const (
 
 	CodecAAC = 10
 	CodecAVC = 7
+
+	CodecExHeader = 9 // Enhanced RTMP audio SoundFormat
+)
+
+// Enhanced RTMP FourCC
+// https://veovera.org/docs/enhanced/enhanced-rtmp-v2
+const (
+	FourCCHEVC = "hvc1"
+	FourCCAV1  = "av01"
+	FourCCOpus = "Opus"
 )
 
 const (
@@ -62,6 +73,11 @@ const (
 	PacketTypeMPEG2TSSequenceStart
 )
 
+const (
+	AudioPacketTypeSequenceStart = iota
+	AudioPacketTypeCodedFrames
+)
+
 func (c *Producer) GetTrack(media *core.Media, codec *core.Codec) (*core.Receiver, error) {
 	receiver, _ := c.Connection.GetTrack(media, codec)
 	if media.Kind == core.KindVideo {
@@ -83,12 +99,24 @@ func (c *Producer) Start() error {
 
 		switch pkt.PayloadType {
 		case TagAudio:
-			if c.audio == nil || pkt.Payload[1] == 0 {
+			if c.audio == nil || len(pkt.Payload) < 2 {
 				continue
 			}
 
+			if isAudioExHeader(pkt.Payload) {
+				// sound format 4b, packet type 4b, fourCC 32b
+				if pkt.Payload[0]&0b1111 != AudioPacketTypeCodedFrames || len(pkt.Payload) < 5 {
+					continue
+				}
+				pkt.Payload = pkt.Payload[5:]
+			} else {
+				if pkt.Payload[1] == 0 {
+					continue
+				}
+				pkt.Payload = pkt.Payload[2:]
+			}
+
 			pkt.Timestamp = TimeToRTP(pkt.Timestamp, c.audio.Codec.ClockRate)
-			pkt.Payload = pkt.Payload[2:]
 			c.audio.WriteRTP(pkt)
 
 		case TagVideo:
@@ -99,6 +127,11 @@ func (c *Producer) Start() error {
 			if isExHeader(pkt.Payload) {
 				switch packetType := pkt.Payload[0] & 0b1111; packetType {
 				case PacketTypeCodedFrames:
+					if string(pkt.Payload[1:5]) == FourCCAV1 {
+						// frame type 4b, packet type 4b, fourCC 32b
+						pkt.Payload = pkt.Payload[5:]
+						break
+					}
 					// frame type 4b, packet type 4b, fourCC 32b, composition time 24b
 					pkt.Payload = pkt.Payload[8:]
 				case PacketTypeCodedFramesX:
@@ -165,20 +198,35 @@ func (c *Producer) probe() error {
 
 			_ = pkt.Payload[1] // bounds
 
-			codecID := pkt.Payload[0] >> 4 // SoundFormat
-			_ = pkt.Payload[0] & 0b1100    // SoundRate
-			_ = pkt.Payload[0] & 0b0010    // SoundSize
-			_ = pkt.Payload[0] & 0b0001    // SoundType
+			var codec *core.Codec
 
-			if codecID != CodecAAC {
-				continue
-			}
+			if isAudioExHeader(pkt.Payload) {
+				if len(pkt.Payload) < 5 || string(pkt.Payload[1:5]) != FourCCOpus {
+					continue
+				}
 
-			if pkt.Payload[1] != 0 { // check if header
-				continue
+				if packetType := pkt.Payload[0] & 0b1111; packetType != AudioPacketTypeSequenceStart {
+					continue
+				}
+
+				codec = opusHeadToCodec(pkt.Payload[5:])
+			} else {
+				codecID := pkt.Payload[0] >> 4 // SoundFormat
+				_ = pkt.Payload[0] & 0b1100    // SoundRate
+				_ = pkt.Payload[0] & 0b0010    // SoundSize
+				_ = pkt.Payload[0] & 0b0001    // SoundType
+
+				if codecID != CodecAAC {
+					continue
+				}
+
+				if pkt.Payload[1] != 0 { // check if header
+					continue
+				}
+
+				codec = aac.ConfigToCodec(pkt.Payload[2:])
 			}
 
-			codec := aac.ConfigToCodec(pkt.Payload[2:])
 			media := &core.Media{
 				Kind:      core.KindAudio,
 				Direction: core.DirectionRecvonly,
@@ -195,15 +243,18 @@ func (c *Producer) probe() error {
 			var codec *core.Codec
 
 			if isExHeader(pkt.Payload) {
-				if string(pkt.Payload[1:5]) != "hvc1" {
+				if packetType := pkt.Payload[0] & 0b1111; packetType != PacketTypeSequenceStart {
 					continue
 				}
 
-				if packetType := pkt.Payload[0] & 0b1111; packetType != PacketTypeSequenceStart {
+				switch string(pkt.Payload[1:5]) {
+				case FourCCHEVC:
+					codec = h265.ConfigToCodec(pkt.Payload[5:])
+				case FourCCAV1:
+					codec = av1.ConfigToCodec(pkt.Payload[5:])
+				default:
 					continue
 				}
-
-				codec = h265.ConfigToCodec(pkt.Payload[5:])
 			} else {
 				_ = pkt.Payload[0] >> 4 // FrameType
 
@@ -301,3 +352,22 @@ func TimeToRTP(timeMS uint32, clockRate uint32) uint32 {
 func isExHeader(data []byte) bool {
 	return data[0]&0b1000_0000 != 0
 }
+
+func isAudioExHeader(data []byte) bool {
+	return data[0]>>4 == CodecExHeader
+}
+
+// opusHeadToCodec - parse Opus ID header from Enhanced RTMP sequence start
+// https://datatracker.ietf.org/doc/html/rfc7845#section-5.1
+func opusHeadToCodec(b []byte) *core.Codec {
+	codec := &core.Codec{
+		Name:        core.CodecOpus,
+		ClockRate:   48000, // Opus RTP clock rate is always 48000
+		Channels:    2,
+		PayloadType: core.PayloadTypeRAW,
+	}
+	if len(b) >= 10 && string(b[:8]) == "OpusHead" {
+		codec.Channels = b[9]
+	}
+	return codec
+}
@@ -55,7 +55,7 @@ func NewClient(rawURL string) (*Client, error) {
 	}
 
 	conf := pion.Configuration{}
-	pc, err := rtcAPI.NewPeerConnection(conf)
+	pc, err := webrtc.NewPeerConnection(rtcAPI, conf)
 	if err != nil {
 		return nil, err
 	}
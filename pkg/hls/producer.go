@@ -4,14 +4,34 @@ import (
 	"io"
 	"net/url"
 
+	"github.com/hamza-farouk/go2rtc/pkg/core"
+	"github.com/hamza-farouk/go2rtc/pkg/mp4"
 	"github.com/hamza-farouk/go2rtc/pkg/mpegts"
 )
 
-func OpenURL(u *url.URL, body io.ReadCloser) (*mpegts.Producer, error) {
-	rd, err := NewReader(u, body)
+// OpenURL - HLS source with MPEG-TS or fMP4/CMAF segments, query is used
+// for variant selection from master playlist
+func OpenURL(u *url.URL, body io.ReadCloser, query url.Values) (core.Producer, error) {
+	rd, err := NewReader(u, body, query)
 	if err != nil {
 		return nil, err
 	}
+
+	fmp4, err := rd.(*reader).fmp4()
+	if err != nil {
+		return nil, err
+	}
+
+	if fmp4 {
+		prod, err := mp4.Open(rd)
+		if err != nil {
+			return nil, err
+		}
+		prod.FormatName = "hls/fmp4"
+		prod.RemoteAddr = u.Host
+		return prod, nil
+	}
+
 	prod, err := mpegts.Open(rd)
 	if err != nil {
 		return nil, err
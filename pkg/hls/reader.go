@@ -2,6 +2,7 @@ package hls
 
 import (
 	"bytes"
+	"errors"
 	"io"
 	"net/http"
 	"net/url"
@@ -18,11 +19,15 @@ type reader struct {
 	playlist    []byte
 	lastSegment []byte
 	lastTime    time.Time
+	lastMap     []byte // fMP4 init URI
 
 	buf []byte
 }
 
-func NewReader(u *url.URL, body io.ReadCloser) (io.Reader, error) {
+// NewReader - reader for media playlist segments, variant from master playlist
+// selected by query (read more in SelectVariant). Only muxed variants are supported,
+// separate audio renditions (EXT-X-MEDIA with URI) are not downloaded.
+func NewReader(u *url.URL, body io.ReadCloser, query url.Values) (io.Reader, error) {
 	b, err := io.ReadAll(body)
 	if err != nil {
 		return nil, err
@@ -30,10 +35,8 @@ func NewReader(u *url.URL, body io.ReadCloser) (io.Reader, error) {
 
 	var rawURL string
 
-	re := regexp.MustCompile(`#EXT-X-STREAM-INF.+?\n(\S+)`)
-	m := re.FindSubmatch(b)
-	if m != nil {
-		ref, err := url.Parse(string(m[1]))
+	if variant := SelectVariant(ParseVariants(b), query); variant != nil {
+		ref, err := url.Parse(variant.URI)
 		if err != nil {
 			return nil, err
 		}
@@ -87,27 +90,72 @@ func (r *reader) RoundTrip(_ *http.Request) (*http.Response, error) {
 	return nil, io.EOF
 }
 
+// fmp4 - check if media playlist has fMP4 segments
+func (r *reader) fmp4() (bool, error) {
+	if r.playlist == nil {
+		if err := r.loadPlaylist(); err != nil {
+			return false, err
+		}
+	}
+	return bytes.Contains(r.playlist, []byte("#EXT-X-MAP:")), nil
+}
+
+func (r *reader) loadPlaylist() error {
+	if wait := time.Second - time.Since(r.lastTime); wait > 0 {
+		time.Sleep(wait)
+	}
+
+	res, err := r.client.Do(r.request)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	r.playlist, err = io.ReadAll(res.Body)
+	if err != nil {
+		return err
+	}
+
+	r.lastTime = time.Now()
+
+	//log.Printf("[hls] load playlist\n%s", r.playlist)
+
+	return nil
+}
+
+func (r *reader) get(uri []byte) ([]byte, error) {
+	ref, err := url.Parse(string(uri))
+	if err != nil {
+		return nil, err
+	}
+
+	ref = r.request.URL.ResolveReference(ref)
+	res, err := r.client.Get(ref.String())
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, errors.New("hls: " + res.Status)
+	}
+
+	return io.ReadAll(res.Body)
+}
+
 func (r *reader) getSegment() ([]byte, error) {
 	for i := 0; i < 10; i++ {
 		if r.playlist == nil {
-			if wait := time.Second - time.Since(r.lastTime); wait > 0 {
-				time.Sleep(wait)
-			}
-
 			// 1. Load playlist
-			res, err := r.client.Do(r.request)
-			if err != nil {
-				return nil, err
-			}
-
-			r.playlist, err = io.ReadAll(res.Body)
-			if err != nil {
+			if err := r.loadPlaylist(); err != nil {
 				return nil, err
 			}
+		}
 
-			r.lastTime = time.Now()
-
-			//log.Printf("[hls] load playlist\n%s", r.playlist)
+		// fMP4 init before first segment and after init change
+		if uri := getMap(r.playlist); uri != nil && !bytes.Equal(uri, r.lastMap) {
+			r.lastMap = uri
+			return r.get(uri)
 		}
 
 		for r.playlist != nil {
@@ -125,20 +173,9 @@ func (r *reader) getSegment() ([]byte, error) {
 
 			//log.Printf("[hls] load segment: %s", segment)
 
-			ref, err := url.Parse(string(segment))
-			if err != nil {
-				return nil, err
-			}
-
-			ref = r.request.URL.ResolveReference(ref)
-			res, err := r.client.Get(ref.String())
-			if err != nil {
-				return nil, err
-			}
-
 			r.lastSegment = segment
 
-			return io.ReadAll(res.Body)
+			return r.get(segment)
 		}
 	}
 
@@ -163,3 +200,12 @@ func getSegment(src []byte) []byte {
 
 	return src
 }
+
+// getMap - URI from EXT-X-MAP tag
+func getMap(src []byte) []byte {
+	re := regexp.MustCompile(`#EXT-X-MAP:.*?URI="([^"]+)"`)
+	if m := re.FindSubmatch(src); m != nil {
+		return m[1]
+	}
+	return nil
+}
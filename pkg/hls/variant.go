@@ -0,0 +1,137 @@
+package hls
+
+import (
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Variant - one quality of master playlist (HLS) or representation (DASH)
+type Variant struct {
+	URI       string
+	Bandwidth int
+	Width     int
+	Height    int
+}
+
+// ParseVariants - parse EXT-X-STREAM-INF tags from master playlist
+func ParseVariants(playlist []byte) (variants []*Variant) {
+	re := regexp.MustCompile(`#EXT-X-STREAM-INF:(.*)\r?\n(\S+)`)
+	for _, m := range re.FindAllSubmatch(playlist, -1) {
+		variant := &Variant{URI: string(m[2])}
+
+		attrs := string(m[1])
+		variant.Bandwidth, _ = strconv.Atoi(attribute(attrs, "BANDWIDTH"))
+
+		if s := attribute(attrs, "RESOLUTION"); s != "" {
+			variant.Width, variant.Height = ParseResolution(s)
+		}
+
+		variants = append(variants, variant)
+	}
+	return
+}
+
+// ParseResolution - parse 1920x1080 or 1080 string
+func ParseResolution(s string) (width, height int) {
+	if w, h, ok := strings.Cut(s, "x"); ok {
+		width, _ = strconv.Atoi(w)
+		height, _ = strconv.Atoi(h)
+	} else {
+		height, _ = strconv.Atoi(s)
+	}
+	return
+}
+
+// SelectVariant - select variant by query params:
+//   - bandwidth=max, bandwidth=min or bandwidth=800000 (max, but not higher)
+//   - resolution=max, resolution=min or resolution=720 or resolution=1280x720 (closest height)
+//
+// First variant by default.
+func SelectVariant(variants []*Variant, query url.Values) *Variant {
+	if len(variants) == 0 {
+		return nil
+	}
+
+	selected := variants[0]
+
+	if s := query.Get("bandwidth"); s != "" {
+		limit, _ := strconv.Atoi(s)
+
+		for _, variant := range variants[1:] {
+			switch {
+			case s == "max":
+				if variant.Bandwidth > selected.Bandwidth {
+					selected = variant
+				}
+			case s == "min":
+				if variant.Bandwidth < selected.Bandwidth {
+					selected = variant
+				}
+			case selected.Bandwidth > limit:
+				// current is too high, any lower is better
+				if variant.Bandwidth < selected.Bandwidth {
+					selected = variant
+				}
+			case variant.Bandwidth <= limit && variant.Bandwidth > selected.Bandwidth:
+				selected = variant
+			}
+		}
+	} else if s = query.Get("resolution"); s != "" {
+		_, height := ParseResolution(s)
+
+		for _, variant := range variants[1:] {
+			switch s {
+			case "max":
+				if variant.Height > selected.Height {
+					selected = variant
+				}
+			case "min":
+				if variant.Height < selected.Height {
+					selected = variant
+				}
+			default:
+				if abs(variant.Height-height) < abs(selected.Height-height) {
+					selected = variant
+				}
+			}
+		}
+	}
+
+	return selected
+}
+
+func attribute(attrs, name string) string {
+	for attrs != "" {
+		var attr string
+		attr, attrs = cutAttribute(attrs)
+		if k, v, ok := strings.Cut(attr, "="); ok && k == name {
+			return strings.Trim(v, `"`)
+		}
+	}
+	return ""
+}
+
+// cutAttribute - cut by comma outside of quotes
+func cutAttribute(s string) (attr, tail string) {
+	quoted := false
+	for i, r := range s {
+		switch r {
+		case '"':
+			quoted = !quoted
+		case ',':
+			if !quoted {
+				return s[:i], s[i+1:]
+			}
+		}
+	}
+	return s, ""
+}
+
+func abs(i int) int {
+	if i < 0 {
+		return -i
+	}
+	return i
+}
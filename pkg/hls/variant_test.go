@@ -0,0 +1,40 @@
+package hls
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSelectVariant(t *testing.T) {
+	playlist := []byte(`#EXTM3U
+#EXT-X-STREAM-INF:BANDWIDTH=1280000,RESOLUTION=1280x720,CODECS="avc1.640020,mp4a.40.2"
+720p.m3u8
+#EXT-X-STREAM-INF:BANDWIDTH=2560000,RESOLUTION=1920x1080,CODECS="avc1.640028,mp4a.40.2"
+1080p.m3u8
+#EXT-X-STREAM-INF:BANDWIDTH=640000,RESOLUTION=640x360,CODECS="avc1.64001e,mp4a.40.2"
+360p.m3u8
+`)
+
+	variants := ParseVariants(playlist)
+	require.Len(t, variants, 3)
+	require.Equal(t, &Variant{URI: "1080p.m3u8", Bandwidth: 2560000, Width: 1920, Height: 1080}, variants[1])
+
+	tests := map[string]string{
+		"":                     "720p.m3u8",
+		"bandwidth=max":        "1080p.m3u8",
+		"bandwidth=min":        "360p.m3u8",
+		"bandwidth=2000000":    "720p.m3u8",
+		"bandwidth=100000":     "360p.m3u8",
+		"resolution=max":       "1080p.m3u8",
+		"resolution=min":       "360p.m3u8",
+		"resolution=400":       "360p.m3u8",
+		"resolution=1920x1080": "1080p.m3u8",
+	}
+	for rawQuery, uri := range tests {
+		query, err := url.ParseQuery(rawQuery)
+		require.Nil(t, err)
+		require.Equal(t, uri, SelectVariant(variants, query).URI, rawQuery)
+	}
+}
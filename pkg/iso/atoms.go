@@ -261,10 +261,12 @@ func (m *Movie) WriteAudioTrack(id uint32, codec string, timescale uint32, chann
 }
 
 const (
-	TfhdDefaultSampleDuration = 0x000008
-	TfhdDefaultSampleSize     = 0x000010
-	TfhdDefaultSampleFlags    = 0x000020
-	TfhdDefaultBaseIsMoof     = 0x020000
+	TfhdBaseDataOffset         = 0x000001
+	TfhdSampleDescriptionIndex = 0x000002
+	TfhdDefaultSampleDuration  = 0x000008
+	TfhdDefaultSampleSize      = 0x000010
+	TfhdDefaultSampleFlags     = 0x000020
+	TfhdDefaultBaseIsMoof      = 0x020000
 )
 
 const (
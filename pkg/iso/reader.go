@@ -42,7 +42,9 @@ type AtomMdat struct {
 }
 
 type AtomTfhd struct {
+	Flags          uint32
 	TrackID        uint32
+	BaseDataOffset uint64
 	SampleDuration uint32
 	SampleSize     uint32
 	SampleFlags    uint32
@@ -52,6 +54,7 @@ type AtomTfdt struct {
 }
 
 type AtomTrun struct {
+	SampleCount      uint32
 	DataOffset       uint32
 	FirstSampleFlags uint32
 	SamplesDuration  []uint32
@@ -61,8 +64,12 @@ type AtomTrun struct {
 }
 
 func DecodeAtom(b []byte) (any, error) {
+	if len(b) < 8 {
+		return nil, io.EOF
+	}
+
 	size := binary.BigEndian.Uint32(b)
-	if len(b) < int(size) {
+	if size < 8 || len(b) < int(size) {
 		return nil, io.EOF
 	}
 
@@ -75,19 +82,34 @@ func DecodeAtom(b []byte) (any, error) {
 		return DecodeAtoms(data)
 
 	case MoovTrakTkhd:
-		return &AtomTkhd{TrackID: binary.BigEndian.Uint32(data[1+3+4+4:])}, nil
+		// track ID after creation and modification time
+		if i := timeOffset(data); len(data) >= i+4 {
+			return &AtomTkhd{TrackID: binary.BigEndian.Uint32(data[i:])}, nil
+		}
+		return nil, io.EOF
 
 	case MoovTrakMdiaMdhd:
-		return &AtomMdhd{TimeScale: binary.BigEndian.Uint32(data[1+3+4+4:])}, nil
+		// time scale after creation and modification time
+		if i := timeOffset(data); len(data) >= i+4 {
+			return &AtomMdhd{TimeScale: binary.BigEndian.Uint32(data[i:])}, nil
+		}
+		return nil, io.EOF
 
 	case MoovTrakMdiaMinfStblStsd:
+		if len(data) < 1+3+4 {
+			return nil, io.EOF
+		}
 		// support only 1 codec entry
 		if n := binary.BigEndian.Uint32(data[1+3:]); n == 1 {
 			return DecodeAtom(data[1+3+4:])
 		}
 
-	case "avc1", "hev1":
-		b = data[6+2+2+2+4+4+4+2+2+4+4+4+2+32+2+2:]
+	case "avc1", "hev1", "hvc1":
+		const size = 6 + 2 + 2 + 2 + 4 + 4 + 4 + 2 + 2 + 4 + 4 + 4 + 2 + 32 + 2 + 2
+		if len(data) < size {
+			return nil, io.EOF
+		}
+		b = data[size:]
 		atom, err := DecodeAtom(b)
 		if err != nil {
 			return nil, err
@@ -116,6 +138,9 @@ func DecodeAtom(b []byte) (any, error) {
 		return atom, nil
 
 	case MoofMfhd:
+		if len(data) < 4+4 {
+			return nil, io.EOF
+		}
 		return &AtomMfhd{Sequence: binary.BigEndian.Uint32(data[4:])}, nil
 
 	case MoofTrafTfhd:
@@ -124,9 +149,17 @@ func DecodeAtom(b []byte) (any, error) {
 		flags := rd.ReadUint24()
 
 		atom := &AtomTfhd{
+			Flags:   flags,
 			TrackID: rd.ReadUint32(),
 		}
 
+		if flags&TfhdBaseDataOffset != 0 {
+			atom.BaseDataOffset = uint64(rd.ReadUint32())<<32 | uint64(rd.ReadUint32())
+		}
+		if flags&TfhdSampleDescriptionIndex != 0 {
+			_ = rd.ReadUint32() // skip
+		}
+
 		if flags&TfhdDefaultSampleDuration != 0 {
 			atom.SampleDuration = rd.ReadUint32()
 
@@ -141,7 +174,13 @@ func DecodeAtom(b []byte) (any, error) {
 		return atom, nil
 
 	case MoofTrafTfdt:
-		return &AtomTfdt{DecodeTime: binary.BigEndian.Uint64(data[4:])}, nil
+		switch {
+		case len(data) >= 4+4 && data[0] == 0:
+			return &AtomTfdt{DecodeTime: uint64(binary.BigEndian.Uint32(data[4:]))}, nil
+		case len(data) >= 4+8:
+			return &AtomTfdt{DecodeTime: binary.BigEndian.Uint64(data[4:])}, nil
+		}
+		return nil, io.EOF
 
 	case MoofTrafTrun:
 		rd := bits.NewReader(data)
@@ -149,7 +188,7 @@ func DecodeAtom(b []byte) (any, error) {
 		flags := rd.ReadUint24()
 		samples := rd.ReadUint32()
 
-		atom := &AtomTrun{}
+		atom := &AtomTrun{SampleCount: samples}
 
 		if flags&TrunDataOffset != 0 {
 			atom.DataOffset = rd.ReadUint32()
@@ -158,7 +197,18 @@ func DecodeAtom(b []byte) (any, error) {
 			atom.FirstSampleFlags = rd.ReadUint32()
 		}
 
-		for i := uint32(0); i < samples; i++ {
+		// all sample fields should fit in the atom
+		var size int
+		for _, flag := range []uint32{TrunSampleDuration, TrunSampleSize, TrunSampleFlags, TrunSampleCTS} {
+			if flags&flag != 0 {
+				size += 4
+			}
+		}
+		if rd.EOF || uint64(samples)*uint64(size) > uint64(len(rd.Left())) {
+			return nil, io.EOF
+		}
+
+		for i := uint32(0); i < samples && size > 0; i++ {
 			if flags&TrunSampleDuration != 0 {
 				atom.SamplesDuration = append(atom.SamplesDuration, rd.ReadUint32())
 			}
@@ -182,6 +232,15 @@ func DecodeAtom(b []byte) (any, error) {
 	return &Atom{Name: name, Data: data}, nil
 }
 
+// timeOffset - skip version, flags, creation and modification time of full atom,
+// version 1 has 64-bit times
+func timeOffset(data []byte) int {
+	if len(data) > 0 && data[0] == 1 {
+		return 1 + 3 + 8 + 8
+	}
+	return 1 + 3 + 4 + 4
+}
+
 func DecodeAtoms(b []byte) (atoms []any, err error) {
 	for len(b) > 0 {
 		atom, err := DecodeAtom(b)
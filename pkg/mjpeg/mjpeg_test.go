@@ -1,6 +1,11 @@
 package mjpeg
 
 import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -11,3 +16,53 @@ func TestRFC2435(t *testing.T) {
 	require.Equal(t, byte(9), lqt[0])
 	require.Equal(t, byte(10), cqt[0])
 }
+
+func TestTransform(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 640, 360))
+	draw.Draw(src, src.Rect, &image.Uniform{C: color.White}, image.Point{}, draw.Src)
+
+	buf := bytes.NewBuffer(nil)
+	require.Nil(t, jpeg.Encode(buf, src, nil))
+
+	require.Equal(t, image.Rect(10, 20, 110, 70), ParseRect("10,20,100,50"))
+	require.True(t, ParseRect("10,20").Empty())
+
+	tr := &Transform{
+		Masks:  []image.Rectangle{image.Rect(0, 0, 320, 360)},
+		Crop:   image.Rect(160, 0, 480, 360),
+		Rotate: 90,
+		Width:  180,
+	}
+	require.False(t, tr.IsEmpty())
+	require.True(t, (&Transform{}).IsEmpty())
+
+	b, err := tr.JPEG(buf.Bytes())
+	require.Nil(t, err)
+
+	img, err := jpeg.Decode(bytes.NewReader(b))
+	require.Nil(t, err)
+	require.Equal(t, image.Rect(0, 0, 180, 160), img.Bounds())
+
+	// left half of crop is masked, after clockwise rotation it is on the top
+	y, _, _, _ := img.At(90, 20).RGBA()
+	require.Less(t, y, uint32(0x2000))
+	y, _, _, _ = img.At(90, 140).RGBA()
+	require.Greater(t, y, uint32(0xE000))
+
+	// overlay draws white text on dark background in top-left corner
+	tr = &Transform{Overlays: []Overlay{{Text: "12:00"}}}
+	dst := tr.Image(image.NewRGBA(image.Rect(0, 0, 320, 240))).(*image.RGBA)
+	require.Equal(t, color.RGBA{R: 0xFF, G: 0xFF, B: 0xFF, A: 0xFF}, dst.RGBAAt(4, 2)) // top of "1"
+	require.Equal(t, color.RGBA{A: 0x80}, dst.RGBAAt(3, 2))                            // background
+	require.Equal(t, color.RGBA{}, dst.RGBAAt(300, 200))
+
+	// user params are limited
+	require.Nil(t, (&Transform{Width: MaxSize, Crop: ParseRect("0,0,100,100")}).Validate())
+	require.NotNil(t, (&Transform{Width: MaxSize + 1}).Validate())
+	require.NotNil(t, (&Transform{Height: -1}).Validate())
+	require.NotNil(t, (&Transform{Crop: ParseRect("0,0,1,100000")}).Validate())
+
+	w, h := scaleSize(1, 1000, MaxSize, 0)
+	require.Equal(t, MaxSize, w)
+	require.Equal(t, MaxSize, h)
+}
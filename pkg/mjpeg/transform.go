@@ -0,0 +1,307 @@
+package mjpeg
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Transform - pure Go processing of JPEG snapshots. Steps are applied in
+// order: privacy masks (source coordinates), crop, rotate, scale, overlays.
+type Transform struct {
+	Masks    []image.Rectangle // privacy masks, filled with black
+	Crop     image.Rectangle   // empty - full frame
+	Rotate   int               // clockwise: 90, 180, 270 (or -90)
+	Width    int               // zero - keep aspect ratio with Height
+	Height   int               // zero - keep aspect ratio with Width
+	Overlays []Overlay
+	Quality  int // JPEG quality, zero - default
+}
+
+// Overlay - one line of text on a semi-transparent background
+type Overlay struct {
+	Text     string
+	Position string // top-left (default), top-right, bottom-left, bottom-right
+}
+
+// MaxSize - limit for output and crop width and height, protects from huge
+// memory allocations with user params
+const MaxSize = 8192
+
+// Validate - check user params before processing
+func (t *Transform) Validate() error {
+	if t.Width < 0 || t.Width > MaxSize || t.Height < 0 || t.Height > MaxSize {
+		return errors.New("mjpeg: wrong width or height")
+	}
+	if t.Crop.Dx() > MaxSize || t.Crop.Dy() > MaxSize {
+		return errors.New("mjpeg: wrong crop size")
+	}
+	return nil
+}
+
+func (t *Transform) IsEmpty() bool {
+	return len(t.Masks) == 0 && t.Crop.Empty() && t.Rotate%360 == 0 &&
+		t.Width <= 0 && t.Height <= 0 && len(t.Overlays) == 0 && t.Quality == 0
+}
+
+// JPEG - decode, transform and encode JPEG image
+func (t *Transform) JPEG(b []byte) ([]byte, error) {
+	src, err := jpeg.Decode(bytes.NewReader(FixJPEG(b)))
+	if err != nil {
+		return nil, err
+	}
+
+	var options *jpeg.Options
+	if t.Quality > 0 {
+		options = &jpeg.Options{Quality: t.Quality}
+	}
+
+	buf := bytes.NewBuffer(nil)
+	if err = jpeg.Encode(buf, t.Image(src), options); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (t *Transform) Image(src image.Image) image.Image {
+	img := toRGBA(src)
+
+	for _, mask := range t.Masks {
+		fillRect(img, mask.Add(img.Rect.Min), color.RGBA{A: 0xFF})
+	}
+
+	if crop := t.Crop.Add(img.Rect.Min).Intersect(img.Rect); !crop.Empty() {
+		img = img.SubImage(crop).(*image.RGBA)
+	}
+
+	switch (t.Rotate%360 + 360) % 360 {
+	case 90:
+		img = rotate(img, true)
+	case 180:
+		img = rotate180(img)
+	case 270:
+		img = rotate(img, false)
+	}
+
+	if w, h := scaleSize(img.Rect.Dx(), img.Rect.Dy(), t.Width, t.Height); w != img.Rect.Dx() || h != img.Rect.Dy() {
+		img = scale(img, w, h)
+	}
+
+	for _, overlay := range t.Overlays {
+		drawOverlay(img, overlay)
+	}
+
+	return img
+}
+
+// ParseRect - rectangle from "x,y,width,height" string
+func ParseRect(s string) image.Rectangle {
+	ss := strings.Split(s, ",")
+	if len(ss) != 4 {
+		return image.Rectangle{}
+	}
+
+	var i [4]int
+	for j, v := range ss {
+		i[j], _ = strconv.Atoi(strings.TrimSpace(v))
+	}
+
+	return image.Rect(i[0], i[1], i[0]+i[2], i[1]+i[3])
+}
+
+func toRGBA(src image.Image) *image.RGBA {
+	bounds := src.Bounds()
+	img := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(img, img.Rect, src, bounds.Min, draw.Src)
+	return img
+}
+
+func fillRect(img *image.RGBA, r image.Rectangle, c color.Color) {
+	draw.Draw(img, r.Intersect(img.Rect), &image.Uniform{C: c}, image.Point{}, draw.Over)
+}
+
+// rotate - 90 degrees clockwise or counterclockwise
+func rotate(src *image.RGBA, clockwise bool) *image.RGBA {
+	w, h := src.Rect.Dx(), src.Rect.Dy()
+	dst := image.NewRGBA(image.Rect(0, 0, h, w))
+
+	for y := 0; y < h; y++ {
+		i := src.PixOffset(src.Rect.Min.X, src.Rect.Min.Y+y)
+		for x := 0; x < w; x++ {
+			var dx, dy int
+			if clockwise {
+				dx, dy = h-1-y, x
+			} else {
+				dx, dy = y, w-1-x
+			}
+			j := dst.PixOffset(dx, dy)
+			copy(dst.Pix[j:j+4], src.Pix[i:i+4])
+			i += 4
+		}
+	}
+
+	return dst
+}
+
+func rotate180(src *image.RGBA) *image.RGBA {
+	w, h := src.Rect.Dx(), src.Rect.Dy()
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+
+	for y := 0; y < h; y++ {
+		i := src.PixOffset(src.Rect.Min.X, src.Rect.Min.Y+y)
+		for x := 0; x < w; x++ {
+			j := dst.PixOffset(w-1-x, h-1-y)
+			copy(dst.Pix[j:j+4], src.Pix[i:i+4])
+			i += 4
+		}
+	}
+
+	return dst
+}
+
+// scaleSize - same logic as FFmpeg scale filter with -1 for unknown side
+func scaleSize(srcW, srcH, w, h int) (int, int) {
+	switch {
+	case w > 0 && h > 0:
+		return w, h
+	case w > 0:
+		return w, min(MaxSize, max(1, srcH*w/srcW))
+	case h > 0:
+		return min(MaxSize, max(1, srcW*h/srcH)), h
+	}
+	return srcW, srcH
+}
+
+// scale - bilinear interpolation, with box averaging for big downscale
+func scale(src *image.RGBA, w, h int) *image.RGBA {
+	// first halve image while it is much bigger, bilinear skips pixels otherwise
+	for src.Rect.Dx() >= 2*w && src.Rect.Dy() >= 2*h {
+		src = halve(src)
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+
+	srcW, srcH := src.Rect.Dx(), src.Rect.Dy()
+	fx := float64(srcW) / float64(w)
+	fy := float64(srcH) / float64(h)
+
+	for y := 0; y < h; y++ {
+		sy := (float64(y)+0.5)*fy - 0.5
+		y0 := clamp(int(sy), srcH-1)
+		y1 := clamp(y0+1, srcH-1)
+		wy := sy - float64(y0)
+		if wy < 0 {
+			wy = 0
+		}
+
+		j := dst.PixOffset(0, y)
+
+		for x := 0; x < w; x++ {
+			sx := (float64(x)+0.5)*fx - 0.5
+			x0 := clamp(int(sx), srcW-1)
+			x1 := clamp(x0+1, srcW-1)
+			wx := sx - float64(x0)
+			if wx < 0 {
+				wx = 0
+			}
+
+			p00 := src.PixOffset(src.Rect.Min.X+x0, src.Rect.Min.Y+y0)
+			p01 := src.PixOffset(src.Rect.Min.X+x1, src.Rect.Min.Y+y0)
+			p10 := src.PixOffset(src.Rect.Min.X+x0, src.Rect.Min.Y+y1)
+			p11 := src.PixOffset(src.Rect.Min.X+x1, src.Rect.Min.Y+y1)
+
+			for c := 0; c < 4; c++ {
+				top := float64(src.Pix[p00+c])*(1-wx) + float64(src.Pix[p01+c])*wx
+				bottom := float64(src.Pix[p10+c])*(1-wx) + float64(src.Pix[p11+c])*wx
+				dst.Pix[j+c] = uint8(top*(1-wy) + bottom*wy + 0.5)
+			}
+
+			j += 4
+		}
+	}
+
+	return dst
+}
+
+// halve - downscale image two times with 2x2 box filter
+func halve(src *image.RGBA) *image.RGBA {
+	w, h := src.Rect.Dx()/2, src.Rect.Dy()/2
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+
+	for y := 0; y < h; y++ {
+		j := dst.PixOffset(0, y)
+		for x := 0; x < w; x++ {
+			p0 := src.PixOffset(src.Rect.Min.X+2*x, src.Rect.Min.Y+2*y)
+			p1 := p0 + src.Stride
+			for c := 0; c < 4; c++ {
+				sum := uint16(src.Pix[p0+c]) + uint16(src.Pix[p0+4+c]) + uint16(src.Pix[p1+c]) + uint16(src.Pix[p1+4+c])
+				dst.Pix[j+c] = uint8((sum + 2) / 4)
+			}
+			j += 4
+		}
+	}
+
+	return dst
+}
+
+func clamp(i, n int) int {
+	if i < 0 {
+		return 0
+	}
+	if i > n {
+		return n
+	}
+	return i
+}
+
+// drawOverlay - white text with font size depends on image height
+func drawOverlay(img *image.RGBA, overlay Overlay) {
+	if overlay.Text == "" {
+		return
+	}
+
+	// 7px font for 240p, 21px for 720p, 28px for 1080p
+	size := max(1, img.Rect.Dy()/240)
+
+	textW := (utf8.RuneCountInString(overlay.Text)*(fontWidth+fontSpacing) - fontSpacing) * size
+	textH := fontHeight * size
+	padding := 2 * size
+
+	boxW, boxH := textW+2*padding, textH+2*padding
+
+	var x, y int
+	switch overlay.Position {
+	case "top-right":
+		x = img.Rect.Dx() - boxW
+	case "bottom-left":
+		y = img.Rect.Dy() - boxH
+	case "bottom-right":
+		x, y = img.Rect.Dx()-boxW, img.Rect.Dy()-boxH
+	}
+
+	origin := img.Rect.Min.Add(image.Pt(x, y))
+	fillRect(img, image.Rectangle{Min: origin, Max: origin.Add(image.Pt(boxW, boxH))}, color.RGBA{A: 0x80})
+
+	white := color.RGBA{R: 0xFF, G: 0xFF, B: 0xFF, A: 0xFF}
+	origin = origin.Add(image.Pt(padding, padding))
+
+	for _, r := range overlay.Text {
+		g := glyph(r)
+		for col := 0; col < fontWidth; col++ {
+			for row := 0; row < fontHeight; row++ {
+				if g[col]&(1<<row) == 0 {
+					continue
+				}
+				dot := origin.Add(image.Pt(col*size, row*size))
+				fillRect(img, image.Rectangle{Min: dot, Max: dot.Add(image.Pt(size, size))}, white)
+			}
+		}
+		origin.X += (fontWidth + fontSpacing) * size
+	}
+}
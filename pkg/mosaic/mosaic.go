@@ -0,0 +1,93 @@
+package mosaic
+
+import (
+	"fmt"
+	"image"
+	"strconv"
+	"strings"
+)
+
+// Layout - grid of tiles, filled from left to right and from top to bottom
+type Layout struct {
+	Columns, Rows int
+}
+
+// ParseLayout - grid from "3x3" string, or the smallest common grid for count tiles
+func ParseLayout(s string, count int) Layout {
+	if cols, rows, ok := strings.Cut(s, "x"); ok {
+		l := Layout{Columns: atoi(cols), Rows: atoi(rows)}
+		if l.Columns > 0 && l.Rows > 0 {
+			return l
+		}
+	}
+
+	switch {
+	case count <= 1:
+		return Layout{Columns: 1, Rows: 1}
+	case count == 2:
+		return Layout{Columns: 2, Rows: 1}
+	case count <= 4:
+		return Layout{Columns: 2, Rows: 2}
+	case count <= 6:
+		return Layout{Columns: 3, Rows: 2}
+	case count <= 9:
+		return Layout{Columns: 3, Rows: 3}
+	}
+	return Layout{Columns: 4, Rows: 4}
+}
+
+func (l Layout) Count() int {
+	return l.Columns * l.Rows
+}
+
+// TileSize - even tile size for output size, important for yuv420p
+func (l Layout) TileSize(width, height int) (int, int) {
+	return width / l.Columns &^ 1, height / l.Rows &^ 1
+}
+
+// Tile - position of tile with index i inside output image
+func (l Layout) Tile(i, width, height int) image.Rectangle {
+	w, h := l.TileSize(width, height)
+	x, y := i%l.Columns*w, i/l.Columns*h
+	return image.Rect(x, y, x+w, y+h)
+}
+
+// FilterComplex - FFmpeg filter graph for Layout.Count() video inputs with `[v]` output.
+// Each input is scaled with keeping aspect ratio and padded with black to tile size.
+func FilterComplex(l Layout, width, height, fps int) string {
+	w, h := l.TileSize(width, height)
+	n := l.Count()
+
+	sb := &strings.Builder{}
+
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(sb, "[%d:v]fps=%d,scale=%d:%d:force_original_aspect_ratio=decrease,"+
+			"pad=%d:%d:(ow-iw)/2:(oh-ih)/2,setsar=1,setpts=PTS-STARTPTS[v%d];", i, fps, w, h, w, h, i)
+	}
+
+	if n == 1 {
+		sb.WriteString("[v0]null[v]")
+		return sb.String()
+	}
+
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(sb, "[v%d]", i)
+	}
+
+	fmt.Fprintf(sb, "xstack=inputs=%d:layout=", n)
+	for i := 0; i < n; i++ {
+		if i > 0 {
+			sb.WriteByte('|')
+		}
+		r := l.Tile(i, width, height)
+		fmt.Fprintf(sb, "%d_%d", r.Min.X, r.Min.Y)
+	}
+	sb.WriteString("[v]")
+
+	return sb.String()
+}
+
+func atoi(s string) int {
+	i, _ := strconv.Atoi(strings.TrimSpace(s))
+	return i
+}
@@ -0,0 +1,42 @@
+package mosaic
+
+import (
+	"image"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseLayout(t *testing.T) {
+	require.Equal(t, Layout{Columns: 1, Rows: 1}, ParseLayout("", 1))
+	require.Equal(t, Layout{Columns: 2, Rows: 1}, ParseLayout("", 2))
+	require.Equal(t, Layout{Columns: 2, Rows: 2}, ParseLayout("", 3))
+	require.Equal(t, Layout{Columns: 3, Rows: 3}, ParseLayout("", 9))
+	require.Equal(t, Layout{Columns: 4, Rows: 4}, ParseLayout("", 10))
+	require.Equal(t, Layout{Columns: 4, Rows: 1}, ParseLayout("4x1", 2))
+	require.Equal(t, Layout{Columns: 2, Rows: 2}, ParseLayout("0x2", 4))
+}
+
+func TestTile(t *testing.T) {
+	l := Layout{Columns: 3, Rows: 3}
+	require.Equal(t, image.Rect(0, 0, 640, 360), l.Tile(0, 1920, 1080))
+	require.Equal(t, image.Rect(640, 360, 1280, 720), l.Tile(4, 1920, 1080))
+	require.Equal(t, image.Rect(1280, 720, 1920, 1080), l.Tile(8, 1920, 1080))
+
+	// odd sizes rounded to even
+	w, h := l.TileSize(1000, 1000)
+	require.Equal(t, 332, w)
+	require.Equal(t, 332, h)
+}
+
+func TestFilterComplex(t *testing.T) {
+	s := FilterComplex(Layout{Columns: 2, Rows: 1}, 1280, 360, 10)
+	require.Equal(t,
+		"[0:v]fps=10,scale=640:360:force_original_aspect_ratio=decrease,pad=640:360:(ow-iw)/2:(oh-ih)/2,setsar=1,setpts=PTS-STARTPTS[v0];"+
+			"[1:v]fps=10,scale=640:360:force_original_aspect_ratio=decrease,pad=640:360:(ow-iw)/2:(oh-ih)/2,setsar=1,setpts=PTS-STARTPTS[v1];"+
+			"[v0][v1]xstack=inputs=2:layout=0_0|640_0[v]", s,
+	)
+
+	s = FilterComplex(Layout{Columns: 1, Rows: 1}, 640, 360, 10)
+	require.Contains(t, s, "[v0]null[v]")
+}
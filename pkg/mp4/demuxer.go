@@ -1,16 +1,19 @@
 package mp4
 
 import (
+	"encoding/binary"
+
 	"github.com/hamza-farouk/go2rtc/pkg/aac"
 	"github.com/hamza-farouk/go2rtc/pkg/core"
 	"github.com/hamza-farouk/go2rtc/pkg/h264"
+	"github.com/hamza-farouk/go2rtc/pkg/h265"
 	"github.com/hamza-farouk/go2rtc/pkg/iso"
 	"github.com/pion/rtp"
 )
 
 type Demuxer struct {
 	codecs     map[uint32]*core.Codec
-	timeScales map[uint32]float32
+	timeScales map[uint32]uint32
 }
 
 func (d *Demuxer) Probe(init []byte) (medias []*core.Media) {
@@ -18,7 +21,7 @@ func (d *Demuxer) Probe(init []byte) (medias []*core.Media) {
 
 	if d.codecs == nil {
 		d.codecs = make(map[uint32]*core.Codec)
-		d.timeScales = make(map[uint32]float32)
+		d.timeScales = make(map[uint32]uint32)
 	}
 
 	atoms, _ := iso.DecodeAtoms(init)
@@ -34,6 +37,8 @@ func (d *Demuxer) Probe(init []byte) (medias []*core.Media) {
 			switch atom.Name {
 			case "avc1":
 				codec = h264.ConfigToCodec(atom.Config)
+			case "hev1", "hvc1":
+				codec = h265.ConfigToCodec(atom.Config)
 			}
 		case *iso.AtomAudio:
 			switch atom.Name {
@@ -44,7 +49,7 @@ func (d *Demuxer) Probe(init []byte) (medias []*core.Media) {
 
 		if codec != nil {
 			d.codecs[trackID] = codec
-			d.timeScales[trackID] = float32(codec.ClockRate) / float32(timeScale)
+			d.timeScales[trackID] = timeScale
 
 			medias = append(medias, &core.Media{
 				Kind:      codec.Kind(),
@@ -89,11 +94,12 @@ func (d *Demuxer) Demux(data2 []byte) (trackID uint32, packets []*core.Packet) {
 		}
 	}
 
-	timeScale := d.timeScales[trackID]
-	if timeScale == 0 {
+	if d.timeScales[trackID] == 0 {
 		return 0, nil
 	}
 
+	timeScale := float32(d.codecs[trackID].ClockRate) / float32(d.timeScales[trackID])
+
 	n := len(trun.SamplesDuration)
 	packets = make([]*core.Packet, n)
 
@@ -114,3 +120,99 @@ func (d *Demuxer) Demux(data2 []byte) (trackID uint32, packets []*core.Packet) {
 
 	return
 }
+
+// DemuxSegment - demux CMAF segment with one or more moof+mdat fragments.
+// Each fragment may contain samples of multiple tracks.
+func (d *Demuxer) DemuxSegment(b []byte, handler func(trackID uint32, packet *core.Packet)) {
+	for start := 0; len(b) >= 8; {
+		size := int(binary.BigEndian.Uint32(b))
+		if size < 8 || size > len(b) {
+			return
+		}
+
+		if string(b[4:8]) == iso.Moof {
+			d.demuxFragment(b, size, start, handler)
+		}
+
+		b = b[size:]
+		start += size
+	}
+}
+
+// demuxFragment - b starts from moof, data offsets are relative to moof,
+// or to base data offset from tfhd (from start of the segment)
+func (d *Demuxer) demuxFragment(b []byte, moofSize, moofStart int, handler func(trackID uint32, packet *core.Packet)) {
+	atoms, err := iso.DecodeAtoms(b[8:moofSize])
+	if err != nil {
+		return
+	}
+
+	var tfhd *iso.AtomTfhd
+	var dts uint64
+
+	// data without offset starts after mdat header
+	offset := moofSize + 8
+	base := 0
+
+	for _, atom := range atoms {
+		switch atom := atom.(type) {
+		case *iso.AtomTfhd:
+			tfhd = atom
+			if tfhd.Flags&iso.TfhdBaseDataOffset != 0 {
+				base = int(tfhd.BaseDataOffset) - moofStart
+				offset = base
+			} else {
+				base = 0
+			}
+		case *iso.AtomTfdt:
+			dts = atom.DecodeTime
+		case *iso.AtomTrun:
+			if tfhd == nil {
+				continue
+			}
+
+			codec := d.codecs[tfhd.TrackID]
+			timeScale := uint64(d.timeScales[tfhd.TrackID])
+			if codec == nil || timeScale == 0 {
+				continue
+			}
+
+			if atom.DataOffset != 0 {
+				offset = base + int(int32(atom.DataOffset)) // signed value
+			}
+
+			// protect from broken sample count, each sample is at least one byte
+			if offset < 0 || int(atom.SampleCount) > len(b)-offset {
+				return
+			}
+
+			for i := 0; i < int(atom.SampleCount); i++ {
+				size := int(tfhd.SampleSize)
+				if i < len(atom.SamplesSize) {
+					size = int(atom.SamplesSize[i])
+				}
+
+				duration := tfhd.SampleDuration
+				if i < len(atom.SamplesDuration) {
+					duration = atom.SamplesDuration[i]
+				}
+
+				if offset+size > len(b) {
+					return
+				}
+
+				// convert in two steps to avoid overflow on big decode time
+				clockRate := uint64(codec.ClockRate)
+				ts := dts/timeScale*clockRate + dts%timeScale*clockRate/timeScale
+
+				handler(tfhd.TrackID, &rtp.Packet{
+					Header:  rtp.Header{Timestamp: uint32(ts)},
+					Payload: b[offset : offset+size],
+				})
+
+				offset += size
+				dts += uint64(duration)
+			}
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package mp4
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/hamza-farouk/go2rtc/pkg/core"
+	"github.com/pion/rtp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDemuxSegment(t *testing.T) {
+	muxer := &Muxer{}
+	muxer.AddTrack(&core.Codec{
+		Name:      core.CodecH264,
+		ClockRate: 90000,
+		FmtpLine:  "profile-level-id=640028;sprop-parameter-sets=Z2QAKKwrQPAET8s=,aO4xshs=",
+	})
+
+	init, err := muxer.GetInit()
+	require.Nil(t, err)
+
+	dem := &Demuxer{}
+	medias := dem.Probe(init)
+	require.Len(t, medias, 1)
+	require.Equal(t, core.CodecH264, medias[0].Codecs[0].Name)
+	require.Equal(t, uint32(1), dem.GetTrackID(medias[0].Codecs[0]))
+
+	// segment with several fragments, like HLS fMP4 or DASH segment
+	var segment []byte
+	for i := 0; i < 3; i++ {
+		packet := &rtp.Packet{
+			Header:  rtp.Header{Timestamp: uint32(3000 * (i + 1))},
+			Payload: []byte{0, 0, 0, 2, 0x65, byte(i)},
+		}
+		segment = append(segment, muxer.GetPayload(0, packet)...)
+	}
+
+	var packets []*core.Packet
+	dem.DemuxSegment(segment, func(trackID uint32, packet *core.Packet) {
+		require.Equal(t, uint32(1), trackID)
+		packets = append(packets, packet)
+	})
+
+	require.Len(t, packets, 3)
+	for i, packet := range packets {
+		require.Equal(t, []byte{0, 0, 0, 2, 0x65, byte(i)}, packet.Payload)
+	}
+	require.Equal(t, uint32(3000), packets[2].Timestamp-packets[1].Timestamp)
+}
+
+func TestDemuxTruncated(t *testing.T) {
+	muxer := &Muxer{}
+	muxer.AddTrack(&core.Codec{
+		Name:      core.CodecH264,
+		ClockRate: 90000,
+		FmtpLine:  "profile-level-id=640028;sprop-parameter-sets=Z2QAKKwrQPAET8s=,aO4xshs=",
+	})
+
+	init, err := muxer.GetInit()
+	require.Nil(t, err)
+
+	segment := muxer.GetPayload(0, &rtp.Packet{Payload: []byte{0, 0, 0, 2, 0x65, 0}})
+
+	// broken data from network should not panic
+	for i := 0; i < len(init); i++ {
+		_ = (&Demuxer{}).Probe(init[:i])
+	}
+
+	dem := &Demuxer{}
+	dem.Probe(init)
+	for i := 0; i < len(segment); i++ {
+		dem.DemuxSegment(segment[:i], func(uint32, *core.Packet) {})
+	}
+
+	// huge sample count in trun
+	b := append([]byte{}, segment...)
+	i := bytes.Index(b, []byte("trun")) + 4 + 4
+	b[i], b[i+1], b[i+2], b[i+3] = 0xFF, 0xFF, 0xFF, 0xFF
+	dem.DemuxSegment(b, func(uint32, *core.Packet) { t.Fail() })
+}
+
+func TestDemuxBaseDataOffset(t *testing.T) {
+	muxer := &Muxer{}
+	muxer.AddTrack(&core.Codec{
+		Name:      core.CodecH264,
+		ClockRate: 90000,
+		FmtpLine:  "profile-level-id=640028;sprop-parameter-sets=Z2QAKKwrQPAET8s=,aO4xshs=",
+	})
+
+	init, err := muxer.GetInit()
+	require.Nil(t, err)
+
+	dem := &Demuxer{}
+	dem.Probe(init)
+
+	box := func(name string, payload ...[]byte) []byte {
+		b := bytes.Join(payload, nil)
+		return append(binary.BigEndian.AppendUint32(nil, uint32(8+len(b))), append([]byte(name), b...)...)
+	}
+	u32 := func(v uint32) []byte { return binary.BigEndian.AppendUint32(nil, v) }
+
+	styp := box("styp", []byte("msdh"), u32(0))
+	sample := []byte{0, 0, 0, 2, 0x65, 7}
+
+	moof := func(base uint64, dataOffset uint32) []byte {
+		return box("moof",
+			box("mfhd", u32(0), u32(1)),
+			box("traf",
+				// base data offset, default duration and size
+				box("tfhd", u32(0x000001|0x000008|0x000010), u32(1),
+					binary.BigEndian.AppendUint64(nil, base), u32(3000), u32(uint32(len(sample)))),
+				box("tfdt", u32(0), u32(9000)),
+				box("trun", u32(0x000001), u32(1), u32(dataOffset)),
+			),
+		)
+	}
+
+	// base data offset points to mdat from start of the segment with styp
+	moofSize := len(moof(0, 0))
+	segment := append(styp, moof(uint64(len(styp)+moofSize), 8)...)
+	segment = append(segment, box("mdat", sample)...)
+
+	var packets []*core.Packet
+	dem.DemuxSegment(segment, func(trackID uint32, packet *core.Packet) {
+		packets = append(packets, packet)
+	})
+
+	require.Len(t, packets, 1)
+	require.Equal(t, sample, packets[0].Payload)
+	require.Equal(t, uint32(9000), packets[0].Timestamp)
+}
@@ -0,0 +1,113 @@
+package mp4
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+
+	"github.com/hamza-farouk/go2rtc/pkg/core"
+	"github.com/hamza-farouk/go2rtc/pkg/iso"
+)
+
+// Producer - fragmented MP4 stream: init (ftyp, moov) and fragments (moof, mdat).
+// Useful for HLS fMP4 and MPEG-DASH segments.
+type Producer struct {
+	core.Connection
+	rd  io.Reader
+	dem *Demuxer
+}
+
+func Open(rd io.Reader) (*Producer, error) {
+	prod := &Producer{
+		Connection: core.Connection{
+			ID:         core.NewID(),
+			FormatName: "fmp4",
+			Transport:  rd,
+		},
+		rd:  rd,
+		dem: &Demuxer{},
+	}
+	if err := prod.probe(); err != nil {
+		return nil, err
+	}
+	return prod, nil
+}
+
+func (p *Producer) Start() error {
+	receivers := make(map[uint32]*core.Receiver)
+	for _, receiver := range p.Receivers {
+		trackID := p.dem.GetTrackID(receiver.Codec)
+		receivers[trackID] = receiver
+	}
+
+	var moof []byte
+
+	for {
+		atom, err := ReadAtom(p.rd)
+		if err != nil {
+			return err
+		}
+
+		p.Recv += len(atom)
+
+		switch string(atom[4:8]) {
+		case iso.Moof:
+			moof = atom
+		case iso.Mdat:
+			if moof == nil {
+				continue
+			}
+			p.dem.DemuxSegment(append(moof, atom...), func(trackID uint32, packet *core.Packet) {
+				if receiver := receivers[trackID]; receiver != nil {
+					receiver.WriteRTP(packet)
+				}
+			})
+			moof = nil
+		}
+	}
+}
+
+func (p *Producer) probe() error {
+	for {
+		atom, err := ReadAtom(p.rd)
+		if err != nil {
+			return err
+		}
+
+		if string(atom[4:8]) != iso.Moov {
+			continue
+		}
+
+		p.Medias = p.dem.Probe(atom)
+		if len(p.Medias) == 0 {
+			return errors.New("mp4: unsupported codecs")
+		}
+
+		return nil
+	}
+}
+
+// maxAtomSize - protect from allocating memory for broken or malicious size,
+// enough for mdat of several seconds of 4K video
+const maxAtomSize = 128 << 20
+
+// ReadAtom - read full top level atom with header
+func ReadAtom(rd io.Reader) ([]byte, error) {
+	header := make([]byte, 8)
+	if _, err := io.ReadFull(rd, header); err != nil {
+		return nil, err
+	}
+
+	size := binary.BigEndian.Uint32(header)
+	if size < 8 || size > maxAtomSize {
+		return nil, errors.New("mp4: unsupported atom size")
+	}
+
+	b := make([]byte, size)
+	copy(b, header)
+	if _, err := io.ReadFull(rd, b[8:]); err != nil {
+		return nil, err
+	}
+
+	return b, nil
+}
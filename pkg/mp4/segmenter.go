@@ -0,0 +1,381 @@
+package mp4
+
+import (
+	"encoding/binary"
+	"sync"
+	"time"
+
+	"github.com/hamza-farouk/go2rtc/pkg/core"
+	"github.com/hamza-farouk/go2rtc/pkg/iso"
+)
+
+// Segmenter - cut fMP4 stream (init + moof/mdat fragments from Consumer) to
+// segments and parts. Segments always start with keyframe of main track
+// (video or first track). Durations are calculated from fragments decode time.
+type Segmenter struct {
+	SegmentDuration time.Duration // minimum segment duration, real depends on keyframes
+	PartDuration    time.Duration // maximum part duration
+	WindowSize      int           // number of complete segments to keep
+
+	// Timeline - optional shared grid for aligned segments of ABR variants
+	Timeline *core.Timeline
+
+	init []byte
+
+	timescales map[uint32]uint32
+	main       uint32 // main track ID
+
+	segments []*Segment
+	sequence int
+	complete int // total complete segments
+
+	part      *Part
+	partStart uint64
+	segStart  uint64
+	firstDTS  uint64
+	firstTime time.Time
+
+	notify chan struct{}
+	closed bool
+	mu     sync.Mutex
+}
+
+type Segment struct {
+	Sequence int           `json:"sequence"`
+	Start    time.Duration `json:"start"` // from first segment
+	DTS      time.Duration `json:"dts"`   // decode time of segment start from tfdt
+	Duration time.Duration `json:"duration"`
+	Time     time.Time     `json:"time"` // wall clock of segment start
+	Size     int           `json:"size"`
+	Parts    []*Part       `json:"-"` // only complete parts
+	Complete bool          `json:"complete"`
+
+	data []byte
+}
+
+type Part struct {
+	Data        []byte        `json:"-"`
+	Duration    time.Duration `json:"duration"`
+	Independent bool          `json:"independent"` // starts with keyframe
+}
+
+func NewSegmenter() *Segmenter {
+	return &Segmenter{
+		SegmentDuration: time.Second,
+		PartDuration:    500 * time.Millisecond,
+		WindowSize:      5,
+		notify:          make(chan struct{}),
+	}
+}
+
+// Write - first write should be init, next writes - one or multiple fragments
+func (s *Segmenter) Write(b []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.init == nil {
+		s.init = b
+		s.parseInit(b)
+		return len(b), nil
+	}
+
+	n := len(b)
+
+	// one write may contain multiple moof + mdat pairs
+	for len(b) >= 8 {
+		size := int(binary.BigEndian.Uint32(b))
+		if size < 8 || size+8 > len(b) {
+			break
+		}
+		if size += int(binary.BigEndian.Uint32(b[size:])); size > len(b) {
+			break
+		}
+
+		s.writeFragment(b[:size])
+		b = b[size:]
+	}
+
+	s.broadcast()
+
+	return n, nil
+}
+
+func (s *Segmenter) parseInit(b []byte) {
+	atoms, err := iso.DecodeAtoms(b)
+	if err != nil {
+		return
+	}
+
+	s.timescales = map[uint32]uint32{}
+
+	var trackID uint32
+	var video bool
+
+	for _, atom := range atoms {
+		switch atom := atom.(type) {
+		case *iso.AtomTkhd:
+			trackID = atom.TrackID
+			if s.main == 0 {
+				s.main = trackID
+			}
+		case *iso.AtomMdhd:
+			s.timescales[trackID] = atom.TimeScale
+		case *iso.AtomVideo:
+			if !video {
+				s.main = trackID
+				video = true
+			}
+		}
+	}
+}
+
+func (s *Segmenter) writeFragment(b []byte) {
+	atoms, err := iso.DecodeAtoms(b)
+	if err != nil {
+		return
+	}
+
+	var tfhd *iso.AtomTfhd
+	var dts uint64
+
+	for _, atom := range atoms {
+		switch atom := atom.(type) {
+		case *iso.AtomTfhd:
+			tfhd = atom
+		case *iso.AtomTfdt:
+			dts = atom.DecodeTime
+		}
+	}
+
+	if tfhd == nil {
+		return
+	}
+
+	if tfhd.TrackID != s.main {
+		// other tracks are skipped until first keyframe of main track
+		if s.part != nil {
+			s.part.Data = append(s.part.Data, b...)
+		}
+		return
+	}
+
+	keyframe := tfhd.SampleFlags&iso.SampleVideoNonIFrame != iso.SampleVideoNonIFrame
+
+	if s.part == nil {
+		if !keyframe {
+			return
+		}
+		s.firstDTS = dts
+		s.firstTime = time.Now()
+		s.startSegment(dts)
+	} else if keyframe && s.nextSegment(dts) {
+		s.closePart(dts)
+		s.closeSegment(dts)
+		s.startSegment(dts)
+	} else if len(s.part.Data) > 0 && s.duration(dts-s.partStart+uint64(tfhd.SampleDuration)) > s.PartDuration {
+		s.closePart(dts)
+	}
+
+	if len(s.part.Data) == 0 {
+		s.partStart = dts
+		s.part.Independent = keyframe
+	}
+
+	s.part.Data = append(s.part.Data, b...)
+}
+
+// nextSegment - segment duration reached or next slot of shared timeline started
+func (s *Segmenter) nextSegment(dts uint64) bool {
+	if s.Timeline != nil {
+		return s.Timeline.Slot(s.wallTime(dts)) > s.Timeline.Slot(s.wallTime(s.segStart))
+	}
+	return s.duration(dts-s.segStart) >= s.SegmentDuration
+}
+
+func (s *Segmenter) wallTime(dts uint64) time.Time {
+	return s.firstTime.Add(s.duration(dts - s.firstDTS))
+}
+
+func (s *Segmenter) startSegment(dts uint64) {
+	s.segStart = dts
+	s.partStart = dts
+	s.part = &Part{}
+
+	segment := &Segment{
+		Sequence: s.sequence,
+		Start:    s.duration(dts - s.firstDTS),
+		DTS:      s.duration(dts),
+		Time:     s.wallTime(dts),
+	}
+
+	if s.Timeline != nil {
+		prev := -1
+		if len(s.segments) > 0 {
+			prev = s.sequence - 1
+		}
+		segment.Sequence, segment.Time = s.Timeline.Segment(segment.Time, prev)
+	}
+
+	s.segments = append(s.segments, segment)
+	s.sequence = segment.Sequence + 1
+
+	// window of complete segments + current segment
+	if n := len(s.segments) - s.WindowSize - 1; n > 0 {
+		s.segments = s.segments[n:]
+	}
+}
+
+func (s *Segmenter) closePart(dts uint64) {
+	s.part.Duration = s.duration(dts - s.partStart)
+
+	segment := s.segments[len(s.segments)-1]
+	segment.Parts = append(segment.Parts, s.part)
+
+	s.part = &Part{}
+	s.partStart = dts
+}
+
+func (s *Segmenter) closeSegment(dts uint64) {
+	segment := s.segments[len(s.segments)-1]
+	segment.Duration = s.duration(dts - s.segStart)
+	segment.Complete = true
+
+	for _, part := range segment.Parts {
+		segment.data = append(segment.data, part.Data...)
+	}
+	segment.Size = len(segment.data)
+
+	s.complete++
+}
+
+func (s *Segmenter) duration(ticks uint64) time.Duration {
+	timescale := s.timescales[s.main]
+	if timescale == 0 {
+		return 0
+	}
+	// in two steps to avoid overflow on big decode time
+	return time.Duration(ticks/uint64(timescale))*time.Second +
+		time.Duration(ticks%uint64(timescale))*time.Second/time.Duration(timescale)
+}
+
+func (s *Segmenter) broadcast() {
+	close(s.notify)
+	s.notify = make(chan struct{})
+}
+
+// Close - wake up all waiters, segmenter won't get new data
+func (s *Segmenter) Close() {
+	s.mu.Lock()
+	if !s.closed {
+		s.closed = true
+		s.broadcast()
+	}
+	s.mu.Unlock()
+}
+
+// Segments - snapshot of segments window, last segment may be incomplete
+func (s *Segmenter) Segments() []Segment {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	segments := make([]Segment, len(s.segments))
+	for i, segment := range s.segments {
+		segments[i] = *segment
+		segments[i].Parts = append([]*Part(nil), segment.Parts...)
+		segments[i].data = nil
+	}
+	return segments
+}
+
+// Init - return init when first part is ready
+func (s *Segmenter) Init(timeout time.Duration) (init []byte) {
+	s.wait(timeout, func() bool {
+		if len(s.segments) > 0 && len(s.segments[0].Parts) > 0 {
+			init = s.init
+			return true
+		}
+		return false
+	})
+	return
+}
+
+// Segment - wait and return complete segment
+func (s *Segmenter) Segment(sequence int, timeout time.Duration) (data []byte) {
+	s.wait(timeout, func() bool {
+		if segment := s.get(sequence); segment != nil && segment.Complete {
+			data = segment.data
+			return true
+		}
+		return s.expired(sequence)
+	})
+	return
+}
+
+// Part - wait and return complete part
+func (s *Segmenter) Part(sequence, part int, timeout time.Duration) (data []byte) {
+	s.wait(timeout, func() bool {
+		if segment := s.get(sequence); segment != nil && part >= 0 && part < len(segment.Parts) {
+			data = segment.Parts[part].Data
+			return true
+		}
+		return s.expired(sequence)
+	})
+	return
+}
+
+// Wait - wait complete part or complete segment (part < 0)
+func (s *Segmenter) Wait(sequence, part int, timeout time.Duration) (ok bool) {
+	s.wait(timeout, func() bool {
+		if segment := s.get(sequence); segment != nil {
+			ok = segment.Complete || part >= 0 && part < len(segment.Parts)
+		} else {
+			ok = sequence < s.sequence // already removed from window
+		}
+		return ok
+	})
+	return
+}
+
+// WaitComplete - wait at least n complete segments from start
+func (s *Segmenter) WaitComplete(n int, timeout time.Duration) (ok bool) {
+	s.wait(timeout, func() bool {
+		ok = s.complete >= n
+		return ok
+	})
+	return
+}
+
+func (s *Segmenter) get(sequence int) *Segment {
+	if len(s.segments) == 0 {
+		return nil
+	}
+	if i := sequence - s.segments[0].Sequence; i >= 0 && i < len(s.segments) {
+		return s.segments[i]
+	}
+	return nil
+}
+
+func (s *Segmenter) expired(sequence int) bool {
+	return len(s.segments) > 0 && sequence < s.segments[0].Sequence
+}
+
+func (s *Segmenter) wait(timeout time.Duration, ready func() bool) {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	for {
+		s.mu.Lock()
+		if ready() || s.closed {
+			s.mu.Unlock()
+			return
+		}
+		notify := s.notify
+		s.mu.Unlock()
+
+		select {
+		case <-notify:
+		case <-timer.C:
+			return
+		}
+	}
+}
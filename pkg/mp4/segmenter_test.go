@@ -0,0 +1,72 @@
+package mp4
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hamza-farouk/go2rtc/pkg/core"
+	"github.com/pion/rtp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSegmenter(t *testing.T) {
+	muxer := &Muxer{}
+	muxer.AddTrack(&core.Codec{Name: core.CodecH264, ClockRate: 90000})
+
+	init, err := muxer.GetInit()
+	require.Nil(t, err)
+
+	s := NewSegmenter()
+	_, _ = s.Write(init)
+
+	// 30 fps, keyframe every second
+	var prev []byte
+	for i := 0; i <= 90; i++ {
+		payload := []byte{0, 0, 0, 1, 0x41}
+		if i%30 == 0 {
+			payload = []byte{0, 0, 0, 1, 0x65}
+		}
+
+		packet := &rtp.Packet{
+			Header:  rtp.Header{Timestamp: uint32(3000 * (i + 1))},
+			Payload: payload,
+		}
+		b := muxer.GetPayload(0, packet)
+
+		// one write with two fragments
+		if i%2 == 0 {
+			prev = b
+		} else {
+			_, _ = s.Write(append(prev, b...))
+			prev = nil
+		}
+	}
+	_, _ = s.Write(prev)
+
+	segments := s.Segments()
+	require.Len(t, segments, 4)
+
+	for i, segment := range segments[:3] {
+		require.Equal(t, i, segment.Sequence)
+		require.Equal(t, time.Duration(i)*time.Second, segment.Start)
+		require.Equal(t, time.Second, segment.Duration)
+		require.True(t, segment.Complete)
+		require.Len(t, segment.Parts, 2)
+		require.Equal(t, 500*time.Millisecond, segment.Parts[0].Duration)
+		require.True(t, segment.Parts[0].Independent)
+		require.False(t, segment.Parts[1].Independent)
+	}
+
+	require.False(t, segments[3].Complete)
+	require.Len(t, segments[3].Parts, 0)
+
+	data := s.Segment(1, 0)
+	require.Equal(t, append(segments[1].Parts[0].Data, segments[1].Parts[1].Data...), data)
+
+	require.NotNil(t, s.Part(2, 1, 0))
+	require.True(t, s.Wait(2, -1, 0))
+	require.False(t, s.Wait(3, 0, 10*time.Millisecond))
+
+	s.Close()
+	require.Nil(t, s.Part(3, 0, time.Second))
+}
@@ -0,0 +1,343 @@
+package mpegts
+
+import (
+	"bytes"
+	"sync"
+	"time"
+
+	"github.com/hamza-farouk/go2rtc/pkg/core"
+)
+
+// Segmenter - cut MPEG-TS stream (header + PES packets from Consumer) to
+// segments. Segments start with header and keyframe of video track.
+// Durations are calculated from video PTS. Streams without video are cut
+// on any audio PES by audio PTS.
+type Segmenter struct {
+	SegmentDuration time.Duration // minimum segment duration, real depends on keyframes
+	WindowSize      int           // number of complete segments to keep
+
+	// Timeline - optional shared grid for aligned segments of ABR variants
+	Timeline *core.Timeline
+
+	header []byte
+
+	videoPID uint16
+	audioPID uint16 // used only for streams without video
+	h265     bool
+
+	segments []*Segment
+	sequence int
+	complete int // total complete segments
+
+	data      []byte
+	segStart  uint32
+	firstPTS  uint32
+	firstTime time.Time
+
+	notify chan struct{}
+	closed bool
+	mu     sync.Mutex
+}
+
+type Segment struct {
+	Sequence int           `json:"sequence"`
+	Duration time.Duration `json:"duration"`
+	Time     time.Time     `json:"time"` // wall clock of segment start
+	Size     int           `json:"size"`
+	Complete bool          `json:"complete"`
+
+	data []byte
+}
+
+func NewSegmenter() *Segmenter {
+	return &Segmenter{
+		SegmentDuration: time.Second,
+		WindowSize:      5,
+		notify:          make(chan struct{}),
+	}
+}
+
+// Write - first write should be header, next writes - PES packets
+func (s *Segmenter) Write(b []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.header == nil {
+		s.header = b
+		s.parseHeader(b)
+		return len(b), nil
+	}
+
+	n := len(b)
+
+	for len(b) >= PacketSize {
+		// all packets of one PES are written together
+		size := PacketSize
+		pid, start := packetPID(b)
+		if start && (pid == s.videoPID || s.videoPID == 0 && pid == s.audioPID) {
+			for size+PacketSize <= len(b) {
+				if next, start := packetPID(b[size:]); next != pid || start {
+					break
+				}
+				size += PacketSize
+			}
+
+			s.writePES(b[:size])
+		} else if s.data != nil {
+			s.data = append(s.data, b[:size]...)
+		}
+
+		b = b[size:]
+	}
+
+	s.broadcast()
+
+	return n, nil
+}
+
+func (s *Segmenter) parseHeader(b []byte) {
+	for ; len(b) >= PacketSize; b = b[PacketSize:] {
+		if pid, _ := packetPID(b); pid != pmtPID {
+			continue
+		}
+
+		// skip pointer field, table ID, section length and PMT header
+		i := 5 + int(b[4])
+		end := i + 3 + (int(b[i+1]&0x0F)<<8 | int(b[i+2])) - 4 // without CRC32
+		i += 12 + (int(b[i+10]&0x0F)<<8 | int(b[i+11]))
+
+		for ; i+5 <= end && end <= PacketSize; i += 5 + (int(b[i+3]&0x0F)<<8 | int(b[i+4])) {
+			pid := uint16(b[i+1]&0x1F)<<8 | uint16(b[i+2])
+			switch b[i] {
+			case StreamTypeH264, StreamTypeH265:
+				s.videoPID = pid
+				s.h265 = b[i] == StreamTypeH265
+				return
+			case StreamTypeMetadata:
+			default:
+				if s.audioPID == 0 {
+					s.audioPID = pid
+				}
+			}
+		}
+	}
+}
+
+func (s *Segmenter) writePES(b []byte) {
+	payload := packetPayload(b)
+	// PES start code, stream ID, length, flags, header length, PTS
+	if len(payload) < 14 || payload[7]&0x80 == 0 {
+		return
+	}
+
+	pts := ParseTime(payload[9:])
+	// every audio frame can start segment
+	keyframe := s.videoPID == 0 || s.isKeyframe(b)
+
+	if s.data == nil {
+		if !keyframe {
+			return
+		}
+		s.firstPTS = pts
+		s.firstTime = time.Now()
+		s.startSegment(pts)
+	} else if keyframe && s.nextSegment(pts) {
+		s.closeSegment(pts)
+		s.startSegment(pts)
+	}
+
+	s.data = append(s.data, b...)
+}
+
+func (s *Segmenter) isKeyframe(b []byte) bool {
+	var es []byte
+	for ; len(b) >= PacketSize; b = b[PacketSize:] {
+		es = append(es, packetPayload(b)...)
+	}
+
+	// skip PES header
+	es = es[9+int(es[8]):]
+
+	for {
+		i := bytes.Index(es, []byte{0, 0, 1})
+		if i < 0 || i+3 >= len(es) {
+			return false
+		}
+		es = es[i+3:]
+
+		if s.h265 {
+			if typ := (es[0] >> 1) & 0x3F; typ >= 16 && typ <= 21 {
+				return true // IRAP
+			}
+		} else if es[0]&0x1F == 5 {
+			return true // IDR
+		}
+	}
+}
+
+// nextSegment - segment duration reached or next slot of shared timeline started
+func (s *Segmenter) nextSegment(pts uint32) bool {
+	if s.Timeline != nil {
+		return s.Timeline.Slot(s.wallTime(pts)) > s.Timeline.Slot(s.wallTime(s.segStart))
+	}
+	return s.duration(pts-s.segStart) >= s.SegmentDuration
+}
+
+func (s *Segmenter) wallTime(pts uint32) time.Time {
+	return s.firstTime.Add(s.duration(pts - s.firstPTS))
+}
+
+func (s *Segmenter) startSegment(pts uint32) {
+	s.segStart = pts
+	s.data = append([]byte{}, s.header...)
+
+	segment := &Segment{
+		Sequence: s.sequence,
+		Time:     s.wallTime(pts),
+	}
+
+	if s.Timeline != nil {
+		prev := -1
+		if len(s.segments) > 0 {
+			prev = s.sequence - 1
+		}
+		segment.Sequence, segment.Time = s.Timeline.Segment(segment.Time, prev)
+	}
+
+	s.segments = append(s.segments, segment)
+	s.sequence = segment.Sequence + 1
+
+	// window of complete segments + current segment
+	if n := len(s.segments) - s.WindowSize - 1; n > 0 {
+		s.segments = s.segments[n:]
+	}
+}
+
+func (s *Segmenter) closeSegment(pts uint32) {
+	segment := s.segments[len(s.segments)-1]
+	segment.Duration = s.duration(pts - s.segStart)
+	segment.Complete = true
+	segment.data = s.data
+	segment.Size = len(s.data)
+
+	s.complete++
+}
+
+func (s *Segmenter) duration(ticks uint32) time.Duration {
+	return time.Duration(ticks) * time.Second / 90000
+}
+
+func (s *Segmenter) broadcast() {
+	close(s.notify)
+	s.notify = make(chan struct{})
+}
+
+// Close - wake up all waiters, segmenter won't get new data
+func (s *Segmenter) Close() {
+	s.mu.Lock()
+	if !s.closed {
+		s.closed = true
+		s.broadcast()
+	}
+	s.mu.Unlock()
+}
+
+// Segments - snapshot of segments window, last segment may be incomplete
+func (s *Segmenter) Segments() []Segment {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	segments := make([]Segment, len(s.segments))
+	for i, segment := range s.segments {
+		segments[i] = *segment
+		segments[i].data = nil
+	}
+	return segments
+}
+
+// Segment - wait and return complete segment
+func (s *Segmenter) Segment(sequence int, timeout time.Duration) (data []byte) {
+	s.wait(timeout, func() bool {
+		if segment := s.get(sequence); segment != nil && segment.Complete {
+			data = segment.data
+			return true
+		}
+		return len(s.segments) > 0 && sequence < s.segments[0].Sequence
+	})
+	return
+}
+
+// Wait - wait complete segment
+func (s *Segmenter) Wait(sequence int, timeout time.Duration) (ok bool) {
+	s.wait(timeout, func() bool {
+		if segment := s.get(sequence); segment != nil {
+			ok = segment.Complete
+		} else {
+			ok = sequence < s.sequence // already removed from window
+		}
+		return ok
+	})
+	return
+}
+
+// WaitComplete - wait at least n complete segments from start
+func (s *Segmenter) WaitComplete(n int, timeout time.Duration) (ok bool) {
+	s.wait(timeout, func() bool {
+		ok = s.complete >= n
+		return ok
+	})
+	return
+}
+
+func (s *Segmenter) get(sequence int) *Segment {
+	if len(s.segments) == 0 {
+		return nil
+	}
+	if i := sequence - s.segments[0].Sequence; i >= 0 && i < len(s.segments) {
+		return s.segments[i]
+	}
+	return nil
+}
+
+func (s *Segmenter) wait(timeout time.Duration, ready func() bool) {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	for {
+		s.mu.Lock()
+		if ready() || s.closed {
+			s.mu.Unlock()
+			return
+		}
+		notify := s.notify
+		s.mu.Unlock()
+
+		select {
+		case <-notify:
+		case <-timer.C:
+			return
+		}
+	}
+}
+
+func packetPID(b []byte) (pid uint16, start bool) {
+	return uint16(b[1]&0x1F)<<8 | uint16(b[2]), b[1]&0x40 != 0
+}
+
+func packetPayload(b []byte) []byte {
+	const flagAdaptation = 0b00100000
+
+	if b[3]&flagAdaptation != 0 {
+		if i := 5 + int(b[4]); i < PacketSize {
+			return b[i:PacketSize]
+		}
+		return nil
+	}
+	return b[4:PacketSize]
+}
+
+// ParseTime - PTS or DTS from PES header
+func ParseTime(b []byte) uint32 {
+	_ = b[4] // bounds
+	return uint32(b[0]>>1&0b111)<<30 | uint32(b[1])<<22 | uint32(b[2]>>1)<<15 | uint32(b[3])<<7 | uint32(b[4]>>1)
+}
@@ -0,0 +1,74 @@
+package mpegts
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSegmenter(t *testing.T) {
+	muxer := NewMuxer()
+	video := muxer.AddTrack(StreamTypeH264)
+	audio := muxer.AddTrack(StreamTypeAAC)
+
+	s := NewSegmenter()
+	_, _ = s.Write(muxer.GetHeader())
+
+	// 25 fps, keyframe every second, first frames without keyframe
+	for i := 10; i <= 75; i++ {
+		// AVCC with one NAL unit, big enough for several TS packets
+		payload := make([]byte, 4+400)
+		payload[2], payload[3] = 1, 400-256
+		if i%25 == 0 {
+			payload[4] = 0x65
+		} else {
+			payload[4] = 0x41
+		}
+
+		_, _ = s.Write(muxer.GetPayload(video, uint32(3600*i), payload))
+		_, _ = s.Write(muxer.GetPayload(audio, uint32(3600*i), []byte{0xFF, 0xF1}))
+	}
+
+	segments := s.Segments()
+	require.Len(t, segments, 3)
+
+	for i, segment := range segments[:2] {
+		require.Equal(t, i, segment.Sequence)
+		require.Equal(t, time.Second, segment.Duration)
+		require.True(t, segment.Complete)
+	}
+	require.False(t, segments[2].Complete)
+
+	data := s.Segment(0, 0)
+	require.Equal(t, muxer.GetHeader(), data[:2*PacketSize])
+	require.Zero(t, len(data)%PacketSize)
+
+	require.True(t, s.Wait(1, 0))
+	require.False(t, s.Wait(2, 10*time.Millisecond))
+}
+
+func TestSegmenterAudio(t *testing.T) {
+	muxer := NewMuxer()
+	audio := muxer.AddTrack(StreamTypeAAC)
+
+	s := NewSegmenter()
+	_, _ = s.Write(muxer.GetHeader())
+
+	// 1024 samples at 48000 Hz - 1920 ticks of 90000 Hz clock
+	for i := 0; i <= 100; i++ {
+		_, _ = s.Write(muxer.GetPayload(audio, uint32(1920*i), []byte{0xFF, 0xF1}))
+	}
+
+	segments := s.Segments()
+	require.Len(t, segments, 3)
+
+	for _, segment := range segments[:2] {
+		require.True(t, segment.Complete)
+		require.GreaterOrEqual(t, segment.Duration, time.Second)
+	}
+	require.False(t, segments[2].Complete)
+
+	data := s.Segment(0, 0)
+	require.Equal(t, muxer.GetHeader(), data[:2*PacketSize])
+}
@@ -108,7 +108,7 @@ func rtcConn(nestAPI *API, rawURL, projectID, deviceID string) (*WebRTCClient, e
 		}
 
 		conf := pion.Configuration{}
-		pc, err := rtcAPI.NewPeerConnection(conf)
+		pc, err := webrtc.NewPeerConnection(rtcAPI, conf)
 		if err != nil {
 			return nil, err
 		}
@@ -3,6 +3,7 @@ package onvif
 import (
 	"bytes"
 	"errors"
+	"fmt"
 	"html"
 	"io"
 	"net/http"
@@ -20,6 +21,7 @@ type Client struct {
 	deviceURL string
 	mediaURL  string
 	imaginURL string
+	ptzURL    string
 }
 
 func NewClient(rawURL string) (*Client, error) {
@@ -44,6 +46,7 @@ func NewClient(rawURL string) (*Client, error) {
 
 	client.mediaURL = FindTagValue(b, "Media.+?XAddr")
 	client.imaginURL = FindTagValue(b, "Imaging.+?XAddr")
+	client.ptzURL = FindTagValue(b, "PTZ.+?XAddr")
 
 	return client, nil
 }
@@ -51,18 +54,10 @@ func NewClient(rawURL string) (*Client, error) {
 func (c *Client) GetURI() (string, error) {
 	query := c.url.Query()
 
-	token := query.Get("subtype")
-
 	// support empty
-	if i := atoi(token); i >= 0 {
-		tokens, err := c.GetProfilesTokens()
-		if err != nil {
-			return "", err
-		}
-		if i >= len(tokens) {
-			return "", errors.New("onvif: wrong subtype")
-		}
-		token = tokens[i]
+	token, err := c.GetProfileToken()
+	if err != nil {
+		return "", err
 	}
 
 	getUri := c.GetStreamUri
@@ -125,13 +120,13 @@ func (c *Client) HasSnapshots() bool {
 
 func (c *Client) GetProfile(token string) ([]byte, error) {
 	return c.Request(
-		c.mediaURL, `<trt:GetProfile><trt:ProfileToken>`+token+`</trt:ProfileToken></trt:GetProfile>`,
+		c.mediaURL, `<trt:GetProfile><trt:ProfileToken>`+html.EscapeString(token)+`</trt:ProfileToken></trt:GetProfile>`,
 	)
 }
 
 func (c *Client) GetVideoSourceConfiguration(token string) ([]byte, error) {
 	return c.Request(c.mediaURL, `<trt:GetVideoSourceConfiguration>
-	<trt:ConfigurationToken>`+token+`</trt:ConfigurationToken>
+	<trt:ConfigurationToken>`+html.EscapeString(token)+`</trt:ConfigurationToken>
 </trt:GetVideoSourceConfiguration>`)
 }
 
@@ -141,13 +136,13 @@ func (c *Client) GetStreamUri(token string) ([]byte, error) {
 		<tt:Stream>RTP-Unicast</tt:Stream>
 		<tt:Transport><tt:Protocol>RTSP</tt:Protocol></tt:Transport>
 	</trt:StreamSetup>
-	<trt:ProfileToken>`+token+`</trt:ProfileToken>
+	<trt:ProfileToken>`+html.EscapeString(token)+`</trt:ProfileToken>
 </trt:GetStreamUri>`)
 }
 
 func (c *Client) GetSnapshotUri(token string) ([]byte, error) {
 	return c.Request(
-		c.imaginURL, `<trt:GetSnapshotUri><trt:ProfileToken>`+token+`</trt:ProfileToken></trt:GetSnapshotUri>`,
+		c.imaginURL, `<trt:GetSnapshotUri><trt:ProfileToken>`+html.EscapeString(token)+`</trt:ProfileToken></trt:GetSnapshotUri>`,
 	)
 }
 
@@ -158,6 +153,77 @@ func (c *Client) GetServiceCapabilities() ([]byte, error) {
 	)
 }
 
+// GetProfileToken - profile token from subtype param, default - first profile
+func (c *Client) GetProfileToken() (string, error) {
+	token := c.url.Query().Get("subtype")
+	if i := atoi(token); i >= 0 {
+		tokens, err := c.GetProfilesTokens()
+		if err != nil {
+			return "", err
+		}
+		if i >= len(tokens) {
+			return "", errors.New("onvif: wrong subtype")
+		}
+		token = tokens[i]
+	}
+	return token, nil
+}
+
+// ContinuousMove - pan, tilt and zoom speed in range [-1, 1]
+func (c *Client) ContinuousMove(token string, pan, tilt, zoom float64) ([]byte, error) {
+	return c.Request(c.ptzURL, fmt.Sprintf(`<tptz:ContinuousMove>
+	<tptz:ProfileToken>%s</tptz:ProfileToken>
+	<tptz:Velocity>
+		<tt:PanTilt x="%g" y="%g"/>
+		<tt:Zoom x="%g"/>
+	</tptz:Velocity>
+</tptz:ContinuousMove>`, html.EscapeString(token), pan, tilt, zoom))
+}
+
+func (c *Client) StopMove(token string) ([]byte, error) {
+	return c.Request(c.ptzURL, `<tptz:Stop>
+	<tptz:ProfileToken>`+html.EscapeString(token)+`</tptz:ProfileToken>
+	<tptz:PanTilt>true</tptz:PanTilt>
+	<tptz:Zoom>true</tptz:Zoom>
+</tptz:Stop>`)
+}
+
+func (c *Client) GotoPreset(token, preset string) ([]byte, error) {
+	return c.Request(c.ptzURL, `<tptz:GotoPreset>
+	<tptz:ProfileToken>`+html.EscapeString(token)+`</tptz:ProfileToken>
+	<tptz:PresetToken>`+html.EscapeString(preset)+`</tptz:PresetToken>
+</tptz:GotoPreset>`)
+}
+
+// GetPresets - preset tokens for profile
+func (c *Client) GetPresets(token string) ([]string, error) {
+	b, err := c.Request(c.ptzURL, `<tptz:GetPresets>
+	<tptz:ProfileToken>`+html.EscapeString(token)+`</tptz:ProfileToken>
+</tptz:GetPresets>`)
+	if err != nil {
+		return nil, err
+	}
+	return parsePresets(b), nil
+}
+
+func parsePresets(b []byte) []string {
+	var tokens []string
+
+	re := regexp.MustCompile(`<(?:\w+:)?Preset\s[^>]*token="([^"]+)"`)
+	for _, s := range re.FindAllStringSubmatch(string(b), -1) {
+		tokens = append(tokens, html.UnescapeString(s[1]))
+	}
+
+	return tokens
+}
+
+// SetSynchronizationPoint - request keyframe from camera encoder for this profile
+func (c *Client) SetSynchronizationPoint(token string) ([]byte, error) {
+	return c.Request(c.mediaURL, `<trt:SetSynchronizationPoint>
+	<trt:ProfileToken>`+html.EscapeString(token)+`</trt:ProfileToken>
+</trt:SetSynchronizationPoint>`)
+}
+
 func (c *Client) DeviceRequest(operation string) ([]byte, error) {
 	switch operation {
 	case DeviceGetServices:
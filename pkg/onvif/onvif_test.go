@@ -225,3 +225,9 @@ func TestGetCapabilities(t *testing.T) {
 		})
 	}
 }
+
+func TestParsePresets(t *testing.T) {
+	b := []byte(`<tptz:GetPresetsResponse><tptz:Preset token="1"><tt:Name>Gate</tt:Name></tptz:Preset>` +
+		`<tptz:Preset token="a&amp;b"><tt:Name>Door</tt:Name></tptz:Preset></tptz:GetPresetsResponse>`)
+	require.Equal(t, []string{"1", "a&b"}, parsePresets(b))
+}
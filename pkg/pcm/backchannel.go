@@ -1,7 +1,11 @@
 package pcm
 
 import (
+	"bufio"
+	"encoding/json"
 	"errors"
+	"io"
+	"os"
 
 	"github.com/hamza-farouk/go2rtc/pkg/core"
 	"github.com/hamza-farouk/go2rtc/pkg/shell"
@@ -10,6 +14,7 @@ import (
 
 type Backchannel struct {
 	core.Connection
+	core.Listener
 	cmd *shell.Command
 }
 
@@ -65,5 +70,35 @@ func (c *Backchannel) AddTrack(media *core.Media, codec *core.Codec, track *core
 }
 
 func (c *Backchannel) Start() error {
-	return c.cmd.Run()
+	// JSON lines from process stdout will be fired as custom messages.
+	// Own pipe instead of StdoutPipe, because shell.Command waits for the
+	// process in background and Wait closes StdoutPipe before reads complete.
+	rd, wr, err := os.Pipe()
+	if err != nil {
+		return err
+	}
+	defer rd.Close()
+
+	c.cmd.Stdout = wr
+	err = c.cmd.Start()
+	_ = wr.Close() // process has own copy
+
+	if err != nil {
+		return err
+	}
+
+	// read until process exits and closes stdout, then wait for exit status
+	c.readMessages(rd)
+
+	return c.cmd.Wait()
+}
+
+func (c *Backchannel) readMessages(rd io.Reader) {
+	scanner := bufio.NewScanner(rd)
+	for scanner.Scan() {
+		var msg core.Message
+		if err := json.Unmarshal(scanner.Bytes(), &msg); err == nil && msg.Type != "" {
+			c.Fire(&msg)
+		}
+	}
 }
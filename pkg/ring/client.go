@@ -200,7 +200,7 @@ func Dial(rawURL string) (*Client, error) {
 		return nil, err
 	}
 
-	pc, err := api.NewPeerConnection(conf)
+	pc, err := webrtc.NewPeerConnection(api, conf)
 	if err != nil {
 		client.ws.Close()
 		return nil, err
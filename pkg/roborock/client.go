@@ -102,7 +102,7 @@ func (c *Client) Connect() error {
 		return err
 	}
 
-	pc, err := api.NewPeerConnection(conf)
+	pc, err := webrtc.NewPeerConnection(api, conf)
 	if err != nil {
 		return err
 	}
@@ -8,6 +8,7 @@ import (
 	"strings"
 	"sync"
 
+	"github.com/hamza-farouk/go2rtc/pkg/flv"
 	"github.com/hamza-farouk/go2rtc/pkg/flv/amf"
 )
 
@@ -21,11 +22,18 @@ const (
 	TypeCommand         = 20
 )
 
+// FourCCList - supported Enhanced RTMP codecs
+// https://veovera.org/docs/enhanced/enhanced-rtmp-v2
+var FourCCList = []string{flv.FourCCHEVC, flv.FourCCAV1, flv.FourCCOpus}
+
 type Conn struct {
 	App    string
 	Stream string
 	Intent string
 
+	// Validate - optional server check for publish and play commands
+	Validate func(c *Conn) error
+
 	rdPacketSize uint32
 	wrPacketSize uint32
 
@@ -242,9 +250,10 @@ func (c *Conn) writePacketSize() error {
 
 func (c *Conn) writeConnect() error {
 	b := amf.EncodeItems("connect", 1, map[string]any{
-		"app":      c.App,
-		"flashVer": "FMLE/3.0 (compatible; FMSc/1.0)",
-		"tcUrl":    c.url,
+		"app":        c.App,
+		"flashVer":   "FMLE/3.0 (compatible; FMSc/1.0)",
+		"tcUrl":      c.url,
+		"fourCcList": FourCCList, // Enhanced RTMP
 	})
 	if err := c.writeMessage(3, TypeCommand, 0, b); err != nil {
 		return err
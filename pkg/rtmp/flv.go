@@ -1,6 +1,8 @@
 package rtmp
 
 import (
+	"errors"
+
 	"github.com/hamza-farouk/go2rtc/pkg/flv"
 )
 
@@ -69,28 +71,41 @@ func encodeFLV(b []byte, msgType byte, time uint32, payload []byte) {
 	copy(b[4+11:], payload)
 }
 
+var errWrongFLV = errors.New("rtmp: wrong FLV tag")
+
 // Write - convert FLV format to RTMP format
 func (c *Conn) Write(p []byte) (n int, err error) {
 	n = len(p)
 
-	if p[0] == 'F' {
+	if len(p) > 0 && p[0] == 'F' {
+		if len(p) < 9+4 {
+			return 0, errWrongFLV
+		}
 		p = p[9+4:] // skip first msg with FLV header
+	}
 
-		for len(p) > 0 {
-			size := 11 + uint16(p[2])<<8 + uint16(p[3]) + 4
-			if _, err = c.Write(p[:size]); err != nil {
-				return 0, err
-			}
-			p = p[size:]
+	// one write can contain multiple tags (ex. sequence start + keyframe)
+	for len(p) > 0 {
+		if len(p) < 11+4 {
+			return 0, errWrongFLV
 		}
-		return
-	}
 
-	// decode FLV: 11 bytes header + payload + 4 byte size
-	tagType := p[0]
-	timeMS := uint32(p[4])<<16 | uint32(p[5])<<8 | uint32(p[6]) | uint32(p[7])<<24
-	payload := p[11 : len(p)-4]
+		// decode FLV: 11 bytes header + payload + 4 byte size
+		size := 11 + Uint24(p[1:]) + 4
+		if len(p) < int(size) {
+			return 0, errWrongFLV
+		}
+
+		tagType := p[0]
+		timeMS := uint32(p[4])<<16 | uint32(p[5])<<8 | uint32(p[6]) | uint32(p[7])<<24
+		payload := p[11 : size-4]
+
+		if err = c.writeMessage(4, tagType, timeMS, payload); err != nil {
+			return 0, err
+		}
+
+		p = p[size:]
+	}
 
-	err = c.writeMessage(4, tagType, timeMS, payload)
 	return
 }
@@ -151,9 +151,17 @@ func (c *Conn) acceptCommand(b []byte) error {
 			}
 		}
 
+		props := map[string]any{"fmsVer": "FMS/3,0,1,123"}
+
+		// Enhanced RTMP client should send fourCcList and server should response with supported codecs
+		if len(items) == 3 {
+			if v, ok := items[2].(map[string]any); ok && v["fourCcList"] != nil {
+				props["fourCcList"] = FourCCList
+			}
+		}
+
 		payload := amf.EncodeItems(
-			"_result", tID,
-			map[string]any{"fmsVer": "FMS/3,0,1,123"},
+			"_result", tID, props,
 			map[string]any{"code": "NetConnection.Connect.Success"},
 		)
 		return c.writeMessage(3, TypeCommand, 0, payload)
@@ -177,6 +185,18 @@ func (c *Conn) acceptCommand(b []byte) error {
 		c.Intent = cmd
 		c.streamID = 1
 
+		// stream key (publish) or stream name (play)
+		if len(items) >= 4 {
+			c.Stream, _ = items[3].(string)
+		}
+
+		if c.Validate != nil {
+			if err = c.Validate(c); err != nil {
+				_ = c.writeStatus("error", c.errorCode(), err.Error())
+				return err
+			}
+		}
+
 	default:
 		println("rtmp: unknown command: " + cmd)
 	}
@@ -196,6 +216,20 @@ func (c *Conn) WriteStart() error {
 	return c.writeMessage(3, TypeCommand, 0, payload)
 }
 
+func (c *Conn) writeStatus(level, code, description string) error {
+	payload := amf.EncodeItems("onStatus", 0, nil, map[string]any{
+		"level": level, "code": code, "description": description,
+	})
+	return c.writeMessage(3, TypeCommand, 0, payload)
+}
+
+func (c *Conn) errorCode() string {
+	if c.Intent == CommandPublish {
+		return "NetStream.Publish.BadName" // OBS shows it as invalid stream key
+	}
+	return "NetStream.Play.Failed"
+}
+
 func nowMS() uint32 {
 	return uint32(time.Now().UnixNano() / int64(time.Millisecond))
 }
@@ -41,8 +41,13 @@ func (c *Conn) Dial() (err error) {
 		if c.Timeout != 0 {
 			timeout = time.Second * time.Duration(c.Timeout)
 		}
-		conn, err = tcp.Dial(c.URL, timeout)
-		c.Protocol = "rtsp+tcp"
+		if strings.HasPrefix(c.URL.Scheme, "rtsp+http") {
+			c.Protocol = c.URL.Scheme
+			conn, err = DialTunnel(c.URL, timeout)
+		} else {
+			conn, err = tcp.Dial(c.URL, timeout)
+			c.Protocol = "rtsp+tcp"
+		}
 	} else {
 		conn, err = websocket.Dial(c.Transport)
 		c.Protocol = "ws"
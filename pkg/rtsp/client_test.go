@@ -1,8 +1,10 @@
 package rtsp
 
 import (
+	"io"
 	"net"
 	"os"
+	"strings"
 	"testing"
 	"time"
 
@@ -93,3 +95,12 @@ Session: 1
 	require.Nil(t, err)
 	require.Equal(t, ch, byte(4))
 }
+
+func TestBase64Reader(t *testing.T) {
+	// each message is encoded separately with own padding
+	rd := &base64Reader{rd: strings.NewReader("T1BUSU9OUyAq\r\nIFJUU1AvMS4wDQo=Q1NlcTogMQ0KDQo=")}
+
+	b, err := io.ReadAll(rd)
+	require.Nil(t, err)
+	require.Equal(t, "OPTIONS * RTSP/1.0\r\nCSeq: 1\r\n\r\n", string(b))
+}
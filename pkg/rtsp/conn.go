@@ -42,6 +42,8 @@ type Conn struct {
 	mode      core.Mode
 	playOK    bool
 	reader    *bufio.Reader
+	reject    string
+	skipAuth  bool
 	sequence  int
 	session   string
 	uri       string
@@ -33,6 +33,16 @@ func (c *Conn) Auth(username, password string) {
 	c.auth = tcp.NewAuth(info)
 }
 
+// SkipAuth - can be called from request listener to skip auth check for this request
+func (c *Conn) SkipAuth() {
+	c.skipAuth = true
+}
+
+// Reject - can be called from ANNOUNCE listener to respond with error status
+func (c *Conn) Reject(status string) {
+	c.reject = status
+}
+
 // addSpropParameters adds sprop-parameter-sets to H.264 codec if missing
 func addSpropParameters(codec *core.Codec, forceSprop bool) {
 	if codec.Name != core.CodecH264 {
@@ -91,7 +101,10 @@ func (c *Conn) Accept() error {
 
 		c.Fire(req)
 
-		if valid, empty := c.auth.Validate(req); !valid {
+		skip := c.skipAuth
+		c.skipAuth = false
+
+		if valid, empty := c.auth.Validate(req); !valid && !skip {
 			res := &tcp.Response{
 				Status:  "401 Unauthorized",
 				Header:  map[string][]string{"Www-Authenticate": {`Basic realm="go2rtc"`}},
@@ -144,6 +157,12 @@ func (c *Conn) Accept() error {
 			c.mode = core.ModePassiveProducer
 			c.Fire(MethodAnnounce)
 
+			if c.reject != "" {
+				res := &tcp.Response{Status: c.reject, Request: req}
+				_ = c.WriteResponse(res)
+				return errors.New("rtsp: publish rejected: " + c.reject)
+			}
+
 			res := &tcp.Response{Request: req}
 			if err = c.WriteResponse(res); err != nil {
 				return err
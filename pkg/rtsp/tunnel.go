@@ -0,0 +1,258 @@
+package rtsp
+
+import (
+	"bufio"
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/hamza-farouk/go2rtc/pkg/core"
+	"github.com/hamza-farouk/go2rtc/pkg/tcp"
+)
+
+// RTSP over HTTP tunneling (Apple style):
+// - client opens GET connection with x-sessioncookie header, server sends RTSP data to it
+// - client opens POST connection with same cookie and sends base64 encoded RTSP data
+// https://web.archive.org/web/20140702052709/https://developer.apple.com/quicktime/icefloe/dispatch028.html
+
+const MimeTunnel = "application/x-rtsp-tunnelled"
+
+// Tunnel - net.Conn with separate connections for read and write
+type Tunnel struct {
+	rd io.Reader
+	wr io.Writer
+
+	get  net.Conn // server => client
+	post net.Conn // client => server
+
+	server bool
+}
+
+// NewServerTunnel - create tunnel from hijacked GET and POST connections
+// rd should contain rest of POST request (after headers)
+func NewServerTunnel(get, post net.Conn, rd io.Reader) *Tunnel {
+	return &Tunnel{
+		rd:     &base64Reader{rd: rd},
+		wr:     get,
+		get:    get,
+		post:   post,
+		server: true,
+	}
+}
+
+// DialTunnel - open GET and POST connections for rtsp+http and rtsp+https URL,
+// change URL scheme to rtsp for RTSP requests inside the tunnel
+func DialTunnel(u *url.URL, timeout time.Duration) (net.Conn, error) {
+	secure := u.Scheme == "rtsp+https"
+
+	address := u.Host
+	if u.Port() == "" {
+		if secure {
+			address += ":443"
+		} else {
+			address += ":80"
+		}
+	}
+
+	get, err := dialHTTP(address, u.Hostname(), secure, timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	cookie := core.RandString(22, 62)
+
+	req := tunnelRequest("GET", u, cookie)
+	req += "Accept: " + MimeTunnel + "\r\n\r\n"
+
+	if _, err = get.Write([]byte(req)); err != nil {
+		_ = get.Close()
+		return nil, err
+	}
+
+	rd := bufio.NewReaderSize(get, core.BufferSize)
+
+	res, err := http.ReadResponse(rd, nil)
+	if err != nil {
+		_ = get.Close()
+		return nil, err
+	}
+
+	if res.StatusCode != http.StatusOK {
+		_ = get.Close()
+		return nil, errors.New("rtsp: tunnel wrong status: " + res.Status)
+	}
+
+	post, err := dialHTTP(address, u.Hostname(), secure, timeout)
+	if err != nil {
+		_ = get.Close()
+		return nil, err
+	}
+
+	req = tunnelRequest("POST", u, cookie)
+	req += "Content-Type: " + MimeTunnel + "\r\n" +
+		"Content-Length: 32767\r\n" +
+		"Expires: Sun, 9 Jan 1972 00:00:00 GMT\r\n\r\n"
+
+	if _, err = post.Write([]byte(req)); err != nil {
+		_ = get.Close()
+		_ = post.Close()
+		return nil, err
+	}
+
+	u.Scheme = "rtsp"
+
+	return &Tunnel{
+		rd:   rd,
+		wr:   &base64Writer{wr: post},
+		get:  get,
+		post: post,
+	}, nil
+}
+
+func (t *Tunnel) Read(b []byte) (int, error) {
+	return t.rd.Read(b)
+}
+
+func (t *Tunnel) Write(b []byte) (int, error) {
+	return t.wr.Write(b)
+}
+
+func (t *Tunnel) Close() error {
+	_ = t.post.Close()
+	return t.get.Close()
+}
+
+func (t *Tunnel) LocalAddr() net.Addr {
+	return t.get.LocalAddr()
+}
+
+func (t *Tunnel) RemoteAddr() net.Addr {
+	return t.get.RemoteAddr()
+}
+
+func (t *Tunnel) SetDeadline(ts time.Time) error {
+	_ = t.post.SetDeadline(ts)
+	return t.get.SetDeadline(ts)
+}
+
+func (t *Tunnel) SetReadDeadline(ts time.Time) error {
+	if t.server {
+		return t.post.SetReadDeadline(ts)
+	}
+	return t.get.SetReadDeadline(ts)
+}
+
+func (t *Tunnel) SetWriteDeadline(ts time.Time) error {
+	if t.server {
+		return t.get.SetWriteDeadline(ts)
+	}
+	return t.post.SetWriteDeadline(ts)
+}
+
+func dialHTTP(address, hostname string, secure bool, timeout time.Duration) (net.Conn, error) {
+	conn, err := net.DialTimeout("tcp", address, timeout)
+	if err != nil || !secure {
+		return conn, err
+	}
+
+	var config *tls.Config
+	if tcp.IsIP(hostname) {
+		config = &tls.Config{InsecureSkipVerify: true}
+	} else {
+		config = &tls.Config{ServerName: hostname}
+	}
+
+	tlsConn := tls.Client(conn, config)
+	if err = tlsConn.Handshake(); err != nil {
+		return nil, err
+	}
+
+	return tlsConn, nil
+}
+
+func tunnelRequest(method string, u *url.URL, cookie string) string {
+	s := fmt.Sprintf(
+		"%s %s HTTP/1.0\r\nHost: %s\r\nx-sessioncookie: %s\r\nPragma: no-cache\r\nCache-Control: no-cache\r\n",
+		method, u.RequestURI(), u.Host, cookie,
+	)
+	if u.User != nil {
+		pass, _ := u.User.Password()
+		s += "Authorization: Basic " + tcp.B64(u.User.Username(), pass) + "\r\n"
+	}
+	return s
+}
+
+type base64Writer struct {
+	wr io.Writer
+}
+
+func (w *base64Writer) Write(b []byte) (int, error) {
+	s := base64.StdEncoding.EncodeToString(b)
+	if _, err := w.wr.Write([]byte(s)); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+// base64Reader - decode stream of base64 messages, each message can have own padding
+type base64Reader struct {
+	rd  io.Reader
+	src []byte // not decoded chars
+	dst []byte // decoded but not read bytes
+}
+
+func (r *base64Reader) Read(b []byte) (int, error) {
+	for len(r.dst) == 0 {
+		buf := make([]byte, 4096)
+		n, err := r.rd.Read(buf)
+		if n == 0 && err != nil {
+			return 0, err
+		}
+
+		for _, c := range buf[:n] {
+			// skip line breaks and any other chars outside base64 alphabet
+			if c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '+' || c == '/' || c == '=' {
+				r.src = append(r.src, c)
+			}
+		}
+
+		// decode only full groups, stop after group with padding
+		i := 0
+		for i+4 <= len(r.src) {
+			i += 4
+			if r.src[i-1] == '=' {
+				if err = r.decode(i); err != nil {
+					return 0, err
+				}
+				i = 0
+			}
+		}
+		if err = r.decode(i); err != nil {
+			return 0, err
+		}
+	}
+
+	n := copy(b, r.dst)
+	r.dst = r.dst[n:]
+	return n, nil
+}
+
+func (r *base64Reader) decode(n int) error {
+	if n == 0 {
+		return nil
+	}
+	b := make([]byte, base64.StdEncoding.DecodedLen(n))
+	n2, err := base64.StdEncoding.Decode(b, r.src[:n])
+	if err != nil {
+		return err
+	}
+	r.dst = append(r.dst, b[:n2]...)
+	r.src = r.src[n:]
+	return nil
+}
@@ -2,11 +2,14 @@ package webrtc
 
 import (
 	"net"
+	"path"
 
 	"github.com/hamza-farouk/go2rtc/pkg/core"
 	"github.com/hamza-farouk/go2rtc/pkg/xnet"
 	"github.com/pion/ice/v4"
 	"github.com/pion/interceptor"
+	"github.com/pion/interceptor/pkg/nack"
+	"github.com/pion/interceptor/pkg/twcc"
 	"github.com/pion/webrtc/v4"
 )
 
@@ -19,12 +22,14 @@ func NewAPI() (*webrtc.API, error) {
 }
 
 type Filters struct {
-	Candidates []string `yaml:"candidates"`
-	Loopback   bool     `yaml:"loopback"`
-	Interfaces []string `yaml:"interfaces"`
-	IPs        []string `yaml:"ips"`
-	Networks   []string `yaml:"networks"`
-	UDPPorts   []uint16 `yaml:"udp_ports"`
+	Candidates        []string `yaml:"candidates"`
+	Loopback          bool     `yaml:"loopback"`
+	Interfaces        []string `yaml:"interfaces"`         // names or patterns, ex. eth*
+	ExcludeInterfaces []string `yaml:"exclude_interfaces"` // names or patterns, ex. docker*
+	IPs               []string `yaml:"ips"`
+	Networks          []string `yaml:"networks"`
+	UDPPorts          []uint16 `yaml:"udp_ports"`
+	MDNS              string   `yaml:"mdns"` // query (default), gather, disabled
 }
 
 func NewServerAPI(network, address string, filters *Filters) (*webrtc.API, error) {
@@ -38,7 +43,7 @@ func NewServerAPI(network, address string, filters *Filters) (*webrtc.API, error
 	}
 
 	i := &interceptor.Registry{}
-	if err := webrtc.RegisterDefaultInterceptors(m, i); err != nil {
+	if err := RegisterDefaultInterceptors(m, i); err != nil {
 		return nil, err
 	}
 
@@ -52,9 +57,12 @@ func NewServerAPI(network, address string, filters *Filters) (*webrtc.API, error
 	}
 
 	var interfaceFilter func(name string) bool
-	if filters != nil && filters.Interfaces != nil {
+	if filters != nil && (filters.Interfaces != nil || filters.ExcludeInterfaces != nil) {
 		interfaceFilter = func(name string) bool {
-			return core.Contains(filters.Interfaces, name)
+			if filters.Interfaces != nil && !MatchInterface(filters.Interfaces, name) {
+				return false
+			}
+			return !MatchInterface(filters.ExcludeInterfaces, name)
 		}
 	} else {
 		// default interfaces - all, except loopback
@@ -95,6 +103,17 @@ func NewServerAPI(network, address string, filters *Filters) (*webrtc.API, error
 	}
 	s.SetNetworkTypes(networkTypes)
 
+	if filters != nil {
+		switch filters.MDNS {
+		case "gather":
+			// hide local IPs behind random .local names, like browsers do
+			s.SetICEMulticastDNSMode(ice.MulticastDNSModeQueryAndGather)
+		case "disabled":
+			// useful in Docker bridge network, where multicast doesn't work
+			s.SetICEMulticastDNSMode(ice.MulticastDNSModeDisabled)
+		}
+	}
+
 	if filters != nil && len(filters.UDPPorts) == 2 {
 		_ = s.SetEphemeralUDPPortRange(filters.UDPPorts[0], filters.UDPPorts[1])
 	}
@@ -222,3 +241,63 @@ func RegisterDefaultCodecs(m *webrtc.MediaEngine) error {
 
 	return nil
 }
+
+// MatchInterface - check interface name by list of names or patterns (ex. docker*, br-*)
+func MatchInterface(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// RegisterDefaultInterceptors - same as pion defaults, but with a bigger NACK send buffer
+// and transport-wide sequence numbers for outgoing packets, so viewers send TWCC feedback.
+// Also RTP stats interceptor, same as newer pion versions
+func RegisterDefaultInterceptors(m *webrtc.MediaEngine, i *interceptor.Registry) error {
+	generator, err := nack.NewGeneratorInterceptor()
+	if err != nil {
+		return err
+	}
+
+	// 4096 packets is about 1-2 seconds of 4K video
+	responder, err := nack.NewResponderInterceptor(nack.ResponderSize(4096))
+	if err != nil {
+		return err
+	}
+
+	m.RegisterFeedback(webrtc.RTCPFeedback{Type: "nack"}, webrtc.RTPCodecTypeVideo)
+	m.RegisterFeedback(webrtc.RTCPFeedback{Type: "nack", Parameter: "pli"}, webrtc.RTPCodecTypeVideo)
+	i.Add(responder)
+	i.Add(generator)
+
+	if err = webrtc.ConfigureRTCPReports(i); err != nil {
+		return err
+	}
+
+	if err = webrtc.ConfigureSimulcastExtensionHeaders(m); err != nil {
+		return err
+	}
+
+	if err = webrtc.ConfigureTWCCSender(m, i); err != nil {
+		return err
+	}
+
+	header, err := twcc.NewHeaderExtensionInterceptor()
+	if err != nil {
+		return err
+	}
+
+	i.Add(header)
+
+	// RTP stats for GetStats
+	factory, err := newStatsFactory()
+	if err != nil {
+		return err
+	}
+
+	i.Add(factory)
+
+	return nil
+}
@@ -26,7 +26,10 @@ func (c *Conn) CreateOffer(medias []*core.Media) (string, error) {
 			_, err = c.pc.AddTransceiverFromTrack(NewTrack(media.Kind))
 		default:
 			// Nest cameras require data channel
-			_, err = c.pc.CreateDataChannel(media.Kind, nil)
+			var channel *webrtc.DataChannel
+			if channel, err = c.pc.CreateDataChannel(media.Kind, nil); err == nil {
+				c.addChannel(channel)
+			}
 		}
 
 		if err != nil {
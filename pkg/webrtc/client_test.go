@@ -13,7 +13,7 @@ func TestClient(t *testing.T) {
 	api, err := NewAPI()
 	require.Nil(t, err)
 
-	pc, err := api.NewPeerConnection(webrtc.Configuration{})
+	pc, err := NewPeerConnection(api, webrtc.Configuration{})
 	require.Nil(t, err)
 
 	prod := NewConn(pc)
@@ -4,6 +4,8 @@ import (
 	"encoding/json"
 	"fmt"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/hamza-farouk/go2rtc/pkg/core"
@@ -22,6 +24,17 @@ type Conn struct {
 
 	offer  string
 	closed core.Waiter
+
+	channels   []*webrtc.DataChannel
+	channelsMu sync.Mutex
+
+	simulcast  *simulcast
+	congestion congestion
+
+	jitter atomic.Int64 // jitter buffer latency for producer tracks
+
+	stats   connStats
+	summary atomic.Pointer[StatsSummary]
 }
 
 func NewConn(pc *webrtc.PeerConnection) *Conn {
@@ -42,6 +55,7 @@ func NewConn(pc *webrtc.PeerConnection) *Conn {
 	})
 
 	pc.OnDataChannel(func(channel *webrtc.DataChannel) {
+		c.addChannel(channel)
 		c.Fire(channel)
 	})
 
@@ -98,6 +112,13 @@ func NewConn(pc *webrtc.PeerConnection) *Conn {
 			}()
 		}
 
+		var jitter *jitterBuffer
+		defer func() {
+			if jitter != nil {
+				jitter.Close()
+			}
+		}()
+
 		for {
 			b := make([]byte, ReceiveMTU)
 			n, _, err := remote.Read(b)
@@ -112,6 +133,19 @@ func NewConn(pc *webrtc.PeerConnection) *Conn {
 				return
 			}
 
+			// jitter buffer can be enabled after connection
+			if jitter == nil {
+				if latency := c.jitter.Load(); latency > 0 {
+					jitter = c.newJitterBuffer(remote, track, time.Duration(latency))
+				}
+			}
+
+			if jitter != nil {
+				// padding packets also needed for sequence numbers
+				jitter.Push(packet)
+				continue
+			}
+
 			if len(packet.Payload) == 0 {
 				continue
 			}
@@ -137,6 +171,7 @@ func NewConn(pc *webrtc.PeerConnection) *Conn {
 			for _, sender := range c.Senders {
 				sender.Start()
 			}
+			go c.statsLoop()
 		case webrtc.PeerConnectionStateDisconnected, webrtc.PeerConnectionStateFailed, webrtc.PeerConnectionStateClosed:
 			// disconnect event comes earlier, than failed
 			// but it comes only for success connections
@@ -148,7 +183,19 @@ func NewConn(pc *webrtc.PeerConnection) *Conn {
 }
 
 func (c *Conn) MarshalJSON() ([]byte, error) {
-	return json.Marshal(c.Connection)
+	summary := c.GetStatsSummary()
+	if summary == nil {
+		return json.Marshal(c.Connection)
+	}
+
+	info := struct {
+		core.Connection
+		Stats *StatsSummary `json:"webrtc_stats"`
+	}{
+		Connection: c.Connection,
+		Stats:      summary,
+	}
+	return json.Marshal(info)
 }
 
 func (c *Conn) Close() error {
@@ -183,14 +230,14 @@ func (c *Conn) getSenderTrack(mid string) *Track {
 
 func (c *Conn) getMediaCodec(remote *webrtc.TrackRemote) (*core.Media, *core.Codec) {
 	for _, tr := range c.pc.GetTransceivers() {
-		// search Transeiver for this TrackRemote
-		if tr.Receiver() == nil || tr.Receiver().Track() != remote {
+		// search Transeiver for this TrackRemote (receiver has multiple tracks for simulcast)
+		if tr.Receiver() == nil || !core.Contains(tr.Receiver().Tracks(), remote) {
 			continue
 		}
 
-		// search Media for this MID
+		// search Media for this MID and simulcast RID
 		for _, media := range c.Medias {
-			if media.ID != tr.Mid() || media.Direction != core.DirectionRecvonly {
+			if media.ID != tr.Mid() || media.Direction != core.DirectionRecvonly || media.Layer != remote.RID() {
 				continue
 			}
 
@@ -19,6 +19,14 @@ func (c *Conn) AddTrack(media *core.Media, codec *core.Codec, track *core.Receiv
 
 	for _, sender := range c.Senders {
 		if sender.Codec == codec {
+			if c.simulcast != nil && media.Layer == core.LayerAuto {
+				// one more simulcast layer for the same consumer track
+				sender = core.NewSender(media, codec)
+				sender.Handler = c.simulcast.Handler(track)
+				sender.Bind(track)
+				c.Senders = append(c.Senders, sender)
+				return nil
+			}
 			sender.Bind(track)
 			return nil
 		}
@@ -46,34 +54,55 @@ func (c *Conn) AddTrack(media *core.Media, codec *core.Codec, track *core.Receiv
 		_ = localTrack.WriteRTP(payloadType, packet)
 	}
 
-	switch track.Codec.Name {
-	case core.CodecH264:
-		sender.Handler = h264.RTPPay(1200, sender.Handler)
-		if track.Codec.IsRTP() {
-			sender.Handler = h264.RTPDepay(track.Codec, sender.Handler)
-		} else {
-			sender.Handler = h264.RepairAVCC(track.Codec, sender.Handler)
-		}
+	if media.Opaque {
+		// encrypted payload (E2EE) - send packets as is, without depacketization
+		c.congestion.SetOpaque()
+	} else {
+		switch track.Codec.Name {
+		case core.CodecH264:
+			sender.Handler = h264.RTPPay(1200, sender.Handler)
+			if track.Codec.IsRTP() {
+				sender.Handler = h264.RTPDepay(track.Codec, sender.Handler)
+			} else {
+				sender.Handler = h264.RepairAVCC(track.Codec, sender.Handler)
+			}
+
+		case core.CodecH265:
+			sender.Handler = h265.RTPPay(1200, sender.Handler)
+			if track.Codec.IsRTP() {
+				sender.Handler = h265.RTPDepay(track.Codec, sender.Handler)
+			} else {
+				sender.Handler = h265.RepairAVCC(track.Codec, sender.Handler)
+			}
 
-	case core.CodecH265:
-		sender.Handler = h265.RTPPay(1200, sender.Handler)
-		if track.Codec.IsRTP() {
-			sender.Handler = h265.RTPDepay(track.Codec, sender.Handler)
-		} else {
-			sender.Handler = h265.RepairAVCC(track.Codec, sender.Handler)
+		case core.CodecPCMA, core.CodecPCMU, core.CodecPCM, core.CodecPCML:
+			// Fix audio quality https://github.com/hamza-farouk/WebRTC/issues/500
+			// should be before ResampleToG711, because it will be called last
+			sender.Handler = pcm.RepackG711(false, sender.Handler)
+
+			if codec.ClockRate == 0 {
+				if codec.Name == core.CodecPCM || codec.Name == core.CodecPCML {
+					codec.Name = core.CodecPCMA
+				}
+				codec.ClockRate = 8000
+				sender.Handler = pcm.TranscodeHandler(codec, track.Codec, sender.Handler)
+			}
 		}
+	}
 
-	case core.CodecPCMA, core.CodecPCMU, core.CodecPCM, core.CodecPCML:
-		// Fix audio quality https://github.com/hamza-farouk/WebRTC/issues/500
-		// should be before ResampleToG711, because it will be called last
-		sender.Handler = pcm.RepackG711(false, sender.Handler)
+	if c.Mode == core.ModePassiveConsumer {
+		if media.Kind == core.KindVideo {
+			// handlers wrap depacketization, so they see packets in source track format
+			sender.Handler = c.congestion.Handler(track.Codec, sender.Handler)
 
-		if codec.ClockRate == 0 {
-			if codec.Name == core.CodecPCM || codec.Name == core.CodecPCML {
-				codec.Name = core.CodecPCMA
+			if media.Layer == core.LayerAuto {
+				c.simulcast = newSimulcast(track.Codec, sender.Handler)
+				sender.Handler = c.simulcast.Handler(track)
 			}
-			codec.ClockRate = 8000
-			sender.Handler = pcm.TranscodeHandler(codec, track.Codec, sender.Handler)
+		}
+
+		if tr := c.getTranseiver(media.ID); tr != nil {
+			go c.readRTCP(tr.Sender())
 		}
 	}
 
@@ -0,0 +1,65 @@
+package webrtc
+
+import (
+	"encoding/json"
+
+	"github.com/hamza-farouk/go2rtc/pkg/core"
+	"github.com/pion/webrtc/v4"
+)
+
+// addChannel - process JSON messages from data channel and fire them as *core.Message,
+// other messages will be fired with type "data"
+func (c *Conn) addChannel(channel *webrtc.DataChannel) {
+	c.channelsMu.Lock()
+	c.channels = append(c.channels, channel)
+	c.channelsMu.Unlock()
+
+	channel.OnMessage(func(msg webrtc.DataChannelMessage) {
+		c.Recv += len(msg.Data)
+
+		if msg.IsString {
+			var m core.Message
+			if err := json.Unmarshal(msg.Data, &m); err == nil && m.Type != "" {
+				c.Fire(&m)
+			} else {
+				c.Fire(&core.Message{Type: "data", Value: string(msg.Data)})
+			}
+		} else {
+			c.Fire(&core.Message{Type: "data", Value: msg.Data})
+		}
+	})
+
+	channel.OnClose(func() {
+		c.channelsMu.Lock()
+		for i, ch := range c.channels {
+			if ch == channel {
+				c.channels = append(c.channels[:i], c.channels[i+1:]...)
+				break
+			}
+		}
+		c.channelsMu.Unlock()
+	})
+}
+
+// SendMessage - send JSON message to all open data channels
+func (c *Conn) SendMessage(msg *core.Message) error {
+	b, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	c.channelsMu.Lock()
+	defer c.channelsMu.Unlock()
+
+	for _, channel := range c.channels {
+		if channel.ReadyState() != webrtc.DataChannelStateOpen {
+			continue
+		}
+		if err = channel.SendText(string(b)); err != nil {
+			return err
+		}
+		c.Send += len(b)
+	}
+
+	return nil
+}
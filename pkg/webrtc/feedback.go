@@ -0,0 +1,207 @@
+package webrtc
+
+import (
+	"sync"
+	"time"
+
+	"github.com/hamza-farouk/go2rtc/pkg/core"
+	"github.com/pion/rtcp"
+	"github.com/pion/rtp"
+	"github.com/pion/webrtc/v4"
+)
+
+// readRTCP - process RTCP feedback from viewer. Reading is also important
+// for pion interceptors: NACK responder retransmit packets from own send buffer.
+func (c *Conn) readRTCP(sender *webrtc.RTPSender) {
+	for {
+		packets, _, err := sender.ReadRTCP()
+		if err != nil {
+			return
+		}
+
+		for _, packet := range packets {
+			switch packet := packet.(type) {
+			case *rtcp.PictureLossIndication, *rtcp.FullIntraRequest:
+				// forward keyframe request to the stream producers
+				c.Fire(&core.Message{Type: "keyframe"})
+			case *rtcp.ReceiverEstimatedMaximumBitrate:
+				c.congestion.SetEstimate(packet.Bitrate)
+			case *rtcp.ReceiverReport:
+				for _, report := range packet.Reports {
+					c.congestion.SetLoss(report.FractionLost)
+				}
+			case *rtcp.TransportLayerCC:
+				if lost, total := twccLoss(packet); total > 0 {
+					c.congestion.SetLoss(uint8(lost * 255 / total))
+				}
+			case *rtcp.TransportLayerNack:
+				for _, pair := range packet.Nacks {
+					c.congestion.AddNACKs(len(pair.PacketList()))
+				}
+			}
+		}
+
+		if estimate, loss, ok := c.congestion.Check(); ok {
+			if c.congestion.Dropping() {
+				c.Fire(&core.Message{Type: "keyframe"})
+			}
+			if c.simulcast != nil {
+				c.simulcast.SelectLayer(estimate, loss)
+			}
+		}
+	}
+}
+
+// congestion - simple sender-side congestion response for viewer connection.
+// We can't change bitrate without transcoding, so when viewer link can't handle
+// the stream - drop video until next keyframe (and request it from producer).
+// So viewer recovers from clean keyframe instead of long freeze with retransmissions.
+type congestion struct {
+	estimate float32 // bitrate from REMB
+	loss     uint8   // fraction lost from RR or TWCC, 0-255
+	nacks    int
+
+	bytes    int // video bytes from last check
+	bitrate  int // video send bitrate
+	checked  time.Time
+	dropping bool
+
+	frames  int // sent video frames
+	dropped int // dropped video frames
+
+	opaque bool // encrypted or unknown video, keyframes can't be detected, so never drop
+
+	mu sync.Mutex
+}
+
+const congestionInterval = 2 * time.Second
+
+func (c *congestion) SetEstimate(bitrate float32) {
+	c.mu.Lock()
+	c.estimate = bitrate
+	c.mu.Unlock()
+}
+
+func (c *congestion) SetLoss(loss uint8) {
+	c.mu.Lock()
+	c.loss = loss
+	c.mu.Unlock()
+}
+
+func (c *congestion) AddNACKs(n int) {
+	c.mu.Lock()
+	c.nacks += n
+	c.mu.Unlock()
+}
+
+func (c *congestion) SetOpaque() {
+	c.mu.Lock()
+	c.opaque = true
+	c.mu.Unlock()
+}
+
+func (c *congestion) Frames() (sent, dropped int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.frames, c.dropped
+}
+
+func (c *congestion) Dropping() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.dropping
+}
+
+// Check - update state not more than once per interval
+func (c *congestion) Check() (estimate float32, loss uint8, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	if c.checked.IsZero() {
+		c.checked = now
+		return
+	}
+
+	seconds := now.Sub(c.checked).Seconds()
+	if seconds < congestionInterval.Seconds() {
+		return
+	}
+
+	c.bitrate = int(float64(c.bytes) * 8 / seconds)
+	c.bytes = 0
+	c.checked = now
+
+	// more than 20% lost or we send much more than viewer can receive
+	if !c.opaque && (c.loss > 51 || c.estimate > 0 && float32(c.bitrate) > c.estimate*1.5) {
+		c.dropping = true
+	}
+
+	return c.estimate, c.loss, true
+}
+
+// Handler - drop video packets until keyframe in dropping state
+// codec - source track codec, so packets can be RTP, AVCC or OBUs
+func (c *congestion) Handler(codec *core.Codec, handler core.HandlerFunc) core.HandlerFunc {
+	if !canDetectKeyframe(codec) {
+		c.SetOpaque()
+	}
+
+	// non RTP sources send one frame per packet
+	frame := !codec.IsRTP()
+
+	return func(packet *rtp.Packet) {
+		c.mu.Lock()
+		if c.dropping {
+			if !isKeyframe(codec, packet.Payload) {
+				if frame || packet.Marker {
+					c.dropped++
+				}
+				c.mu.Unlock()
+				return
+			}
+			c.dropping = false
+		}
+		if frame || packet.Marker {
+			c.frames++
+		}
+		c.bytes += len(packet.Payload)
+		c.mu.Unlock()
+
+		handler(packet)
+	}
+}
+
+// twccLoss - count not received packets from transport-wide feedback
+func twccLoss(packet *rtcp.TransportLayerCC) (lost, total int) {
+	total = int(packet.PacketStatusCount)
+
+	var n int
+	for _, chunk := range packet.PacketChunks {
+		switch chunk := chunk.(type) {
+		case *rtcp.RunLengthChunk:
+			if chunk.PacketStatusSymbol == rtcp.TypeTCCPacketNotReceived {
+				lost += int(chunk.RunLength)
+			}
+			n += int(chunk.RunLength)
+		case *rtcp.StatusVectorChunk:
+			for _, symbol := range chunk.SymbolList {
+				// last chunk may have padding symbols
+				if n >= total {
+					break
+				}
+				if symbol == rtcp.TypeTCCPacketNotReceived {
+					lost++
+				}
+				n++
+			}
+		}
+	}
+
+	// last run length chunk may be longer than packets count
+	if lost > total {
+		lost = total
+	}
+
+	return
+}
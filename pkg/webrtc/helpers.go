@@ -50,6 +50,18 @@ func UnmarshalMedias(descriptions []*sdp.MediaDescription) (medias []*core.Media
 			// skip non-media codecs to avoid confusing users in info and logs
 			media.Codecs = SkipNonMediaCodecs(media.Codecs)
 
+			// 5. Split simulcast media to separate media for each layer
+			if media.Direction == core.DirectionRecvonly {
+				if rids := SimulcastRIDs(md); rids != nil {
+					for _, rid := range rids {
+						layer := media.Clone()
+						layer.Layer = rid
+						medias = append(medias, layer)
+					}
+					continue
+				}
+			}
+
 			medias = append(medias, media)
 		}
 	}
@@ -57,6 +69,21 @@ func UnmarshalMedias(descriptions []*sdp.MediaDescription) (medias []*core.Media
 	return
 }
 
+// SimulcastRIDs - list of RIDs that remote peer will send, in the offer order
+func SimulcastRIDs(md *sdp.MediaDescription) (rids []string) {
+	for _, attr := range md.Attributes {
+		if attr.Key != "rid" {
+			continue
+		}
+		// a=rid:h send pt=96;max-width=1280
+		fields := strings.Fields(attr.Value)
+		if len(fields) >= 2 && fields[1] == "send" {
+			rids = append(rids, fields[0])
+		}
+	}
+	return
+}
+
 func SkipNonMediaCodecs(input []*core.Codec) (output []*core.Codec) {
 	for _, codec := range input {
 		switch codec.Name {
@@ -76,7 +103,8 @@ func SkipNonMediaCodecs(input []*core.Codec) (output []*core.Codec) {
 // so it can add resampling for PCMA/PCMU and repack for PCM/PCML
 func WithResampling(medias []*core.Media) []*core.Media {
 	for _, media := range medias {
-		if media.Kind != core.KindAudio || media.Direction != core.DirectionSendonly {
+		// encrypted audio can't be transcoded
+		if media.Kind != core.KindAudio || media.Direction != core.DirectionSendonly || media.Opaque {
 			continue
 		}
 
@@ -128,6 +156,18 @@ func WithResampling(medias []*core.Media) []*core.Media {
 	return medias
 }
 
+// SetOpaque - mark medias as end-to-end encrypted (insertable streams, SFrame).
+// Such medias are relayed only between WebRTC connections, without depacketization.
+// Simulcast auto switching is not possible, because keyframes can't be detected.
+func SetOpaque(medias []*core.Media) {
+	for _, media := range medias {
+		media.Opaque = true
+		if media.Layer == core.LayerAuto {
+			media.Layer = ""
+		}
+	}
+}
+
 func NewCandidate(network, address string) (string, error) {
 	i := strings.LastIndexByte(address, ':')
 	if i < 0 {
@@ -184,9 +224,19 @@ func LookupIP(address string) (string, error) {
 	return ips[0].String() + address[i:], nil
 }
 
-// GetPublicIP example from https://github.com/pion/stun
+// GetPublicIP - public IPv4 address
 func GetPublicIP() (net.IP, error) {
-	conn, err := net.Dial("udp", "stun.l.google.com:19302")
+	return getPublicIP("udp4")
+}
+
+// GetPublicIP6 - public IPv6 address, usually same as host global address
+func GetPublicIP6() (net.IP, error) {
+	return getPublicIP("udp6")
+}
+
+// getPublicIP example from https://github.com/pion/stun
+func getPublicIP(network string) (net.IP, error) {
+	conn, err := net.Dial(network, "stun.l.google.com:19302")
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,187 @@
+package webrtc
+
+import (
+	"sync"
+	"time"
+
+	"github.com/hamza-farouk/go2rtc/pkg/core"
+	"github.com/pion/rtcp"
+	"github.com/pion/rtp"
+	"github.com/pion/webrtc/v4"
+)
+
+const jitterSize = 512 // same as pion NACK generator buffer
+
+// jitterBuffer - reorder RTP packets by sequence number and wait for retransmissions
+// of lost packets (NACK sends pion interceptor) not longer than latency.
+// Buffer works on incoming packets, and timer flushes it when input pauses.
+type jitterBuffer struct {
+	latency time.Duration
+	handler func(packet *rtp.Packet)
+	onLoss  func() // unrecoverable loss
+
+	packets [jitterSize]*rtp.Packet
+	times   [jitterSize]time.Time
+	count   int
+
+	next    uint16 // next expected sequence number
+	started bool
+
+	timer  *time.Timer
+	closed bool
+	mu     sync.Mutex
+}
+
+func newJitterBuffer(latency time.Duration, handler func(packet *rtp.Packet), onLoss func()) *jitterBuffer {
+	return &jitterBuffer{latency: latency, handler: handler, onLoss: onLoss}
+}
+
+func (j *jitterBuffer) Push(packet *rtp.Packet) {
+	j.PushAt(packet, time.Now())
+}
+
+func (j *jitterBuffer) PushAt(packet *rtp.Packet, now time.Time) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if j.closed {
+		return
+	}
+
+	if !j.started {
+		j.next = packet.SequenceNumber
+		j.started = true
+	}
+
+	if diff := packet.SequenceNumber - j.next; diff >= jitterSize {
+		if j.next-packet.SequenceNumber <= jitterSize {
+			return // late or duplicate packet
+		}
+
+		// too big gap in any direction (ex. source restart or SSRC reset) -
+		// output all and start from this packet
+		j.skip(jitterSize)
+		j.next = packet.SequenceNumber
+		j.onLoss()
+	}
+
+	i := packet.SequenceNumber % jitterSize
+	if j.packets[i] == nil {
+		j.packets[i] = packet
+		j.times[i] = now
+		j.count++
+	}
+
+	j.flush()
+	j.expire(now)
+}
+
+// Close - stop flush timer, next packets will be ignored
+func (j *jitterBuffer) Close() {
+	j.mu.Lock()
+	j.closed = true
+	if j.timer != nil {
+		j.timer.Stop()
+	}
+	j.mu.Unlock()
+}
+
+// expire - wait lost packet not longer than latency from oldest buffered packet,
+// and schedule timer for the rest of buffered packets
+func (j *jitterBuffer) expire(now time.Time) {
+	for j.count > 0 && now.Sub(j.oldest()) >= j.latency {
+		j.skipLost()
+		j.onLoss()
+		j.flush()
+	}
+
+	if j.count == 0 {
+		if j.timer != nil {
+			j.timer.Stop()
+		}
+		return
+	}
+
+	d := j.oldest().Add(j.latency).Sub(now)
+	if j.timer == nil {
+		j.timer = time.AfterFunc(d, j.onTimer)
+	} else {
+		j.timer.Reset(d)
+	}
+}
+
+func (j *jitterBuffer) onTimer() {
+	j.mu.Lock()
+	if !j.closed {
+		j.expire(time.Now())
+	}
+	j.mu.Unlock()
+}
+
+// flush - output all sequential packets
+func (j *jitterBuffer) flush() {
+	for {
+		i := j.next % jitterSize
+		packet := j.packets[i]
+		if packet == nil {
+			return
+		}
+		j.packets[i] = nil
+		j.count--
+		j.next++
+		j.handler(packet)
+	}
+}
+
+// skipLost - move next to first buffered packet after gap
+func (j *jitterBuffer) skipLost() {
+	for j.packets[j.next%jitterSize] == nil {
+		j.next++
+	}
+}
+
+// skip - output buffered packets in sequence order and clear the buffer
+func (j *jitterBuffer) skip(n int) {
+	for ; n > 0 && j.count > 0; n-- {
+		if packet := j.packets[j.next%jitterSize]; packet != nil {
+			j.packets[j.next%jitterSize] = nil
+			j.count--
+			j.handler(packet)
+		}
+		j.next++
+	}
+}
+
+func (j *jitterBuffer) oldest() (ts time.Time) {
+	for n := uint16(0); n < jitterSize; n++ {
+		i := (j.next + n) % jitterSize
+		if j.packets[i] != nil && (ts.IsZero() || j.times[i].Before(ts)) {
+			ts = j.times[i]
+		}
+	}
+	return
+}
+
+// SetJitter - enable jitter buffer with NACK for producer tracks, zero - disabled.
+// Bigger latency - more chances to receive retransmitted packets.
+func (c *Conn) SetJitter(latency time.Duration) {
+	c.jitter.Store(int64(latency))
+}
+
+func (c *Conn) newJitterBuffer(remote *webrtc.TrackRemote, track *core.Receiver, latency time.Duration) *jitterBuffer {
+	var lossTS time.Time
+
+	return newJitterBuffer(latency, func(packet *rtp.Packet) {
+		if len(packet.Payload) > 0 {
+			track.WriteRTP(packet)
+		}
+	}, func() {
+		// request keyframe after unrecoverable loss, but not too often
+		if remote.Kind() != webrtc.RTPCodecTypeVideo || time.Since(lossTS) < time.Second {
+			return
+		}
+		lossTS = time.Now()
+		pkts := []rtcp.Packet{&rtcp.PictureLossIndication{MediaSSRC: uint32(remote.SSRC())}}
+		_ = c.pc.WriteRTCP(pkts)
+	})
+}
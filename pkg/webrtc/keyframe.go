@@ -0,0 +1,132 @@
+package webrtc
+
+import (
+	"github.com/hamza-farouk/go2rtc/pkg/av1"
+	"github.com/hamza-farouk/go2rtc/pkg/core"
+	"github.com/hamza-farouk/go2rtc/pkg/h264"
+	"github.com/hamza-farouk/go2rtc/pkg/h265"
+)
+
+// canDetectKeyframe - codecs and packetization supported by isKeyframe
+func canDetectKeyframe(codec *core.Codec) bool {
+	switch codec.Name {
+	case core.CodecH264, core.CodecH265, core.CodecAV1:
+		return true
+	case core.CodecVP8, core.CodecVP9:
+		return codec.IsRTP()
+	}
+	return false
+}
+
+// isKeyframe - check if packet is start of keyframe: RTP payload for RTP codecs,
+// AVCC or OBUs for other sources (RTMP, HLS, pipes)
+func isKeyframe(codec *core.Codec, payload []byte) bool {
+	if !codec.IsRTP() {
+		switch codec.Name {
+		case core.CodecH264:
+			return len(payload) > 4 && h264.IsKeyframe(payload)
+		case core.CodecH265:
+			return len(payload) > 5 && h265.IsKeyframe(payload)
+		case core.CodecAV1:
+			return len(payload) > 0 && av1.IsKeyframe(payload)
+		}
+		return false
+	}
+
+	switch codec.Name {
+	case core.CodecH264, core.CodecH265:
+		return isKeyframeRTP(codec.Name, payload)
+	case core.CodecVP8:
+		return isKeyframeVP8(payload)
+	case core.CodecVP9:
+		return isKeyframeVP9(payload)
+	case core.CodecAV1:
+		// aggregation header: Z|Y|W|W|N, N - first packet of coded video sequence
+		return len(payload) > 0 && payload[0]&0x08 != 0
+	}
+
+	return false
+}
+
+// isKeyframeRTP - check if RTP packet is start of keyframe (parameter sets or IDR)
+func isKeyframeRTP(codec string, payload []byte) bool {
+	if len(payload) < 4 {
+		return false
+	}
+
+	switch codec {
+	case core.CodecH264:
+		switch payload[0] & 0x1F {
+		case h264.NALUTypeIFrame, h264.NALUTypeSPS:
+			return true
+		case 24: // STAP-A
+			t := payload[3] & 0x1F
+			return t == h264.NALUTypeIFrame || t == h264.NALUTypeSPS
+		case 28: // FU-A
+			return payload[1]&0x80 != 0 && payload[1]&0x1F == h264.NALUTypeIFrame
+		}
+
+	case core.CodecH265:
+		switch t := (payload[0] >> 1) & 0x3F; t {
+		case h265.NALUTypeIFrame, h265.NALUTypeIFrame2, h265.NALUTypeIFrame3, h265.NALUTypeVPS:
+			return true
+		case 48: // AP
+			if len(payload) > 4 {
+				t = (payload[4] >> 1) & 0x3F
+				return t == h265.NALUTypeVPS || t >= h265.NALUTypeIFrame && t <= h265.NALUTypeIFrame3
+			}
+		case h265.NALUTypeFU:
+			t = payload[2] & 0x3F
+			return payload[2]&0x80 != 0 && t >= h265.NALUTypeIFrame && t <= h265.NALUTypeIFrame3
+		}
+	}
+
+	return false
+}
+
+// isKeyframeVP8 - first packet of frame with key frame flag in VP8 payload header
+// https://www.rfc-editor.org/rfc/rfc7741#section-4.2
+func isKeyframeVP8(payload []byte) bool {
+	if len(payload) < 1 {
+		return false
+	}
+
+	// descriptor: X|R|N|S|R|PID, S - start of partition, PID - partition index
+	if payload[0]&0x10 == 0 || payload[0]&0x07 != 0 {
+		return false
+	}
+
+	i := 1
+	if payload[0]&0x80 != 0 { // X - extended control bits
+		if len(payload) < 2 {
+			return false
+		}
+		x := payload[1]
+		i++
+		if x&0x80 != 0 { // I - PictureID
+			if len(payload) <= i {
+				return false
+			}
+			if payload[i]&0x80 != 0 { // M - 15 bit PictureID
+				i++
+			}
+			i++
+		}
+		if x&0x40 != 0 { // L - TL0PICIDX
+			i++
+		}
+		if x&0x30 != 0 { // T or K - TID/KEYIDX
+			i++
+		}
+	}
+
+	// payload header: size0|H|VER|P, P = 0 for key frame
+	return len(payload) > i && payload[i]&0x01 == 0
+}
+
+// isKeyframeVP9 - start of frame which is not inter-predicted
+// https://www.rfc-editor.org/rfc/rfc9628#section-4.2
+func isKeyframeVP9(payload []byte) bool {
+	// descriptor: I|P|L|F|B|E|V|Z, P - inter-picture predicted, B - start of frame
+	return len(payload) > 0 && payload[0]&0x40 == 0 && payload[0]&0x08 != 0
+}
@@ -2,6 +2,7 @@ package webrtc
 
 import (
 	"github.com/hamza-farouk/go2rtc/pkg/core"
+	"github.com/pion/rtcp"
 	"github.com/pion/webrtc/v4"
 )
 
@@ -43,6 +44,23 @@ func (c *Conn) GetTrack(media *core.Media, codec *core.Codec) (*core.Receiver, e
 	return track, nil
 }
 
+// RequestKeyframe - send PLI to remote peer for all video tracks
+func (c *Conn) RequestKeyframe() error {
+	var pkts []rtcp.Packet
+	for _, tr := range c.pc.GetTransceivers() {
+		if tr.Kind() != webrtc.RTPCodecTypeVideo || tr.Receiver() == nil {
+			continue
+		}
+		for _, track := range tr.Receiver().Tracks() {
+			pkts = append(pkts, &rtcp.PictureLossIndication{MediaSSRC: uint32(track.SSRC())})
+		}
+	}
+	if pkts == nil {
+		return nil
+	}
+	return c.pc.WriteRTCP(pkts)
+}
+
 func (c *Conn) Start() error {
 	c.closed.Wait()
 	return nil
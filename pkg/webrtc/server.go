@@ -16,6 +16,11 @@ func (c *Conn) SetOffer(offer string) (err error) {
 
 	// create transceivers with opposite direction
 	for _, md := range sd.MediaDescriptions {
+		// remote peer data channel will be added with OnDataChannel
+		if md.MediaName.Media == "application" {
+			continue
+		}
+
 		var mid string
 		var tr *webrtc.RTPTransceiver
 		for _, attr := range md.Attributes {
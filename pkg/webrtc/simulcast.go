@@ -0,0 +1,130 @@
+package webrtc
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/hamza-farouk/go2rtc/pkg/core"
+	"github.com/pion/rtp"
+)
+
+// simulcast - consumer track that receives all simulcast layers of the producer
+// and pass only one of them. Active layer selected by viewer feedback.
+// Switching happens only on keyframe.
+type simulcast struct {
+	codec   *core.Codec
+	handler core.HandlerFunc
+
+	layers []*layer
+	active *layer
+	target *layer
+
+	offset uint32 // timestamp offset for smooth switching
+	last   uint32 // last output timestamp
+
+	checked time.Time
+
+	mu sync.Mutex
+}
+
+type layer struct {
+	name    string
+	track   *core.Receiver
+	bytes   int // track bytes on last check
+	bitrate int
+}
+
+func newSimulcast(codec *core.Codec, handler core.HandlerFunc) *simulcast {
+	return &simulcast{codec: codec, handler: handler, checked: time.Now()}
+}
+
+// Handler - input for each layer sender
+func (s *simulcast) Handler(track *core.Receiver) core.HandlerFunc {
+	l := &layer{track: track}
+	if track.Media != nil {
+		l.name = track.Media.Layer
+	}
+
+	s.mu.Lock()
+	s.layers = append(s.layers, l)
+	if s.active == nil {
+		s.active = l
+	}
+	s.mu.Unlock()
+
+	return func(packet *rtp.Packet) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		if l == s.target && l != s.active && isKeyframe(s.codec, packet.Payload) {
+			// continue timestamps from previous layer with some small step
+			s.offset = s.last + s.codec.ClockRate/30 - packet.Timestamp
+			s.active = l
+			s.target = nil
+		}
+
+		if l != s.active {
+			return
+		}
+
+		clone := *packet
+		clone.Timestamp += s.offset
+		s.last = clone.Timestamp
+
+		s.handler(&clone)
+	}
+}
+
+// SelectLayer - set target layer by estimated bitrate and loss from viewer feedback
+func (s *simulcast) SelectLayer(estimate float32, loss uint8) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	seconds := now.Sub(s.checked).Seconds()
+	s.checked = now
+
+	for _, l := range s.layers {
+		bytes := l.track.Bytes
+		l.bitrate = int(float64(bytes-l.bytes) * 8 / seconds)
+		l.bytes = bytes
+	}
+
+	if len(s.layers) < 2 {
+		return
+	}
+
+	// sort layers from lowest to highest bitrate
+	layers := make([]*layer, len(s.layers))
+	copy(layers, s.layers)
+	sort.Slice(layers, func(i, j int) bool {
+		return layers[i].bitrate < layers[j].bitrate
+	})
+
+	i := core.Index(layers, s.active)
+
+	switch {
+	case loss > 25: // more than 10% lost - step down
+		if i > 0 {
+			i--
+		}
+	case estimate > 0:
+		// highest layer that fits into estimated bitrate with some reserve
+		for i = len(layers) - 1; i > 0; i-- {
+			if float32(layers[i].bitrate)*1.2 < estimate {
+				break
+			}
+		}
+	case loss == 0: // no estimate and no loss - step up
+		if i < len(layers)-1 {
+			i++
+		}
+	}
+
+	if layers[i] != s.active {
+		s.target = layers[i]
+	} else {
+		s.target = nil
+	}
+}
@@ -0,0 +1,374 @@
+package webrtc
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pion/interceptor"
+	"github.com/pion/interceptor/pkg/stats"
+	"github.com/pion/webrtc/v4"
+)
+
+// Stats - connection report from pion GetStats and RTP stats interceptor
+type Stats struct {
+	ID    uint32 `json:"id"`
+	State string `json:"state"`
+
+	CandidatePair *CandidatePairStats `json:"candidate_pair,omitempty"`
+	Tracks        []*TrackStats       `json:"tracks,omitempty"`
+}
+
+type CandidatePairStats struct {
+	Local  string `json:"local"`  // ip:port protocol type
+	Remote string `json:"remote"` // ip:port protocol type
+
+	RTT       float64 `json:"rtt_ms"`
+	BytesRecv uint64  `json:"bytes_recv"`
+	BytesSend uint64  `json:"bytes_send"`
+
+	AvailableBitrate float64 `json:"available_bitrate,omitempty"`
+}
+
+// TrackStats - inbound (from remote peer) or outbound (to remote peer) RTP stream
+type TrackStats struct {
+	Direction string `json:"direction"` // inbound, outbound
+	Kind      string `json:"kind"`
+	Mid       string `json:"mid"`
+	RID       string `json:"rid,omitempty"`
+	SSRC      uint32 `json:"ssrc"`
+	Codec     string `json:"codec"` // H264/90000, opus/48000/2
+
+	Packets      uint64  `json:"packets"`
+	PacketsLost  int64   `json:"packets_lost"`
+	FractionLost float64 `json:"fraction_lost,omitempty"` // from remote receiver reports, 0-1
+	Jitter       float64 `json:"jitter,omitempty"`
+	RTT          float64 `json:"rtt_ms,omitempty"`
+
+	Bytes   uint64 `json:"bytes"`
+	Bitrate int    `json:"bitrate"` // bits per second from previous report
+
+	NACK uint32 `json:"nack"`
+	PLI  uint32 `json:"pli"`
+	FIR  uint32 `json:"fir"`
+
+	FramesSent    int `json:"frames_sent,omitempty"`
+	FramesDropped int `json:"frames_dropped,omitempty"` // by congestion control
+}
+
+// StatsSummary - short info for streams list
+type StatsSummary struct {
+	RTT         float64 `json:"rtt_ms"`
+	PacketsLost int64   `json:"packets_lost"`
+	NACK        uint32  `json:"nack"`
+	PLI         uint32  `json:"pli"`
+	Bitrate     int     `json:"bitrate"`
+}
+
+// GetStats - collect connection stats. Bitrate is calculated from previous call,
+// but not more often than once per second.
+func (c *Conn) GetStats() *Stats {
+	return c.getStats(&c.stats)
+}
+
+func (c *Conn) getStats(rates *connStats) *Stats {
+	report := c.pc.GetStats()
+
+	s := &Stats{ID: c.ID, State: c.pc.ConnectionState().String()}
+	s.CandidatePair = candidatePairStats(report)
+
+	getter := c.statsGetter()
+
+	for _, tr := range c.pc.GetTransceivers() {
+		if receiver := tr.Receiver(); receiver != nil {
+			for _, remote := range receiver.Tracks() {
+				if remote.SSRC() == 0 {
+					continue
+				}
+				track := &TrackStats{
+					Direction: "inbound",
+					Kind:      remote.Kind().String(),
+					Mid:       tr.Mid(),
+					RID:       remote.RID(),
+					SSRC:      uint32(remote.SSRC()),
+					Codec:     codecString(remote.Codec().RTPCodecCapability),
+				}
+				if getter != nil {
+					if rtp := getter.Get(track.SSRC); rtp != nil {
+						inbound := rtp.InboundRTPStreamStats
+						track.Packets = inbound.PacketsReceived
+						track.PacketsLost = inbound.PacketsLost
+						track.Jitter = inbound.Jitter
+						track.Bytes = inbound.BytesReceived
+						track.NACK = inbound.NACKCount
+						track.PLI = inbound.PLICount
+						track.FIR = inbound.FIRCount
+					}
+				}
+				s.Tracks = append(s.Tracks, track)
+			}
+		}
+
+		if sender := tr.Sender(); sender != nil && sender.Track() != nil {
+			params := sender.GetParameters()
+			if len(params.Encodings) == 0 || params.Encodings[0].SSRC == 0 {
+				continue
+			}
+			track := &TrackStats{
+				Direction: "outbound",
+				Kind:      sender.Track().Kind().String(),
+				Mid:       tr.Mid(),
+				SSRC:      uint32(params.Encodings[0].SSRC),
+			}
+			if len(params.Codecs) > 0 {
+				track.Codec = codecString(params.Codecs[0].RTPCodecCapability)
+			}
+			if getter != nil {
+				if rtp := getter.Get(track.SSRC); rtp != nil {
+					outbound := rtp.OutboundRTPStreamStats
+					remote := rtp.RemoteInboundRTPStreamStats
+					track.Packets = outbound.PacketsSent
+					track.Bytes = outbound.BytesSent
+					track.NACK = outbound.NACKCount
+					track.PLI = outbound.PLICount
+					track.FIR = outbound.FIRCount
+					track.PacketsLost = remote.PacketsLost
+					track.FractionLost = remote.FractionLost
+					track.Jitter = remote.Jitter
+					track.RTT = float64(remote.RoundTripTime) / float64(time.Millisecond)
+				}
+			}
+			if track.Kind == "video" {
+				track.FramesSent, track.FramesDropped = c.congestion.Frames()
+			}
+			s.Tracks = append(s.Tracks, track)
+		}
+	}
+
+	rates.bitrate(s.Tracks)
+
+	return s
+}
+
+// statsInterval - update period of short stats for streams list
+const statsInterval = 5 * time.Second
+
+// GetStatsSummary - short stats, updated by timer while connection is connected,
+// nil before first update
+func (c *Conn) GetStatsSummary() *StatsSummary {
+	return c.summary.Load()
+}
+
+// statsLoop - update short stats with own bitrate state, so frequent streams list
+// requests don't call pion GetStats and don't change bitrate of GetStats
+func (c *Conn) statsLoop() {
+	var rates connStats
+
+	ticker := time.NewTicker(statsInterval)
+	defer ticker.Stop()
+
+	for {
+		c.summary.Store(c.statsSummary(&rates))
+
+		<-ticker.C
+
+		if c.pc.ConnectionState() != webrtc.PeerConnectionStateConnected {
+			c.summary.Store(nil)
+			return
+		}
+	}
+}
+
+func (c *Conn) statsSummary(rates *connStats) *StatsSummary {
+	s := c.getStats(rates)
+
+	summary := &StatsSummary{}
+	if s.CandidatePair != nil {
+		summary.RTT = s.CandidatePair.RTT
+	}
+	for _, track := range s.Tracks {
+		summary.PacketsLost += track.PacketsLost
+		summary.NACK += track.NACK
+		summary.PLI += track.PLI
+		summary.Bitrate += track.Bitrate
+	}
+	return summary
+}
+
+// statsGetter - RTP stats interceptor of this connection
+func (c *Conn) statsGetter() stats.Getter {
+	statsMu.Lock()
+	defer statsMu.Unlock()
+
+	if getter, ok := statsGetters[c.pc]; ok {
+		return getter
+	}
+	return nil
+}
+
+type connStats struct {
+	ts    time.Time
+	bytes map[uint32]uint64
+	rates map[uint32]int
+
+	mu sync.Mutex
+}
+
+func (s *connStats) bitrate(tracks []*TrackStats) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if seconds := now.Sub(s.ts).Seconds(); seconds >= 1 {
+		rates := make(map[uint32]int, len(tracks))
+		for _, track := range tracks {
+			if bytes, ok := s.bytes[track.SSRC]; ok && track.Bytes >= bytes {
+				rates[track.SSRC] = int(float64(track.Bytes-bytes) * 8 / seconds)
+			}
+		}
+
+		s.bytes = make(map[uint32]uint64, len(tracks))
+		for _, track := range tracks {
+			s.bytes[track.SSRC] = track.Bytes
+		}
+		s.rates = rates
+		s.ts = now
+	}
+
+	for _, track := range tracks {
+		track.Bitrate = s.rates[track.SSRC]
+	}
+}
+
+func candidatePairStats(report webrtc.StatsReport) *CandidatePairStats {
+	var pair webrtc.ICECandidatePairStats
+
+	for _, v := range report {
+		if transport, ok := v.(webrtc.TransportStats); ok && transport.SelectedCandidatePairID != "" {
+			pair, _ = report[transport.SelectedCandidatePairID].(webrtc.ICECandidatePairStats)
+			break
+		}
+	}
+
+	// transport stats may not have selected pair yet
+	if pair.ID == "" {
+		for _, v := range report {
+			if v, ok := v.(webrtc.ICECandidatePairStats); ok && v.Nominated {
+				pair = v
+				break
+			}
+		}
+	}
+
+	if pair.ID == "" {
+		return nil
+	}
+
+	return &CandidatePairStats{
+		Local:            candidateString(report, pair.LocalCandidateID),
+		Remote:           candidateString(report, pair.RemoteCandidateID),
+		RTT:              pair.CurrentRoundTripTime * 1000,
+		BytesRecv:        pair.BytesReceived,
+		BytesSend:        pair.BytesSent,
+		AvailableBitrate: pair.AvailableOutgoingBitrate,
+	}
+}
+
+func candidateString(report webrtc.StatsReport, id string) string {
+	candidate, ok := report[id].(webrtc.ICECandidateStats)
+	if !ok {
+		return ""
+	}
+	return fmt.Sprintf(
+		"%s:%d %s %s", sanitizeIP6(candidate.IP), candidate.Port, candidate.Protocol, candidate.CandidateType,
+	)
+}
+
+func codecString(codec webrtc.RTPCodecCapability) string {
+	_, name, _ := strings.Cut(codec.MimeType, "/")
+	s := fmt.Sprintf("%s/%d", name, codec.ClockRate)
+	if codec.Channels > 1 {
+		s += fmt.Sprintf("/%d", codec.Channels)
+	}
+	return s
+}
+
+// statsGetters - RTP stats interceptors of all alive PeerConnections
+var statsGetters = map[*webrtc.PeerConnection]*statsInterceptor{}
+var statsMu sync.Mutex
+
+// statsBuilt - last interceptor built by statsFactory, protected by buildMu
+var statsBuilt *statsInterceptor
+var buildMu sync.Mutex
+
+// NewPeerConnection - create PeerConnection and attach RTP stats interceptor to it.
+// Pion builds interceptors inside NewPeerConnection with the same empty ID,
+// so builds are serialized to get the interceptor of this PeerConnection.
+func NewPeerConnection(api *webrtc.API, conf webrtc.Configuration) (*webrtc.PeerConnection, error) {
+	buildMu.Lock()
+	pc, err := api.NewPeerConnection(conf)
+	s := statsBuilt
+	statsBuilt = nil
+	buildMu.Unlock()
+
+	if err != nil {
+		return nil, err
+	}
+
+	if s != nil {
+		statsMu.Lock()
+		if !s.closed {
+			s.pc = pc
+			statsGetters[pc] = s
+		}
+		statsMu.Unlock()
+	}
+
+	return pc, nil
+}
+
+// statsFactory - pion stats interceptor factory with registration of interceptors
+type statsFactory struct {
+	factory *stats.InterceptorFactory
+}
+
+func newStatsFactory() (*statsFactory, error) {
+	factory, err := stats.NewInterceptor()
+	if err != nil {
+		return nil, err
+	}
+	return &statsFactory{factory: factory}, nil
+}
+
+func (f *statsFactory) NewInterceptor(id string) (interceptor.Interceptor, error) {
+	i, err := f.factory.NewInterceptor(id)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &statsInterceptor{Interceptor: i.(*stats.Interceptor)}
+
+	// called from NewPeerConnection under buildMu
+	statsBuilt = s
+
+	return s, nil
+}
+
+type statsInterceptor struct {
+	*stats.Interceptor
+
+	pc     *webrtc.PeerConnection
+	closed bool
+}
+
+func (s *statsInterceptor) Close() error {
+	statsMu.Lock()
+	s.closed = true
+	if s.pc != nil {
+		delete(statsGetters, s.pc)
+	}
+	statsMu.Unlock()
+
+	return s.Interceptor.Close()
+}
@@ -0,0 +1,146 @@
+package webrtc
+
+import (
+	"strings"
+
+	"github.com/pion/sdp/v3"
+	"github.com/pion/webrtc/v4"
+)
+
+// MimeSDPFrag - trickle ICE and ICE restart for WHIP/WHEP
+// https://www.rfc-editor.org/rfc/rfc8840
+const MimeSDPFrag = "application/trickle-ice-sdpfrag"
+
+type Fragment struct {
+	Ufrag      string
+	Pwd        string
+	MediaName  string // m= line value without prefix
+	Mid        string
+	Candidates []string // candidate values without "a=" prefix
+}
+
+func ParseFragment(s string) *Fragment {
+	f := &Fragment{}
+	for _, line := range strings.Split(s, "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(line, "a=ice-ufrag:"):
+			f.Ufrag = line[len("a=ice-ufrag:"):]
+		case strings.HasPrefix(line, "a=ice-pwd:"):
+			f.Pwd = line[len("a=ice-pwd:"):]
+		case strings.HasPrefix(line, "m="):
+			if f.MediaName == "" {
+				f.MediaName = line[2:]
+			}
+		case strings.HasPrefix(line, "a=mid:"):
+			if f.Mid == "" {
+				f.Mid = line[len("a=mid:"):]
+			}
+		case strings.HasPrefix(line, "a=candidate:"):
+			f.Candidates = append(f.Candidates, line[2:])
+		}
+	}
+	return f
+}
+
+func (f *Fragment) Marshal() string {
+	s := "a=ice-ufrag:" + f.Ufrag + "\r\na=ice-pwd:" + f.Pwd + "\r\n"
+	if f.MediaName != "" {
+		s += "m=" + f.MediaName + "\r\n"
+	}
+	if f.Mid != "" {
+		s += "a=mid:" + f.Mid + "\r\n"
+	}
+	for _, candidate := range f.Candidates {
+		s += "a=" + candidate + "\r\n"
+	}
+	return s + "a=end-of-candidates\r\n"
+}
+
+// LocalICE - local ICE credentials from current local description
+func (c *Conn) LocalICE() (ufrag, pwd string) {
+	return iceCredentials(c.pc.CurrentLocalDescription())
+}
+
+// RemoteICE - remote ICE credentials from current remote description
+func (c *Conn) RemoteICE() (ufrag, pwd string) {
+	return iceCredentials(c.pc.CurrentRemoteDescription())
+}
+
+// RestartICE - process ICE restart from remote peer with new credentials,
+// returns fragment with new local credentials and candidates
+func (c *Conn) RestartICE(ufrag, pwd string, candidates []string, filter func(*webrtc.ICECandidate) bool) (*Fragment, error) {
+	sd := &sdp.SessionDescription{}
+	if err := sd.Unmarshal([]byte(c.offer)); err != nil {
+		return nil, err
+	}
+
+	replaceCredentials(sd.Attributes, ufrag, pwd)
+	for _, md := range sd.MediaDescriptions {
+		replaceCredentials(md.Attributes, ufrag, pwd)
+	}
+
+	b, err := sd.Marshal()
+	if err != nil {
+		return nil, err
+	}
+
+	c.offer = string(b)
+
+	answer, err := c.GetCompleteAnswer(candidates, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	sd = &sdp.SessionDescription{}
+	if err = sd.Unmarshal([]byte(answer)); err != nil {
+		return nil, err
+	}
+
+	f := &Fragment{}
+	f.Ufrag, f.Pwd = iceCredentials(&webrtc.SessionDescription{SDP: answer})
+
+	if len(sd.MediaDescriptions) > 0 {
+		md := sd.MediaDescriptions[0]
+		f.MediaName = md.MediaName.String()
+		f.Mid, _ = md.Attribute("mid")
+		for _, attr := range md.Attributes {
+			if attr.Key == "candidate" {
+				f.Candidates = append(f.Candidates, attr.String())
+			}
+		}
+	}
+
+	return f, nil
+}
+
+func iceCredentials(desc *webrtc.SessionDescription) (ufrag, pwd string) {
+	if desc == nil {
+		return
+	}
+
+	sd := &sdp.SessionDescription{}
+	if err := sd.Unmarshal([]byte(desc.SDP)); err != nil {
+		return
+	}
+
+	// credentials can be on session level or on media level (same for all medias with BUNDLE)
+	ufrag, _ = sd.Attribute("ice-ufrag")
+	pwd, _ = sd.Attribute("ice-pwd")
+	if ufrag == "" && len(sd.MediaDescriptions) > 0 {
+		ufrag, _ = sd.MediaDescriptions[0].Attribute("ice-ufrag")
+		pwd, _ = sd.MediaDescriptions[0].Attribute("ice-pwd")
+	}
+	return
+}
+
+func replaceCredentials(attrs []sdp.Attribute, ufrag, pwd string) {
+	for i := range attrs {
+		switch attrs[i].Key {
+		case "ice-ufrag":
+			attrs[i].Value = ufrag
+		case "ice-pwd":
+			attrs[i].Value = pwd
+		}
+	}
+}
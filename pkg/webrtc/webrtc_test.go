@@ -1,8 +1,14 @@
 package webrtc
 
 import (
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/hamza-farouk/go2rtc/pkg/core"
+	"github.com/pion/rtcp"
+	"github.com/pion/rtp"
+	"github.com/pion/sdp/v3"
 	"github.com/pion/webrtc/v4"
 	"github.com/stretchr/testify/require"
 )
@@ -69,3 +75,351 @@ a=fingerprint:sha-256 A2:93:53:50:E4:2F:C5:4E:DF:7C:70:99:5A:A7:39:50:1A:63:E5:B
 	_, err = conn.GetAnswer()
 	require.Nil(t, err)
 }
+
+func TestFragment(t *testing.T) {
+	// https://www.rfc-editor.org/rfc/rfc9725#section-4.3.1
+	s := "a=ice-ufrag:EsAw\r\na=ice-pwd:P2uYro0UCOQ4zxjKXaWCBui1\r\nm=audio 9 UDP/TLS/RTP/SAVPF 111\r\na=mid:0\r\na=candidate:1387637174 1 udp 2122260223 192.0.2.1 61764 typ host generation 0 ufrag EsAw network-id 1\r\na=end-of-candidates\r\n"
+
+	f := ParseFragment(s)
+	require.Equal(t, "EsAw", f.Ufrag)
+	require.Equal(t, "P2uYro0UCOQ4zxjKXaWCBui1", f.Pwd)
+	require.Equal(t, "0", f.Mid)
+	require.Equal(t, []string{"candidate:1387637174 1 udp 2122260223 192.0.2.1 61764 typ host generation 0 ufrag EsAw network-id 1"}, f.Candidates)
+	require.Equal(t, s, f.Marshal())
+}
+
+func TestSimulcast(t *testing.T) {
+	offer := `v=0
+o=- 1 1 IN IP4 0.0.0.0
+s=-
+t=0 0
+m=video 9 UDP/TLS/RTP/SAVPF 96
+c=IN IP4 0.0.0.0
+a=mid:0
+a=sendonly
+a=rtpmap:96 H264/90000
+a=rid:h send
+a=rid:l send
+a=simulcast:send h;l
+`
+	sd := &sdp.SessionDescription{}
+	require.Nil(t, sd.Unmarshal([]byte(offer)))
+
+	medias := UnmarshalMedias(sd.MediaDescriptions)
+	require.Len(t, medias, 2)
+	require.Equal(t, "h", medias[0].Layer)
+	require.Equal(t, "l", medias[1].Layer)
+	require.Equal(t, core.DirectionRecvonly, medias[1].Direction)
+
+	cons := &core.Media{
+		Kind: core.KindVideo, Direction: core.DirectionSendonly,
+		Codecs: []*core.Codec{{Name: core.CodecH264}},
+	}
+
+	// without layer - match any layer
+	codec, _ := medias[1].MatchMedia(cons)
+	require.NotNil(t, codec)
+
+	cons.Layer = "h"
+	codec, _ = medias[1].MatchMedia(cons)
+	require.Nil(t, codec)
+	codec, _ = medias[0].MatchMedia(cons)
+	require.NotNil(t, codec)
+}
+
+func TestSimulcastSwitch(t *testing.T) {
+	var output []uint32
+	s := newSimulcast(
+		&core.Codec{Name: core.CodecH264, ClockRate: 90000},
+		func(packet *rtp.Packet) { output = append(output, packet.Timestamp) },
+	)
+
+	high := s.Handler(core.NewReceiver(nil, nil))
+	low := s.Handler(core.NewReceiver(nil, nil))
+
+	pframe := []byte{0x41, 0, 0, 0}
+	iframe := []byte{0x65, 0, 0, 0}
+
+	high(&rtp.Packet{Header: rtp.Header{Timestamp: 1000}, Payload: pframe})
+	low(&rtp.Packet{Header: rtp.Header{Timestamp: 5000}, Payload: iframe})
+	require.Equal(t, []uint32{1000}, output) // first layer is active
+
+	s.target = s.layers[1]
+	low(&rtp.Packet{Header: rtp.Header{Timestamp: 5000}, Payload: pframe}) // wait keyframe
+	high(&rtp.Packet{Header: rtp.Header{Timestamp: 2000}, Payload: pframe})
+	low(&rtp.Packet{Header: rtp.Header{Timestamp: 8000}, Payload: iframe})
+	high(&rtp.Packet{Header: rtp.Header{Timestamp: 3000}, Payload: pframe})
+	low(&rtp.Packet{Header: rtp.Header{Timestamp: 11000}, Payload: pframe})
+	require.Equal(t, []uint32{1000, 2000, 5000, 8000}, output)
+}
+
+func TestCongestion(t *testing.T) {
+	var output int
+	cc := &congestion{}
+	handler := cc.Handler(
+		&core.Codec{Name: core.CodecH264}, func(*rtp.Packet) { output++ },
+	)
+
+	pframe := &rtp.Packet{Payload: []byte{0x41, 0, 0, 0}}
+	iframe := &rtp.Packet{Payload: []byte{0x65, 0, 0, 0}}
+
+	handler(pframe)
+	require.Equal(t, 1, output)
+
+	cc.checked = time.Now().Add(-congestionInterval)
+	cc.SetLoss(128) // 50% lost
+	_, _, ok := cc.Check()
+	require.True(t, ok)
+	require.True(t, cc.Dropping())
+
+	handler(pframe) // drop until keyframe
+	handler(iframe)
+	handler(pframe)
+	require.Equal(t, 3, output)
+	require.False(t, cc.Dropping())
+}
+
+func TestCongestionAVCC(t *testing.T) {
+	// RTMP, HLS and pipe sources send AVCC frames, without RTP packetization
+	codec := &core.Codec{Name: core.CodecH264, ClockRate: 90000, PayloadType: core.PayloadTypeRAW}
+
+	var output []byte
+	cc := &congestion{}
+	handler := cc.Handler(codec, func(packet *rtp.Packet) {
+		output = append(output, packet.Payload[4])
+	})
+
+	pframe := &rtp.Packet{Payload: []byte{0, 0, 0, 2, 0x41, 0}}
+	iframe := &rtp.Packet{Payload: []byte{0, 0, 0, 2, 0x65, 0}}
+
+	handler(pframe)
+
+	cc.checked = time.Now().Add(-congestionInterval)
+	cc.SetLoss(128)
+	_, _, ok := cc.Check()
+	require.True(t, ok)
+	require.True(t, cc.Dropping())
+
+	handler(pframe) // drop until keyframe
+	handler(iframe)
+	handler(pframe)
+	require.Equal(t, []byte{0x41, 0x65, 0x41}, output)
+	require.False(t, cc.Dropping())
+
+	sent, dropped := cc.Frames()
+	require.Equal(t, 3, sent)
+	require.Equal(t, 1, dropped)
+
+	// keyframes can't be detected, so never drop
+	cc = &congestion{}
+	_ = cc.Handler(&core.Codec{Name: core.CodecJPEG}, nil)
+	cc.checked = time.Now().Add(-congestionInterval)
+	cc.SetLoss(128)
+	_, _, _ = cc.Check()
+	require.False(t, cc.Dropping())
+}
+
+func TestIsKeyframe(t *testing.T) {
+	vp8 := &core.Codec{Name: core.CodecVP8, ClockRate: 90000}
+	require.True(t, isKeyframe(vp8, []byte{0x10, 0x00}))                   // S=1, PID=0, P=0
+	require.False(t, isKeyframe(vp8, []byte{0x10, 0x01}))                  // inter frame
+	require.False(t, isKeyframe(vp8, []byte{0x00, 0x00}))                  // not start of partition
+	require.True(t, isKeyframe(vp8, []byte{0x90, 0x80, 0x81, 0x02, 0x00})) // 15 bit PictureID
+
+	vp9 := &core.Codec{Name: core.CodecVP9, ClockRate: 90000}
+	require.True(t, isKeyframe(vp9, []byte{0x08}))
+	require.False(t, isKeyframe(vp9, []byte{0x48}))
+
+	av1 := &core.Codec{Name: core.CodecAV1, ClockRate: 90000}
+	require.True(t, isKeyframe(av1, []byte{0x18}))
+	require.False(t, isKeyframe(av1, []byte{0x10}))
+}
+
+func TestTWCCLoss(t *testing.T) {
+	packet := &rtcp.TransportLayerCC{
+		PacketStatusCount: 10,
+		PacketChunks: []rtcp.PacketStatusChunk{
+			&rtcp.RunLengthChunk{PacketStatusSymbol: rtcp.TypeTCCPacketReceivedSmallDelta, RunLength: 6},
+			&rtcp.StatusVectorChunk{
+				SymbolSize: rtcp.TypeTCCSymbolSizeOneBit,
+				SymbolList: []uint16{
+					rtcp.TypeTCCPacketNotReceived, rtcp.TypeTCCPacketReceivedSmallDelta,
+					rtcp.TypeTCCPacketNotReceived, rtcp.TypeTCCPacketNotReceived,
+					// padding
+					rtcp.TypeTCCPacketNotReceived, rtcp.TypeTCCPacketNotReceived,
+				},
+			},
+		},
+	}
+	lost, total := twccLoss(packet)
+	require.Equal(t, 3, lost)
+	require.Equal(t, 10, total)
+}
+
+func TestJitterBuffer(t *testing.T) {
+	var output []uint16
+	var losses int
+
+	j := newJitterBuffer(
+		100*time.Millisecond,
+		func(packet *rtp.Packet) { output = append(output, packet.SequenceNumber) },
+		func() { losses++ },
+	)
+
+	ts := time.Now()
+	push := func(seq uint16, ms int) {
+		j.PushAt(&rtp.Packet{Header: rtp.Header{SequenceNumber: seq}}, ts.Add(time.Duration(ms)*time.Millisecond))
+	}
+
+	push(65534, 0)
+	push(65535, 10)
+	push(1, 20) // 0 lost
+	push(2, 30)
+	require.Equal(t, []uint16{65534, 65535}, output)
+
+	push(0, 40) // retransmission
+	require.Equal(t, []uint16{65534, 65535, 0, 1, 2}, output)
+
+	push(4, 50) // 3 lost forever
+	push(5, 160)
+	require.Equal(t, []uint16{65534, 65535, 0, 1, 2, 4, 5}, output)
+	require.Equal(t, 1, losses)
+
+	push(3, 170) // too late
+	require.Equal(t, []uint16{65534, 65535, 0, 1, 2, 4, 5}, output)
+
+	push(40005, 180) // forward jump, sender restart
+	push(40006, 190)
+	require.Equal(t, []uint16{65534, 65535, 0, 1, 2, 4, 5, 40005, 40006}, output)
+	require.Equal(t, 2, losses)
+
+	push(1000, 200) // backward jump, SSRC reset
+	require.Equal(t, []uint16{65534, 65535, 0, 1, 2, 4, 5, 40005, 40006, 1000}, output)
+	require.Equal(t, 3, losses)
+
+	j.Close()
+}
+
+func TestJitterBufferTimer(t *testing.T) {
+	var mu sync.Mutex
+	var output []uint16
+
+	j := newJitterBuffer(
+		20*time.Millisecond,
+		func(packet *rtp.Packet) {
+			mu.Lock()
+			output = append(output, packet.SequenceNumber)
+			mu.Unlock()
+		},
+		func() {},
+	)
+	defer j.Close()
+
+	j.Push(&rtp.Packet{Header: rtp.Header{SequenceNumber: 1}})
+	j.Push(&rtp.Packet{Header: rtp.Header{SequenceNumber: 3}}) // 2 lost, then input pauses
+
+	// packet 3 should be flushed by timer without next packets
+	require.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(output) == 2 && output[1] == 3
+	}, time.Second, 5*time.Millisecond)
+}
+
+func TestStatsBitrate(t *testing.T) {
+	var s connStats
+
+	tracks := []*TrackStats{{SSRC: 1, Bytes: 1000}}
+	s.bitrate(tracks)
+	require.Equal(t, 0, tracks[0].Bitrate) // no previous report
+
+	s.ts = s.ts.Add(-2 * time.Second)
+
+	tracks = []*TrackStats{{SSRC: 1, Bytes: 51000}, {SSRC: 2, Bytes: 100}}
+	s.bitrate(tracks)
+	require.InDelta(t, 200000, tracks[0].Bitrate, 1000)
+	require.Equal(t, 0, tracks[1].Bitrate) // new track
+
+	// too often - same values from previous report
+	tracks = []*TrackStats{{SSRC: 1, Bytes: 99000}}
+	s.bitrate(tracks)
+	require.InDelta(t, 200000, tracks[0].Bitrate, 1000)
+
+	require.Equal(t, "H264/90000", codecString(webrtc.RTPCodecCapability{MimeType: webrtc.MimeTypeH264, ClockRate: 90000}))
+	require.Equal(t, "opus/48000/2", codecString(webrtc.RTPCodecCapability{MimeType: webrtc.MimeTypeOpus, ClockRate: 48000, Channels: 2}))
+}
+
+func TestMatchInterface(t *testing.T) {
+	patterns := []string{"docker*", "br-*", "wg0"}
+	require.True(t, MatchInterface(patterns, "docker0"))
+	require.True(t, MatchInterface(patterns, "br-1a2b3c"))
+	require.True(t, MatchInterface(patterns, "wg0"))
+	require.False(t, MatchInterface(patterns, "eth0"))
+	require.False(t, MatchInterface(nil, "eth0"))
+}
+
+func TestOpaque(t *testing.T) {
+	prod := &core.Media{
+		Kind: core.KindVideo, Direction: core.DirectionRecvonly,
+		Codecs: []*core.Codec{{Name: core.CodecH264, ClockRate: 90000}},
+	}
+	cons := &core.Media{
+		Kind: core.KindVideo, Direction: core.DirectionSendonly, Layer: core.LayerAuto,
+		Codecs: []*core.Codec{{Name: core.CodecH264, ClockRate: 90000}},
+	}
+
+	SetOpaque([]*core.Media{prod})
+
+	// encrypted media can't be passed to usual consumers
+	codec, _ := prod.MatchMedia(cons)
+	require.Nil(t, codec)
+
+	SetOpaque([]*core.Media{cons})
+	require.Equal(t, "", cons.Layer) // no auto switching without keyframes
+	require.Equal(t, "video, sendonly, H264, opaque", cons.String())
+
+	codec, _ = prod.MatchMedia(cons)
+	require.NotNil(t, codec)
+
+	// no transcoding for encrypted audio
+	audio := &core.Media{
+		Kind: core.KindAudio, Direction: core.DirectionSendonly, Opaque: true,
+		Codecs: []*core.Codec{{Name: core.CodecPCMA, ClockRate: 8000}},
+	}
+	WithResampling([]*core.Media{audio})
+	require.Len(t, audio.Codecs, 1)
+
+	// never drop encrypted video, because keyframes can't be detected
+	cc := &congestion{}
+	cc.SetOpaque()
+	cc.checked = time.Now().Add(-congestionInterval)
+	cc.SetLoss(128)
+	_, _, ok := cc.Check()
+	require.True(t, ok)
+	require.False(t, cc.Dropping())
+}
+
+func TestStatsGetter(t *testing.T) {
+	api, err := NewAPI()
+	require.Nil(t, err)
+
+	pc1, err := NewPeerConnection(api, webrtc.Configuration{})
+	require.Nil(t, err)
+	pc2, err := NewPeerConnection(api, webrtc.Configuration{})
+	require.Nil(t, err)
+
+	// each PeerConnection has own stats interceptor
+	conn1, conn2 := NewConn(pc1), NewConn(pc2)
+	require.NotNil(t, conn1.statsGetter())
+	require.NotNil(t, conn2.statsGetter())
+	require.NotSame(t, conn1.statsGetter(), conn2.statsGetter())
+
+	// not connected connection has no summary
+	require.Nil(t, conn1.GetStatsSummary())
+
+	_ = conn1.Close()
+	require.Nil(t, conn1.statsGetter())
+	require.NotNil(t, conn2.statsGetter())
+
+	_ = conn2.Close()
+}
@@ -107,6 +107,12 @@ export class VideoRTC extends HTMLElement {
          */
         this.pc = null;
 
+        /**
+         * Data channel for JSON messages with server (PTZ, keyframe, stream events).
+         * @type {RTCDataChannel}
+         */
+        this.pcChannel = null;
+
         /**
          * @type {number}
          */
@@ -182,6 +188,16 @@ export class VideoRTC extends HTMLElement {
         if (this.ws) this.ws.send(JSON.stringify(value));
     }
 
+    /**
+     * Send message to server via WebRTC data channel
+     * @param {Object} value
+     */
+    sendMessage(value) {
+        if (this.pcChannel && this.pcChannel.readyState === 'open') {
+            this.pcChannel.send(JSON.stringify(value));
+        }
+    }
+
     /** @param {Function} isSupported */
     codecs(isSupported) {
         return this.CODECS
@@ -321,6 +337,7 @@ export class VideoRTC extends HTMLElement {
             });
             this.pc.close();
             this.pc = null;
+            this.pcChannel = null;
         }
 
         this.video.src = '';
@@ -510,6 +527,7 @@ export class VideoRTC extends HTMLElement {
 
                 this.pcState = WebSocket.CLOSED;
                 this.pc = null;
+                this.pcChannel = null;
 
                 this.onconnect();
             }
@@ -535,12 +553,24 @@ export class VideoRTC extends HTMLElement {
             }
         };
 
+        // server answers on this channel, messages dispatched as `rtc-message` events
+        const channel = pc.createDataChannel('go2rtc');
+        channel.addEventListener('message', ev => {
+            if (typeof ev.data !== 'string') return;
+            try {
+                this.dispatchEvent(new CustomEvent('rtc-message', {detail: JSON.parse(ev.data)}));
+            } catch (e) {
+                console.warn(e);
+            }
+        });
+
         this.createOffer(pc).then(offer => {
             this.send({type: 'webrtc/offer', value: offer.sdp});
         });
 
         this.pcState = WebSocket.CONNECTING;
         this.pc = pc;
+        this.pcChannel = channel;
     }
 
     /**
@@ -603,6 +633,7 @@ export class VideoRTC extends HTMLElement {
                 if (this.pc) {
                     this.pc.close();
                     this.pc = null;
+                    this.pcChannel = null;
                 }
             }
         }